@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/infra"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiKeyName   string
+	apiKeyTenant string
+	apiKeyRole   string
+	apiKeysCmd   = &cobra.Command{
+		Use:   "apikeys",
+		Short: "Manage API keys",
+		Long: "Manage API keys accepted by /api/contacts endpoints. Clients pass the key either in " +
+			"'Authorization: Bearer <key>' or in 'X-API-Key: <key>' request header",
+	}
+	apiKeysCreateCmd = &cobra.Command{
+		Use:   "create --deployment={local|dev|prod|...} --name=<name> [--tenant=<tenant>] [--role=<role>]",
+		Short: "Issue new API key and print it once",
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.APIKeysCreate(deployment, apiKeyName, apiKeyTenant, apiKeyRole)
+		},
+	}
+	apiKeysListCmd = &cobra.Command{
+		Use:   "list --deployment={local|dev|prod|...}",
+		Short: "Print issued API keys",
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.APIKeysList(deployment)
+		},
+	}
+	apiKeysRevokeCmd = &cobra.Command{
+		Use:   "revoke <id> --deployment={local|dev|prod|...}",
+		Short: "Revoke API key",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.APIKeysRevoke(deployment, args[0])
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{apiKeysCreateCmd, apiKeysListCmd, apiKeysRevokeCmd} {
+		c.Flags().StringVar(&deployment, "deployment", "",
+			"deployment environment, e.g. local, prod (it should match your configuration filename)")
+		_ = c.MarkFlagRequired("deployment")
+	}
+	apiKeysCreateCmd.Flags().StringVar(&apiKeyName, "name", "", "name telling who or what uses the key")
+	_ = apiKeysCreateCmd.MarkFlagRequired("name")
+	apiKeysCreateCmd.Flags().StringVar(&apiKeyTenant, "tenant", app.DefaultTenant,
+		"tenant (user or organisation) whose address book is available with the key")
+	apiKeysCreateCmd.Flags().StringVar(&apiKeyRole, "role", string(model.RoleEditor),
+		"role granted to the key holder: reader, editor or admin")
+	apiKeysCmd.AddCommand(apiKeysCreateCmd, apiKeysListCmd, apiKeysRevokeCmd)
+	rootCmd.AddCommand(apiKeysCmd)
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/migrations"
+	"github.com/skvenkat/golang-chi-rest-api/internal/infra"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateSteps int
+	migrateDir   string
+	migrateCmd   = &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database schema migrations",
+		Long: "Manage database schema migrations. Pending migrations are also applied automatically when " +
+			"service starts with 'run' command",
+	}
+	migrateUpCmd = &cobra.Command{
+		Use:   "up --deployment={local|dev|prod|...}",
+		Short: "Apply all pending migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.MigrateUp(deployment)
+		},
+	}
+	migrateDownCmd = &cobra.Command{
+		Use:   "down --deployment={local|dev|prod|...} [--steps=1]",
+		Short: "Revert most recently applied migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.MigrateDown(deployment, migrateSteps)
+		},
+	}
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status --deployment={local|dev|prod|...}",
+		Short: "Print migrations and whether they were applied",
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.MigrateStatus(deployment)
+		},
+	}
+	migrateCreateCmd = &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create new empty up/down migration scripts",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			infra.MigrateCreate(migrateDir, args[0])
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{migrateUpCmd, migrateDownCmd, migrateStatusCmd} {
+		c.Flags().StringVar(&deployment, "deployment", "",
+			"deployment environment, e.g. local, prod (it should match your configuration filename)")
+		_ = c.MarkFlagRequired("deployment")
+	}
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "number of migrations to revert")
+	migrateCreateCmd.Flags().StringVar(&migrateDir, "dir", migrations.SourceDir,
+		"directory with per-database subdirectories where migration scripts are created")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateCreateCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
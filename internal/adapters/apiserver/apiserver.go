@@ -49,14 +49,50 @@ func Start(_ context.Context, di *di.DI) {
 
 func apiRoutes(mux *chi.Mux, di *di.DI) {
 	mux.Get("/api/version", internal.GetVersion())
+	mux.With(authMiddleware(di.UseCases)).Get("/debug/vars", internal.GetDebugVars(di.UseCases))
 	mux.Route("/api/contacts", func(r chi.Router) {
+		r.Use(authMiddleware(di.UseCases))
 		r.Post("/", internal.CreateContact(di.UseCases))
-		r.Get("/", internal.ListAllContacts(di.UseCases))
+		r.Get("/", internal.ListContacts(di.UseCases))
+		r.Get("/search", internal.SearchContacts(di.UseCases))
+		r.Get("/by-phone/{number}", internal.FindContactsByPhone(di.UseCases))
 
 		r.Route("/{contactId}", func(r chi.Router) {
 			r.Get("/", internal.GetContact(di.UseCases))
 			r.Put("/", internal.UpdateContact(di.UseCases))
+			r.Patch("/", internal.PatchContact(di.UseCases))
 			r.Delete("/", internal.DeleteContact(di.UseCases))
+			r.Post("/restore", internal.RestoreContact(di.UseCases))
+			r.Get("/history", internal.GetContactHistory(di.UseCases))
+			r.Post("/revert", internal.RevertContact(di.UseCases))
+		})
+	})
+
+	mux.With(authMiddleware(di.UseCases)).Post("/api/contacts:batch", internal.BatchContacts(di.UseCases))
+
+	mux.Route("/api/trash", func(r chi.Router) {
+		r.Use(authMiddleware(di.UseCases))
+		r.Get("/", internal.ListTrash(di.UseCases))
+	})
+
+	mux.Route("/api/custom-fields", func(r chi.Router) {
+		r.Use(authMiddleware(di.UseCases))
+		r.Get("/", internal.ListCustomFields(di.UseCases))
+		r.Post("/", internal.CreateCustomField(di.UseCases))
+		r.Delete("/{fieldId}", internal.DeleteCustomField(di.UseCases))
+	})
+
+	mux.Route("/api/groups", func(r chi.Router) {
+		r.Use(authMiddleware(di.UseCases))
+		r.Get("/", internal.ListGroups(di.UseCases))
+		r.Post("/", internal.CreateGroup(di.UseCases))
+
+		r.Route("/{groupId}", func(r chi.Router) {
+			r.Get("/", internal.GetGroup(di.UseCases))
+			r.Put("/", internal.UpdateGroup(di.UseCases))
+			r.Delete("/", internal.DeleteGroup(di.UseCases))
+			r.Post("/members", internal.AddGroupMembers(di.UseCases))
+			r.Delete("/members", internal.RemoveGroupMembers(di.UseCases))
 		})
 	})
 }
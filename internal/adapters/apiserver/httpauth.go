@@ -0,0 +1,78 @@
+package apiserver
+
+import (
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/apiserver/internal"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+var (
+	errNoCredentials = fmt.Errorf("%w: API key or bearer token is required", model.ErrUnauthenticated)
+	errInvalidAPIKey = fmt.Errorf("%w: API key is invalid or revoked", model.ErrUnauthenticated)
+)
+
+// authMiddleware rejects requests that do not present valid credentials: either API key in
+// "Authorization: Bearer <key>" or "X-API-Key: <key>" header, or JWT issued by identity provider
+// in "Authorization: Bearer <token>" header. Caller identity is stored in request context.
+func authMiddleware(uc *usecase.UseCases) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			identity, err := authenticate(r, uc)
+			if err == nil && identity == nil {
+				err = errNoCredentials
+			}
+			if err != nil {
+				internal.RenderError(w, r, err)
+				return
+			}
+			ctx := app.ContextWithIdentity(r.Context(), identity)
+			l := app.Logger(ctx).With(zap.String("subject", identity.Subject))
+			next.ServeHTTP(w, r.WithContext(app.ContextWithLogger(ctx, l)))
+		}
+		return http.HandlerFunc(fn)
+	}
+}
+
+// authenticate returns nil identity if request has no credentials at all
+func authenticate(r *http.Request, uc *usecase.UseCases) (*app.Identity, error) {
+	if rawKey := r.Header.Get(apiKeyHeader); rawKey != "" {
+		return authenticateAPIKey(r, uc, rawKey)
+	}
+	authz := r.Header.Get("Authorization")
+	if len(authz) <= len("Bearer ") || !strings.EqualFold(authz[:len("Bearer ")], "Bearer ") {
+		return nil, nil
+	}
+	credential := strings.TrimSpace(authz[len("Bearer "):])
+	if isJWT(credential) {
+		return uc.AuthenticateToken(r.Context(), credential)
+	}
+	return authenticateAPIKey(r, uc, credential)
+}
+
+func authenticateAPIKey(r *http.Request, uc *usecase.UseCases, rawKey string) (*app.Identity, error) {
+	key, err := uc.AuthenticateAPIKey(r.Context(), rawKey)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, errInvalidAPIKey
+	}
+	return &app.Identity{
+		Subject:  "apikey:" + key.ID,
+		Tenant:   key.Tenant,
+		Roles:    []string{string(key.Role)},
+		APIKeyID: key.ID,
+	}, nil
+}
+
+// isJWT tells JWT (three dot separated parts) apart from API key which never contains dots
+func isJWT(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
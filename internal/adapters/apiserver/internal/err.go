@@ -1,33 +1,70 @@
 package internal
 
-import "net/http"
+import (
+	"errors"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"net/http"
+)
 
-// ErrResponse renderer for HTTP failed response
+// errUnsupportedMediaType is returned when request body has content type which is not supported by the endpoint
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// ErrResponse renderer for HTTP failed response, it follows RFC 7807 problem details format
 type ErrResponse struct {
-	Err            error `json:"-"` // low-level runtime error
-	HTTPStatusCode int   `json:"-"` // http response status code
+	Err error `json:"-"` // low-level runtime error
 
-	StatusText string `json:"status"`          // user-level status message
-	AppCode    int64  `json:"code,omitempty"`  // application-specific error code
-	ErrorText  string `json:"error,omitempty"` // application-level error message, for debugging
+	Type      string           `json:"type"`                 // problem type, "about:blank" means that title is HTTP status text
+	Title     string           `json:"title"`                // user-level status message
+	Status    int              `json:"status"`               // http response status code
+	Detail    string           `json:"detail,omitempty"`     // application-level error message
+	Instance  string           `json:"instance,omitempty"`   // request path
+	RequestID string           `json:"request_id,omitempty"` // matches requestId field of application logs
+	Errors    []FieldErrorRest `json:"errors,omitempty"`     // invalid input fields, validation problems only
 }
 
-var NotFoundErrResponse = &ErrResponse{HTTPStatusCode: 404, StatusText: "Resource not found."}
+type FieldErrorRest struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
 
-func NewInternalServerErrResponse(err error) *ErrResponse {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: http.StatusInternalServerError,
-		StatusText:     "Internal server error",
-		ErrorText:      err.Error(),
+// NewErrResponse maps error returned by use case (or by request parsing) to HTTP status and problem details.
+// Details of unexpected errors are not disclosed, they can be found in logs by request id.
+func NewErrResponse(err error) *ErrResponse {
+	var validationErr *model.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		resp := newErrResponse(err, http.StatusBadRequest, "request is invalid")
+		for _, v := range validationErr.Violations {
+			resp.Errors = append(resp.Errors, FieldErrorRest{Field: v.Field, Message: v.Message})
+		}
+		return resp
+	case errors.Is(err, model.ErrNotFound):
+		return newErrResponse(err, http.StatusNotFound, err.Error())
+	case errors.Is(err, model.ErrUnauthenticated):
+		return newErrResponse(err, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, model.ErrForbidden):
+		return newErrResponse(err, http.StatusForbidden, err.Error())
+	case errors.Is(err, model.ErrConflict):
+		return newErrResponse(err, http.StatusConflict, err.Error())
+	case errors.Is(err, model.ErrPreconditionFailed):
+		return newErrResponse(err, http.StatusPreconditionFailed, err.Error())
+	case errors.Is(err, model.ErrNotApplied):
+		return newErrResponse(err, http.StatusFailedDependency, err.Error())
+	case errors.Is(err, errUnsupportedMediaType):
+		return newErrResponse(err, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, model.ErrUnavailable):
+		return newErrResponse(err, http.StatusServiceUnavailable, "service is temporarily unavailable, try again later")
+	default:
+		return newErrResponse(err, http.StatusInternalServerError, "unexpected error, see logs by request_id")
 	}
 }
 
-func NewBadRequestErrResponse(err error) *ErrResponse {
+func newErrResponse(err error, status int, detail string) *ErrResponse {
 	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: http.StatusBadRequest,
-		StatusText:     "Bad request",
-		ErrorText:      err.Error(),
+		Err:    err,
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
 	}
 }
@@ -1,29 +1,23 @@
 package internal
 
 import (
-	"github.com/go-chi/render"
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
 	"net/http"
 )
 
-func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	render.Status(r, e.HTTPStatusCode)
-	return nil
-}
-
-func ErrBadRequest(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: http.StatusBadRequest,
-		StatusText:     "Bad request.",
-		ErrorText:      err.Error(),
-	}
-}
+const problemContentType = "application/problem+json"
 
-func ErrRender(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 422,
-		StatusText:     "Error rendering response.",
-		ErrorText:      err.Error(),
+// RenderError writes problem details response describing the error, see NewErrResponse
+func RenderError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := NewErrResponse(err)
+	resp.Instance = r.URL.Path
+	resp.RequestID = middleware.GetReqID(r.Context())
+	if resp.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
 	}
+	// render.JSON always responds with application/json content type, so problem is encoded here
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(resp.Status)
+	_ = json.NewEncoder(w).Encode(resp)
 }
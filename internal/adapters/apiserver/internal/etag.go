@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// contactETag returns strong entity tag of the contact made of its version, it is empty if the version is unknown
+func contactETag(c *model.Contact) string {
+	if c.Version == 0 {
+		return ""
+	}
+	return strconv.Quote(strconv.Itoa(c.Version))
+}
+
+func setContactETag(w http.ResponseWriter, c *model.Contact) {
+	if etag := contactETag(c); etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+}
+
+// ifMatchVersion returns contact version required by If-Match header. It is zero if there is no header or it is "*"
+// (contact has to exist anyway). Header listing anything but a single strong entity tag of the contact version
+// cannot match, -1 is returned for it, so that the precondition fails.
+func ifMatchVersion(r *http.Request) int {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return 0
+	}
+	tag, err := strconv.Unquote(header)
+	if err != nil || !strings.HasPrefix(header, `"`) {
+		return -1
+	}
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		return -1
+	}
+	return version
+}
+
+// ifNoneMatch reports whether If-None-Match header lists the entity tag, tags are compared weakly as RFC 9110 requires
+func ifNoneMatch(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
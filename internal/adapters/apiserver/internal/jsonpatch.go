@@ -0,0 +1,325 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// contactPatchRest is JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) of the contact. Patch is applied to
+// the contact as it is returned by REST API without read-only id and groups.
+type contactPatchRest struct {
+	mergePatch any                  // JSON Merge Patch document, it is used if operations are nil
+	operations []jsonPatchOperation // JSON Patch document
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"` // nil if value is missing, so that it differs from null
+}
+
+// errPatchNotApplicable is returned when JSON Patch operation does not match the current state of the contact
+var errPatchNotApplicable = errors.New("patch does not match contact")
+
+func (p *contactPatchRest) Apply(c *model.Contact) (*model.ContactToSave, error) {
+	doc, err := toJSONValue(&ContactToSaveRest{
+		FirstName:    c.FirstName,
+		LastName:     c.LastName,
+		Phones:       phonesModelToRest(c.Phones),
+		Emails:       emailsModelToRest(c.Emails),
+		Addresses:    addressesModelToRest(c.Addresses),
+		URLs:         urlsModelToRest(c.URLs),
+		CustomFields: lo.Assign(c.CustomFields),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.operations != nil {
+		if doc, err = applyJSONPatch(doc, p.operations); err != nil {
+			return nil, err
+		}
+	} else {
+		doc = applyMergePatch(doc, p.mergePatch)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	req := &ContactToSaveRest{}
+	if err = decoder.Decode(req); err != nil {
+		return nil, model.NewValidationError("body", fmt.Sprintf("patched contact is malformed: %v", err))
+	}
+	return req.toModel(), nil
+}
+
+// contactPatchFromRequest decodes patch of the contact in the format given by content type of the request
+func contactPatchFromRequest(r *http.Request) (*contactPatchRest, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != mergePatchContentType && mediaType != jsonPatchContentType {
+		return nil, fmt.Errorf("%w: %s or %s is expected", errUnsupportedMediaType, mergePatchContentType, jsonPatchContentType)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err))
+	}
+	patch := &contactPatchRest{}
+	if mediaType == mergePatchContentType {
+		err = json.Unmarshal(body, &patch.mergePatch)
+	} else if err = json.Unmarshal(body, &patch.operations); err == nil && patch.operations == nil {
+		err = errors.New("array of operations is expected")
+	}
+	if err != nil {
+		return nil, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err))
+	}
+	return patch, nil
+}
+
+// toJSONValue converts value into maps, slices and primitives as if it was decoded from JSON
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// applyMergePatch merges patch into target as RFC 7396 defines: null removes member, objects are merged
+// recursively, other values replace target values as a whole
+func applyMergePatch(target any, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = applyMergePatch(targetObject[name], value)
+		}
+	}
+	return targetObject
+}
+
+// applyJSONPatch applies operations to the document one by one as RFC 6902 defines. Malformed operation is
+// reported as ValidationError, operation which does not match the document is reported as model.ErrConflict.
+func applyJSONPatch(doc any, operations []jsonPatchOperation) (any, error) {
+	for i, operation := range operations {
+		var err error
+		if doc, err = operation.apply(doc); err != nil {
+			field := fmt.Sprintf("body[%d]", i)
+			if errors.Is(err, errPatchNotApplicable) {
+				return nil, fmt.Errorf("%w: %s: %v", model.ErrConflict, field, err)
+			}
+			return nil, model.NewValidationError(field, err.Error())
+		}
+	}
+	return doc, nil
+}
+
+func (o *jsonPatchOperation) apply(doc any) (any, error) {
+	path, err := parseJSONPointer(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Op {
+	case "add", "replace", "test":
+		if o.Value == nil {
+			return nil, fmt.Errorf("%s operation requires value", o.Op)
+		}
+		var value any
+		if err = json.Unmarshal(o.Value, &value); err != nil {
+			return nil, fmt.Errorf("malformed value: %v", err)
+		}
+		switch o.Op {
+		case "add":
+			return jsonAdd(doc, path, value)
+		case "replace":
+			if doc, err = jsonRemove(doc, path); err != nil {
+				return nil, err
+			}
+			return jsonAdd(doc, path, value)
+		default:
+			current, err := jsonGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("%w: value at %s differs", errPatchNotApplicable, o.Path)
+			}
+			return doc, nil
+		}
+	case "remove":
+		return jsonRemove(doc, path)
+	case "move", "copy":
+		from, err := parseJSONPointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if o.Op == "copy" {
+			if value, err = toJSONValue(value); err != nil {
+				return nil, err
+			}
+		} else {
+			if o.From != o.Path && strings.HasPrefix(o.Path, o.From+"/") {
+				return nil, fmt.Errorf("cannot move %s into its own child %s", o.From, o.Path)
+			}
+			if doc, err = jsonRemove(doc, from); err != nil {
+				return nil, err
+			}
+		}
+		return jsonAdd(doc, path, value)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", o.Op)
+	}
+}
+
+// parseJSONPointer splits JSON pointer (RFC 6901) into unescaped reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func jsonGet(doc any, path []string) (any, error) {
+	for _, token := range path {
+		switch value := doc.(type) {
+		case map[string]any:
+			member, ok := value[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: member %q not found", errPatchNotApplicable, token)
+			}
+			doc = member
+		case []any:
+			i, err := arrayIndex(token, len(value)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = value[i]
+		default:
+			return nil, fmt.Errorf("%w: %q is neither object nor array", errPatchNotApplicable, token)
+		}
+	}
+	return doc, nil
+}
+
+func jsonAdd(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonChangeParent(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			p[token] = value
+			return p, nil
+		case []any:
+			if token == "-" {
+				return append(p, value), nil
+			}
+			i, err := arrayIndex(token, len(p))
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], append([]any{value}, p[i:]...)...), nil
+		default:
+			return nil, fmt.Errorf("%w: cannot add %q to neither object nor array", errPatchNotApplicable, token)
+		}
+	})
+}
+
+func jsonRemove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return jsonChangeParent(doc, path, func(parent any, token string) (any, error) {
+		switch p := parent.(type) {
+		case map[string]any:
+			if _, ok := p[token]; !ok {
+				return nil, fmt.Errorf("%w: member %q not found", errPatchNotApplicable, token)
+			}
+			delete(p, token)
+			return p, nil
+		case []any:
+			i, err := arrayIndex(token, len(p)-1)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: %q is neither object nor array", errPatchNotApplicable, token)
+		}
+	})
+}
+
+// jsonChangeParent replaces parent of the path target with a result of change, so that arrays may grow or shrink
+func jsonChangeParent(
+	doc any,
+	path []string,
+	change func(parent any, token string) (any, error),
+) (any, error) {
+	if len(path) == 1 {
+		return change(doc, path[0])
+	}
+	child, err := jsonGet(doc, path[:1])
+	if err != nil {
+		return nil, err
+	}
+	if child, err = jsonChangeParent(child, path[1:], change); err != nil {
+		return nil, err
+	}
+	switch value := doc.(type) {
+	case map[string]any:
+		value[path[0]] = child
+	case []any:
+		i, _ := arrayIndex(path[0], len(value)-1) // index was checked by jsonGet
+		value[i] = child
+	}
+	return doc, nil
+}
+
+// arrayIndex parses array index which must not exceed max
+func arrayIndex(token string, max int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || strings.TrimLeft(token, "0123456789") != "" || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("%q is not an array index", token)
+	}
+	if i > max {
+		return 0, fmt.Errorf("%w: array index %d is out of bounds", errPatchNotApplicable, i)
+	}
+	return i, nil
+}
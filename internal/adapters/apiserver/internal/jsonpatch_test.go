@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"reflect"
+	"testing"
+)
+
+// decodeTestJSON returns JSON text decoded into maps, slices and primitives
+func decodeTestJSON(t *testing.T, text string) any {
+	t.Helper()
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		t.Fatalf("malformed test JSON %s: %v", text, err)
+	}
+	return value
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	const doc = `{"name": "John", "phones": ["a", "b"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`
+	tests := []struct {
+		name    string
+		patch   string
+		want    string
+		wantErr error // model.ErrConflict is reported as 409, model.ErrValidation as 400
+	}{
+		{
+			name:  "add to the end of array",
+			patch: `[{"op": "add", "path": "/phones/-", "value": "c"}]`,
+			want:  `{"name": "John", "phones": ["a", "b", "c"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:  "add to the middle of array",
+			patch: `[{"op": "add", "path": "/phones/1", "value": "c"}]`,
+			want:  `{"name": "John", "phones": ["a", "c", "b"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:  "add after the last element",
+			patch: `[{"op": "add", "path": "/phones/2", "value": "c"}]`,
+			want:  `{"name": "John", "phones": ["a", "b", "c"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:    "add out of array bounds",
+			patch:   `[{"op": "add", "path": "/phones/3", "value": "c"}]`,
+			wantErr: model.ErrConflict,
+		},
+		{
+			name:    "add with malformed index",
+			patch:   `[{"op": "add", "path": "/phones/01", "value": "c"}]`,
+			wantErr: model.ErrValidation,
+		},
+		{
+			name:  "add replaces existing member",
+			patch: `[{"op": "add", "path": "/name", "value": "Johnny"}]`,
+			want:  `{"name": "Johnny", "phones": ["a", "b"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:  "replace and remove",
+			patch: `[{"op": "replace", "path": "/phones/0", "value": "z"}, {"op": "remove", "path": "/custom_fields/tier"}]`,
+			want:  `{"name": "John", "phones": ["z", "b"], "custom_fields": {"a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:    "replace missing member",
+			patch:   `[{"op": "replace", "path": "/nickname", "value": "JJ"}]`,
+			wantErr: model.ErrConflict,
+		},
+		{
+			name:    "remove missing member",
+			patch:   `[{"op": "remove", "path": "/custom_fields/score"}]`,
+			wantErr: model.ErrConflict,
+		},
+		{
+			name:    "remove member of missing parent",
+			patch:   `[{"op": "remove", "path": "/emails/0/label"}]`,
+			wantErr: model.ErrConflict,
+		},
+		{
+			name:  "move",
+			patch: `[{"op": "move", "from": "/custom_fields/tier", "path": "/custom_fields/level"}]`,
+			want:  `{"name": "John", "phones": ["a", "b"], "custom_fields": {"level": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:    "move into its own child",
+			patch:   `[{"op": "move", "from": "/custom_fields", "path": "/custom_fields/nested"}]`,
+			wantErr: model.ErrValidation,
+		},
+		{
+			name: "copy is not changed along with original",
+			patch: `[{"op": "copy", "from": "/custom_fields", "path": "/copy"},
+			         {"op": "replace", "path": "/custom_fields/tier", "value": "silver"}]`,
+			want: `{"name": "John", "phones": ["a", "b"], "custom_fields": {"tier": "silver", "a/b": 1, "m~n": 2},
+			        "copy": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:  "passed test",
+			patch: `[{"op": "test", "path": "/phones", "value": ["a", "b"]}, {"op": "remove", "path": "/phones/0"}]`,
+			want:  `{"name": "John", "phones": ["b"], "custom_fields": {"tier": "gold", "a/b": 1, "m~n": 2}}`,
+		},
+		{
+			name:    "failed test",
+			patch:   `[{"op": "remove", "path": "/phones/0"}, {"op": "test", "path": "/name", "value": "Jane"}]`,
+			wantErr: model.ErrConflict,
+		},
+		{
+			name:    "test without value",
+			patch:   `[{"op": "test", "path": "/name"}]`,
+			wantErr: model.ErrValidation,
+		},
+		{
+			name: "escaped tokens",
+			patch: `[{"op": "replace", "path": "/custom_fields/a~1b", "value": 10},
+			         {"op": "replace", "path": "/custom_fields/m~0n", "value": 20}]`,
+			want: `{"name": "John", "phones": ["a", "b"], "custom_fields": {"tier": "gold", "a/b": 10, "m~n": 20}}`,
+		},
+		{
+			name:    "path without leading slash",
+			patch:   `[{"op": "remove", "path": "name"}]`,
+			wantErr: model.ErrValidation,
+		},
+		{
+			name:    "unsupported operation",
+			patch:   `[{"op": "append", "path": "/phones", "value": "c"}]`,
+			wantErr: model.ErrValidation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var operations []jsonPatchOperation
+			if err := json.Unmarshal([]byte(tt.patch), &operations); err != nil {
+				t.Fatal(err)
+			}
+			got, err := applyJSONPatch(decodeTestJSON(t, doc), operations)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("patch is applied with error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("patch is not applied: %v", err)
+			}
+			if want := decodeTestJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched document is %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{
+			name:   "null removes member",
+			target: `{"name": "John", "custom_fields": {"tier": "gold", "score": 1}}`,
+			patch:  `{"custom_fields": {"tier": null}}`,
+			want:   `{"name": "John", "custom_fields": {"score": 1}}`,
+		},
+		{
+			name:   "null of missing member",
+			target: `{"name": "John"}`,
+			patch:  `{"nickname": null}`,
+			want:   `{"name": "John"}`,
+		},
+		{
+			name:   "arrays are replaced as a whole",
+			target: `{"phones": ["a", "b"]}`,
+			patch:  `{"phones": ["c"]}`,
+			want:   `{"phones": ["c"]}`,
+		},
+		{
+			name:   "object replaces primitive",
+			target: `{"custom_fields": "none"}`,
+			patch:  `{"custom_fields": {"tier": "gold", "score": null}}`,
+			want:   `{"custom_fields": {"tier": "gold"}}`,
+		},
+		{
+			name:   "non-object patch replaces target",
+			target: `{"name": "John"}`,
+			patch:  `["John"]`,
+			want:   `["John"]`,
+		},
+		{
+			name:   "null patch replaces target",
+			target: `{"name": "John"}`,
+			patch:  `null`,
+			want:   `null`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyMergePatch(decodeTestJSON(t, tt.target), decodeTestJSON(t, tt.patch))
+			if want := decodeTestJSON(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("patched document is %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyContactMergePatchWhichIsNotObject(t *testing.T) {
+	patch := &contactPatchRest{mergePatch: []any{"John"}}
+	_, err := patch.Apply(&model.Contact{ID: "1", FirstName: "John", LastName: "Doe", Version: 1})
+	if !errors.Is(err, model.ErrValidation) {
+		t.Errorf("patch is applied with error %v, want %v", err, model.ErrValidation)
+	}
+}
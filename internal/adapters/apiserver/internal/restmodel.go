@@ -1,65 +1,120 @@
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"github.com/samber/lo"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ContactToSaveRest struct {
-	FirstName string      `json:"first_name"`
-	LastName  string      `json:"last_name"`
-	Phones    []PhoneRest `json:"phones"`
+	FirstName string        `json:"first_name"`
+	LastName  string        `json:"last_name"`
+	Phones    []PhoneRest   `json:"phones"`
+	Emails    []EmailRest   `json:"emails"`
+	Addresses []AddressRest `json:"addresses"`
+	URLs      []URLRest     `json:"urls"`
+	// CustomFields are values of custom fields by field name, null value is the same as no value
+	CustomFields map[string]any `json:"custom_fields"`
 }
 
 type PhoneRest struct {
 	PhoneType   string `json:"phone_type"`
 	PhoneNumber string `json:"phone_number"`
+	// normalized forms of phone number, they are ignored in requests and omitted for phones saved
+	// before numbers were normalized
+	E164          string `json:"e164,omitempty"`
+	National      string `json:"national,omitempty"`
+	International string `json:"international,omitempty"`
+}
+
+type EmailRest struct {
+	Label   string `json:"label,omitempty"`
+	Address string `json:"address"`
+}
+
+type AddressRest struct {
+	Label      string `json:"label,omitempty"`
+	Street     string `json:"street,omitempty"`
+	City       string `json:"city,omitempty"`
+	Region     string `json:"region,omitempty"`
+	PostalCode string `json:"postal_code,omitempty"`
+	Country    string `json:"country,omitempty"`
+}
+
+type URLRest struct {
+	Label string `json:"label,omitempty"`
+	URL   string `json:"url"`
 }
 
 type ContactRest struct {
-	ID        string      `json:"id"`
-	FirstName string      `json:"first_name"`
-	LastName  string      `json:"last_name"`
-	Phones    []PhoneRest `json:"phones"`
+	ID        string        `json:"id"`
+	FirstName string        `json:"first_name"`
+	LastName  string        `json:"last_name"`
+	Phones    []PhoneRest   `json:"phones"`
+	Emails    []EmailRest   `json:"emails"`
+	Addresses []AddressRest `json:"addresses"`
+	URLs      []URLRest     `json:"urls"`
+	// CustomFields are values of custom fields by field name, values are strings, numbers or booleans
+	CustomFields map[string]any `json:"custom_fields"`
+	// Groups of the contact, membership is changed by /api/groups/{id}/members requests
+	Groups []GroupRefRest `json:"groups"`
+	// DeletedAt is returned for contacts in trash only
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
-func (r *ContactToSaveRest) toModel() (*model.ContactToSave, error) {
-	if r.FirstName == "" {
-		return nil, errors.New("first_name must not be empty")
-	}
-	if r.LastName == "" {
-		return nil, errors.New("last_name must not be empty")
-	}
-	phones := make([]*model.ContactPhoneToSave, len(r.Phones))
-	for i, phone := range r.Phones {
-		phoneModel, err := phone.toContactPhoneToSaveModel()
-		if err != nil {
-			return nil, err
-		}
-		phones[i] = phoneModel
-	}
+type GroupRefRest struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// toModel converts contact as is, it is validated by use cases
+func (r *ContactToSaveRest) toModel() *model.ContactToSave {
 	return &model.ContactToSave{
 		FirstName: r.FirstName,
 		LastName:  r.LastName,
-		Phones:    phones,
-	}, nil
+		Phones: lo.Map(r.Phones, func(item PhoneRest, _ int) *model.ContactPhoneToSave {
+			return item.toContactPhoneToSaveModel()
+		}),
+		Emails: lo.Map(r.Emails, func(item EmailRest, _ int) *model.ContactEmail {
+			return &model.ContactEmail{Label: item.Label, Address: item.Address}
+		}),
+		Addresses: lo.Map(r.Addresses, func(item AddressRest, _ int) *model.ContactAddress {
+			return &model.ContactAddress{
+				Label:      item.Label,
+				Street:     item.Street,
+				City:       item.City,
+				Region:     item.Region,
+				PostalCode: item.PostalCode,
+				Country:    item.Country,
+			}
+		}),
+		URLs: lo.Map(r.URLs, func(item URLRest, _ int) *model.ContactURL {
+			return &model.ContactURL{Label: item.Label, URL: item.URL}
+		}),
+		CustomFields: lo.OmitBy(r.CustomFields, func(_ string, value any) bool {
+			return value == nil
+		}),
+	}
 }
 
-func (r PhoneRest) toContactPhoneToSaveModel() (*model.ContactPhoneToSave, error) {
+func (r PhoneRest) toContactPhoneToSaveModel() *model.ContactPhoneToSave {
 	phoneType, err := phoneTypeRestToModel(r.PhoneType)
 	if err != nil {
-		return nil, err
+		// unknown phone type is kept as is, so that it is reported by validation along with other violations
+		phoneType = lo.ToPtr(model.ContactPhoneType(r.PhoneType))
 	}
 	return &model.ContactPhoneToSave{
 		PhoneType:   *phoneType,
 		PhoneNumber: r.PhoneNumber,
-	}, nil
+	}
 }
 
 func phoneTypeRestToModel(phoneType string) (*model.ContactPhoneType, error) {
-	switch phoneType {
+	switch strings.ToLower(phoneType) {
 	case "mobile":
 		return lo.ToPtr(model.ContactPhoneTypeMobile), nil
 	case "home":
@@ -85,18 +140,342 @@ func phoneTypeModelToRest(phoneType model.ContactPhoneType) string {
 }
 
 func contactModelToRest(m *model.Contact) *ContactRest {
-	phones := lo.Map(m.Phones, func(item *model.ContactPhone, _ int) PhoneRest {
+	return &ContactRest{
+		ID:           m.ID,
+		FirstName:    m.FirstName,
+		LastName:     m.LastName,
+		Phones:       phonesModelToRest(m.Phones),
+		Emails:       emailsModelToRest(m.Emails),
+		Addresses:    addressesModelToRest(m.Addresses),
+		URLs:         urlsModelToRest(m.URLs),
+		CustomFields: lo.Assign(m.CustomFields), // copy is never nil, so that it is rendered as {}
+		Groups: lo.Map(m.Groups, func(item *model.GroupRef, _ int) GroupRefRest {
+			return GroupRefRest{ID: item.ID, Name: item.Name}
+		}),
+		DeletedAt: m.DeletedAt,
+	}
+}
+
+func phonesModelToRest(m []*model.ContactPhone) []PhoneRest {
+	return lo.Map(m, func(item *model.ContactPhone, _ int) PhoneRest {
 		return PhoneRest{
-			PhoneType:   phoneTypeModelToRest(item.PhoneType),
-			PhoneNumber: item.PhoneNumber,
+			PhoneType:     phoneTypeModelToRest(item.PhoneType),
+			PhoneNumber:   item.PhoneNumber,
+			E164:          item.PhoneNumberE164,
+			National:      model.FormatPhoneNumberNational(item.PhoneNumberE164),
+			International: model.FormatPhoneNumberInternational(item.PhoneNumberE164),
 		}
+	})
+}
 
+func emailsModelToRest(m []*model.ContactEmail) []EmailRest {
+	return lo.Map(m, func(item *model.ContactEmail, _ int) EmailRest {
+		return EmailRest{Label: item.Label, Address: item.Address}
 	})
-	return &ContactRest{
-		ID:        m.ID,
-		FirstName: m.FirstName,
-		LastName:  m.LastName,
-		Phones:    phones,
+}
+
+func addressesModelToRest(m []*model.ContactAddress) []AddressRest {
+	return lo.Map(m, func(item *model.ContactAddress, _ int) AddressRest {
+		return AddressRest{
+			Label:      item.Label,
+			Street:     item.Street,
+			City:       item.City,
+			Region:     item.Region,
+			PostalCode: item.PostalCode,
+			Country:    item.Country,
+		}
+	})
+}
+
+func urlsModelToRest(m []*model.ContactURL) []URLRest {
+	return lo.Map(m, func(item *model.ContactURL, _ int) URLRest {
+		return URLRest{Label: item.Label, URL: item.URL}
+	})
+}
+
+type ContactListRest struct {
+	Contacts   []*ContactRest `json:"contacts"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	TotalCount int            `json:"total_count"`
+}
+
+func contactListPageModelToRest(m *model.ContactListPage) *ContactListRest {
+	return &ContactListRest{
+		Contacts:   lo.Map(m.Contacts, func(item *model.Contact, _ int) *ContactRest { return contactModelToRest(item) }),
+		NextCursor: m.NextCursor,
+		TotalCount: m.TotalCount,
+	}
+}
+
+type ContactSearchRest struct {
+	Contacts []*ContactRest `json:"contacts"`
+}
+
+func contactSearchModelToRest(m []*model.Contact) *ContactSearchRest {
+	return &ContactSearchRest{
+		Contacts: lo.Map(m, func(item *model.Contact, _ int) *ContactRest { return contactModelToRest(item) }),
+	}
+}
+
+// contactListQueryFromRequest reads listing parameters from URL query, e.g.
+// ?limit=20&cursor=...&sort=-last_name&last_name=Do&phone_type=mobile&group=3
+func contactListQueryFromRequest(r *http.Request) (*model.ContactListQuery, error) {
+	params := r.URL.Query()
+	q := &model.ContactListQuery{
+		Cursor:         params.Get("cursor"),
+		LastNamePrefix: params.Get("last_name"),
+		GroupID:        params.Get("group"),
+	}
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return nil, model.NewValidationError("limit", "must be a positive integer")
+		}
+		q.Limit = n
+	}
+	if sort := params.Get("sort"); sort != "" {
+		field := strings.TrimPrefix(sort, "-")
+		q.SortDesc = field != sort
+		switch field {
+		case "last_name":
+			q.SortBy = model.ContactSortByLastName
+		case "first_name":
+			q.SortBy = model.ContactSortByFirstName
+		default:
+			return nil, model.NewValidationError("sort", fmt.Sprintf("unsupported sort field: %s", field))
+		}
+	}
+	if phoneType := params.Get("phone_type"); phoneType != "" {
+		pt, err := phoneTypeRestToModel(phoneType)
+		if err != nil {
+			return nil, model.NewValidationError("phone_type", err.Error())
+		}
+		q.PhoneType = pt
+	}
+	// custom field filter is given as cf.<field name>=<value>, e.g. ?cf.account_manager=Jane
+	for param, values := range params {
+		if name := strings.TrimPrefix(param, customFieldParamPrefix); name != param {
+			if q.CustomField != nil {
+				return nil, model.NewValidationError(param, "only one custom field filter is supported")
+			}
+			q.CustomField = &model.CustomFieldFilter{Name: name, Value: values[0]}
+		}
+	}
+	return q, nil
+}
+
+const customFieldParamPrefix = "cf."
+
+type CustomFieldRest struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Required   bool     `json:"required"`
+	EnumValues []string `json:"enum_values"`
+}
+
+type CustomFieldToSaveRest struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Required   bool     `json:"required"`
+	EnumValues []string `json:"enum_values"`
+}
+
+type CustomFieldListRest struct {
+	CustomFields []*CustomFieldRest `json:"custom_fields"`
+}
+
+func (r *CustomFieldToSaveRest) toModel() *model.CustomFieldDefinitionToSave {
+	return &model.CustomFieldDefinitionToSave{
+		Name:       r.Name,
+		Type:       model.CustomFieldType(strings.ToLower(r.Type)),
+		Required:   r.Required,
+		EnumValues: r.EnumValues,
+	}
+}
+
+func customFieldModelToRest(m *model.CustomFieldDefinition) *CustomFieldRest {
+	return &CustomFieldRest{
+		ID:         m.ID,
+		Name:       m.Name,
+		Type:       string(m.Type),
+		Required:   m.Required,
+		EnumValues: m.EnumValues,
+	}
+}
+
+func customFieldListModelToRest(m []*model.CustomFieldDefinition) *CustomFieldListRest {
+	return &CustomFieldListRest{
+		CustomFields: lo.Map(m, func(item *model.CustomFieldDefinition, _ int) *CustomFieldRest {
+			return customFieldModelToRest(item)
+		}),
+	}
+}
+
+type ContactHistoryRest struct {
+	Revisions []*ContactRevisionRest `json:"revisions"`
+}
+
+type ContactRevisionRest struct {
+	Revision  int       `json:"revision"`
+	Action    string    `json:"action"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
+	// RevertedTo is a number of the revision the contact was reverted to by "reverted" action
+	RevertedTo int `json:"reverted_to,omitempty"`
+	// Changes is null if the previous state of the contact is unknown
+	Changes []*ContactFieldChangeRest `json:"changes"`
+}
+
+type ContactFieldChangeRest struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
+
+func contactHistoryModelToRest(m []*model.ContactRevision) *ContactHistoryRest {
+	return &ContactHistoryRest{
+		Revisions: lo.Map(m, func(item *model.ContactRevision, _ int) *ContactRevisionRest {
+			rev := &ContactRevisionRest{
+				Revision:   item.Revision,
+				Action:     string(item.Action),
+				Actor:      item.Actor,
+				ChangedAt:  item.ChangedAt,
+				RevertedTo: item.RevertedTo,
+			}
+			if item.Changes != nil {
+				rev.Changes = lo.Map(item.Changes, func(change *model.ContactFieldChange, _ int) *ContactFieldChangeRest {
+					return &ContactFieldChangeRest{
+						Field: change.Field,
+						Old:   fieldValueModelToRest(change.Old),
+						New:   fieldValueModelToRest(change.New),
+					}
+				})
+			}
+			return rev
+		}),
+	}
+}
+
+// fieldValueModelToRest converts changed value of contact field to the same form the field has in ContactRest
+func fieldValueModelToRest(v any) any {
+	switch value := v.(type) {
+	case []*model.ContactPhone:
+		return phonesModelToRest(value)
+	case []*model.ContactEmail:
+		return emailsModelToRest(value)
+	case []*model.ContactAddress:
+		return addressesModelToRest(value)
+	case []*model.ContactURL:
+		return urlsModelToRest(value)
+	default:
+		return value
+	}
+}
+
+type ContactBatchRest struct {
+	// Atomic is true by default, so that either all operations are applied or none is
+	Atomic     *bool                  `json:"atomic"`
+	Operations []ContactOperationRest `json:"operations"`
+}
+
+type ContactOperationRest struct {
+	Op      string             `json:"op"`      // create, update or delete
+	ID      string             `json:"id"`      // id of updated or deleted contact
+	Version int                `json:"version"` // version from ETag the contact must have, zero matches any version
+	Contact *ContactToSaveRest `json:"contact"` // created or updated contact
+}
+
+type ContactBatchResultRest struct {
+	Results []*ContactOperationResultRest `json:"results"`
+}
+
+// ContactOperationResultRest has the HTTP status a single request making the same change would respond with
+type ContactOperationResultRest struct {
+	Status  int          `json:"status"`
+	ETag    string       `json:"etag,omitempty"`
+	Contact *ContactRest `json:"contact,omitempty"`
+	Error   *ErrResponse `json:"error,omitempty"`
+}
+
+func (r *ContactBatchRest) toModel() *model.ContactBatch {
+	return &model.ContactBatch{
+		Atomic: r.Atomic == nil || *r.Atomic,
+		Operations: lo.Map(r.Operations, func(item ContactOperationRest, _ int) *model.ContactOperation {
+			op := &model.ContactOperation{
+				Type:    model.ContactOperationType(item.Op),
+				ID:      item.ID,
+				Version: item.Version,
+			}
+			if item.Contact != nil {
+				op.Contact = item.Contact.toModel()
+			}
+			return op
+		}),
+	}
+}
+
+func contactBatchResultModelToRest(
+	ops []*model.ContactOperation,
+	m []*model.ContactOperationResult,
+	requestID string,
+) *ContactBatchResultRest {
+	return &ContactBatchResultRest{
+		Results: lo.Map(m, func(item *model.ContactOperationResult, i int) *ContactOperationResultRest {
+			if item.Err != nil {
+				resp := NewErrResponse(item.Err)
+				resp.RequestID = requestID
+				return &ContactOperationResultRest{Status: resp.Status, Error: resp}
+			}
+			switch ops[i].Type {
+			case model.ContactOperationCreate:
+				return &ContactOperationResultRest{
+					Status:  http.StatusCreated,
+					ETag:    contactETag(item.Contact),
+					Contact: contactModelToRest(item.Contact),
+				}
+			case model.ContactOperationUpdate:
+				return &ContactOperationResultRest{
+					Status:  http.StatusOK,
+					ETag:    contactETag(item.Contact),
+					Contact: contactModelToRest(item.Contact),
+				}
+			default:
+				return &ContactOperationResultRest{Status: http.StatusNoContent}
+			}
+		}),
+	}
+}
+
+type GroupRest struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	MemberCount int    `json:"member_count"`
+}
+
+type GroupToSaveRest struct {
+	Name string `json:"name"`
+}
+
+type GroupListRest struct {
+	Groups []*GroupRest `json:"groups"`
+}
+
+type GroupMembersRest struct {
+	ContactIDs []string `json:"contact_ids"`
+}
+
+func groupModelToRest(m *model.Group) *GroupRest {
+	return &GroupRest{
+		ID:          m.ID,
+		Name:        m.Name,
+		MemberCount: m.MemberCount,
+	}
+}
+
+func groupListModelToRest(m []*model.Group) *GroupListRest {
+	return &GroupListRest{
+		Groups: lo.Map(m, func(item *model.Group, _ int) *GroupRest { return groupModelToRest(item) }),
 	}
 }
 
@@ -1,29 +1,32 @@
 package internal
 
 import (
+	"fmt"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func CreateContact(uc *usecase.UseCases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		req := &ContactToSaveRest{}
-		if err := render.Bind(r, req); err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
-			return
-		}
-		contactToSave, err := req.toModel()
+		contactToSave, err := contactToSaveFromRequest(r)
 		if err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
+			RenderError(w, r, err)
 			return
 		}
 		c, err := uc.AddAddrBookContact(r.Context(), contactToSave)
 		if err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
+			RenderError(w, r, err)
 			return
 		}
+		setContactETag(w, c)
 		resp := contactModelToRest(c)
 		render.Status(r, http.StatusCreated)
 		_ = render.Render(w, r, resp)
@@ -33,29 +36,38 @@ func CreateContact(uc *usecase.UseCases) http.HandlerFunc {
 func UpdateContact(uc *usecase.UseCases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		contactId := chi.URLParam(r, "contactId")
-		if contactId == "" {
-			_ = render.Render(w, r, NotFoundErrResponse)
-			return
-		}
-		req := &ContactToSaveRest{}
-		if err := render.Bind(r, req); err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
+		contactToSave, err := contactToSaveFromRequest(r)
+		if err != nil {
+			RenderError(w, r, err)
 			return
 		}
-		contactToSave, err := req.toModel()
+		contactToSave.Version = ifMatchVersion(r)
+		c, err := uc.UpdateAddrBookContact(r.Context(), contactId, contactToSave)
 		if err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
+			RenderError(w, r, err)
 			return
 		}
-		c, found, err := uc.UpdateAddrBookContact(r.Context(), contactId, contactToSave)
-		if !found {
-			_ = render.Render(w, r, NotFoundErrResponse)
+		setContactETag(w, c)
+		resp := contactModelToRest(c)
+		_ = render.Render(w, r, resp)
+	}
+}
+
+func PatchContact(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contactId := chi.URLParam(r, "contactId")
+		patch, err := contactPatchFromRequest(r)
+		if err != nil {
+			w.Header().Set("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+			RenderError(w, r, err)
 			return
 		}
+		c, err := uc.PatchAddrBookContact(r.Context(), contactId, patch, ifMatchVersion(r))
 		if err != nil {
-			_ = render.Render(w, r, ErrBadRequest(err))
+			RenderError(w, r, err)
 			return
 		}
+		setContactETag(w, c)
 		resp := contactModelToRest(c)
 		_ = render.Render(w, r, resp)
 	}
@@ -64,63 +76,216 @@ func UpdateContact(uc *usecase.UseCases) http.HandlerFunc {
 func DeleteContact(uc *usecase.UseCases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		contactId := chi.URLParam(r, "contactId")
-		if contactId == "" {
-			_ = render.Render(w, r, NotFoundErrResponse)
+		if err := uc.DeleteAddrBookContact(r.Context(), contactId, ifMatchVersion(r)); err != nil {
+			RenderError(w, r, err)
 			return
 		}
-		found, err := uc.DeleteAddrBookContact(r.Context(), contactId)
-		if err != nil {
-			_ = render.Render(w, r, NewInternalServerErrResponse(err))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// BatchContacts applies a number of contact changes, result of every change is reported in the response body
+func BatchContacts(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &ContactBatchRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
 			return
 		}
-		if !found {
-			_ = render.Render(w, r, NotFoundErrResponse)
+		batch := req.toModel()
+		results, err := uc.ApplyAddrBookBatch(r.Context(), batch)
+		if err != nil {
+			RenderError(w, r, err)
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
+		resp := contactBatchResultModelToRest(batch.Operations, results, middleware.GetReqID(r.Context()))
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, resp); err != nil {
+			RenderError(w, r, err)
+		}
 	}
 }
 
-func ListAllContacts(uc *usecase.UseCases) http.HandlerFunc {
+func RestoreContact(uc *usecase.UseCases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		contacts, err := uc.LoadAddrBookContacts(r.Context())
+		contactId := chi.URLParam(r, "contactId")
+		contact, err := uc.RestoreAddrBookContact(r.Context(), contactId)
 		if err != nil {
-			_ = render.Render(w, r, NewInternalServerErrResponse(err))
+			RenderError(w, r, err)
 			return
 		}
-		contactRenderers := make([]render.Renderer, len(contacts))
-		for i, contact := range contacts {
-			contactRenderers[i] = contactModelToRest(contact)
+		setContactETag(w, contact)
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactModelToRest(contact)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func RevertContact(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contactId := chi.URLParam(r, "contactId")
+		revision, err := strconv.Atoi(r.URL.Query().Get("revision"))
+		if err != nil || revision <= 0 {
+			RenderError(w, r, model.NewValidationError("revision", "must be a positive integer"))
+			return
 		}
-		if err = render.RenderList(w, r, contactRenderers); err != nil {
-			_ = render.Render(w, r, ErrRender(err))
+		contact, err := uc.RevertAddrBookContact(r.Context(), contactId, revision)
+		if err != nil {
+			RenderError(w, r, err)
 			return
 		}
+		setContactETag(w, contact)
 		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactModelToRest(contact)); err != nil {
+			RenderError(w, r, err)
+		}
 	}
 }
 
-func GetContact(uc *usecase.UseCases) http.HandlerFunc {
+func GetContactHistory(uc *usecase.UseCases) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		contactId := chi.URLParam(r, "contactId")
-		if contactId == "" {
-			_ = render.Render(w, r, NotFoundErrResponse)
+		limit, err := limitFromRequest(r)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		revisions, err := uc.LoadAddrBookContactHistory(r.Context(), contactId, limit)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactHistoryModelToRest(revisions)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func ListTrash(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := limitFromRequest(r)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		contacts, err := uc.LoadAddrBookTrash(r.Context(), limit)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactSearchModelToRest(contacts)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func ListContacts(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := contactListQueryFromRequest(r)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		page, err := uc.LoadAddrBookContacts(r.Context(), q)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactListPageModelToRest(page)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func SearchContacts(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		text := r.URL.Query().Get("q")
+		if strings.TrimSpace(text) == "" {
+			RenderError(w, r, model.NewValidationError("q", "search query must not be empty"))
 			return
 		}
-		c, err := uc.LoadAddrBookContactByID(r.Context(), contactId)
+		limit, err := limitFromRequest(r)
 		if err != nil {
-			_ = render.Render(w, r, NewInternalServerErrResponse(err))
+			RenderError(w, r, err)
 			return
 		}
-		if c == nil {
-			_ = render.Render(w, r, NotFoundErrResponse)
+		contacts, err := uc.SearchAddrBookContacts(r.Context(), text, limit)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactSearchModelToRest(contacts)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func FindContactsByPhone(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// chi routes by escaped path when request has it, so "+" of the number may come percent-encoded
+		number, err := url.PathUnescape(chi.URLParam(r, "number"))
+		if err != nil {
+			RenderError(w, r, model.NewValidationError("number", "malformed phone number"))
+			return
+		}
+		contacts, err := uc.FindAddrBookContactsByPhone(r.Context(), number)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, contactSearchModelToRest(contacts)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func GetContact(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		contactId := chi.URLParam(r, "contactId")
+		var c *model.Contact
+		var err error
+		if asOf := r.URL.Query().Get("as_of"); asOf != "" {
+			t, perr := time.Parse(time.RFC3339, asOf)
+			if perr != nil {
+				RenderError(w, r, model.NewValidationError("as_of", "must be a timestamp in RFC 3339 format"))
+				return
+			}
+			c, err = uc.LoadAddrBookContactAsOf(r.Context(), contactId, t)
+		} else {
+			c, err = uc.LoadAddrBookContactByID(r.Context(), contactId)
+		}
+		if err != nil {
+			RenderError(w, r, err)
 			return
 		}
+		// contact as of given time has no version, so it is never tagged
+		if etag := contactETag(c); etag != "" {
+			w.Header().Set("ETag", etag)
+			if ifNoneMatch(r, etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
 		resp := contactModelToRest(c)
 		_ = render.Render(w, r, resp)
 	}
 }
 
+// contactToSaveFromRequest decodes contact from request body
+func contactToSaveFromRequest(r *http.Request) (*model.ContactToSave, error) {
+	req := &ContactToSaveRest{}
+	if err := render.Bind(r, req); err != nil {
+		return nil, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err))
+	}
+	return req.toModel(), nil
+}
+
 // Bind required to properly deserialize POST body to contactToSaveRest value
 func (u *ContactToSaveRest) Bind(r *http.Request) error {
 	// you can perform contactToSaveRest value validation here
@@ -133,3 +298,43 @@ func (rd *ContactRest) Render(w http.ResponseWriter, r *http.Request) error {
 	// Pre-processing before a response is marshalled and sent across the wire
 	return nil
 }
+
+// Render required to properly serialize contactListRest value into HTTP body response
+func (rd *ContactListRest) Render(w http.ResponseWriter, r *http.Request) error {
+	// Pre-processing before a response is marshalled and sent across the wire
+	return nil
+}
+
+// Render required to properly serialize contactSearchRest value into HTTP body response
+func (rd *ContactSearchRest) Render(w http.ResponseWriter, r *http.Request) error {
+	// Pre-processing before a response is marshalled and sent across the wire
+	return nil
+}
+
+// Bind required to properly deserialize request body to ContactBatchRest value
+func (u *ContactBatchRest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize ContactBatchResultRest value into HTTP body response
+func (rd *ContactBatchResultRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize ContactHistoryRest value into HTTP body response
+func (rd *ContactHistoryRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// limitFromRequest reads optional "limit" parameter from URL query, zero is returned if it is not given
+func limitFromRequest(r *http.Request) (int, error) {
+	l := r.URL.Query().Get("limit")
+	if l == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(l)
+	if err != nil || n <= 0 {
+		return 0, model.NewValidationError("limit", "must be a positive integer")
+	}
+	return n, nil
+}
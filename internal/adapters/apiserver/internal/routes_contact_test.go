@@ -0,0 +1,471 @@
+//go:build sqlite_fts5 || fts5
+
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	cacheadapter "github.com/skvenkat/golang-chi-rest-api/internal/adapters/cache"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// newTestUseCases returns use cases backed by SQLite database in a temporary file
+func newTestUseCases(t *testing.T) *usecase.UseCases {
+	p := persist.NewPersistence(&app.Config{Database: app.DatabaseConfig{
+		Driver:   "sqlite",
+		Filename: filepath.Join(t.TempDir(), "test.db"),
+	}})
+	t.Cleanup(p.Close)
+	addrBook := persist.NewAddrBookAdapter(p, cacheadapter.NewInMemCache())
+	return &usecase.UseCases{
+		AddrBook:     addrBook,
+		APIKeys:      persist.NewAPIKeysAdapter(p),
+		CustomFields: persist.NewCustomFieldsAdapter(p, addrBook),
+		Credentials:  app.CredentialsConfig{Secret: "test-secret-which-is-long-enough-to-hash-keys"},
+		Phones:       app.PhonesConfig{DefaultRegion: "US"},
+	}
+}
+
+// newTestRequest returns request made by editor of the test tenant
+func newTestRequest(method string, target string, body string) *http.Request {
+	return newTestRequestAs(model.RoleEditor, method, target, body)
+}
+
+// newTestRequestAs returns request made by caller of the test tenant having the role
+func newTestRequestAs(role model.Role, method string, target string, body string) *http.Request {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	ctx := app.ContextWithIdentity(app.BackgroundContextWithDefaultLogger(), &app.Identity{
+		Subject: "tester",
+		Tenant:  "tenant-a",
+		Roles:   []string{string(role)},
+	})
+	return r.WithContext(ctx)
+}
+
+func addTestContact(t *testing.T, uc *usecase.UseCases, firstName string) *model.Contact {
+	t.Helper()
+	c, err := uc.AddAddrBookContact(newTestRequest(http.MethodPost, "/", "").Context(),
+		&model.ContactToSave{FirstName: firstName, LastName: "Doe"})
+	if err != nil {
+		t.Fatalf("error adding contact %s: %v", firstName, err)
+	}
+	return c
+}
+
+func postBatch(t *testing.T, uc *usecase.UseCases, body string) (int, *ContactBatchResultRest) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	BatchContacts(uc)(w, newTestRequest(http.MethodPost, "/api/contacts:batch", body))
+	resp := &ContactBatchResultRest{}
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatalf("error decoding response %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, resp
+}
+
+func statusesOf(resp *ContactBatchResultRest) []int {
+	statuses := make([]int, len(resp.Results))
+	for i, result := range resp.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestBatchContactsWithFailedOperation(t *testing.T) {
+	tests := []struct {
+		name       string
+		failed     func(updated *model.Contact) string // operation which fails in the middle of the batch
+		wantStatus int
+	}{
+		{
+			name: "not found",
+			failed: func(*model.Contact) string {
+				return `{"op": "update", "id": "999", "contact": {"first_name": "Nobody", "last_name": "Doe"}}`
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "version mismatch",
+			failed: func(updated *model.Contact) string {
+				return fmt.Sprintf(`{"op": "update", "id": %q, "version": %d, "contact": {"first_name": "Stale", "last_name": "Doe"}}`,
+					updated.ID, updated.Version+1)
+			},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+	}
+	for _, tt := range tests {
+		for _, atomic := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s atomic=%t", tt.name, atomic), func(t *testing.T) {
+				uc := newTestUseCases(t)
+				updated := addTestContact(t, uc, "John")
+				deleted := addTestContact(t, uc, "Jane")
+				body := fmt.Sprintf(`{"atomic": %t, "operations": [
+					{"op": "create", "contact": {"first_name": "Ann", "last_name": "Lee"}},
+					%s,
+					{"op": "delete", "id": %q}
+				]}`, atomic, tt.failed(updated), deleted.ID)
+
+				code, resp := postBatch(t, uc, body)
+				if code != http.StatusOK {
+					t.Fatalf("batch is responded with status %d", code)
+				}
+				want := []int{http.StatusCreated, tt.wantStatus, http.StatusNoContent}
+				if atomic {
+					want = []int{http.StatusFailedDependency, tt.wantStatus, http.StatusFailedDependency}
+				}
+				if got := statusesOf(resp); !reflect.DeepEqual(got, want) {
+					t.Errorf("batch results have statuses %v, want %v", got, want)
+				}
+				if failed := resp.Results[1]; failed.Error == nil || failed.Error.Status != tt.wantStatus || failed.Contact != nil {
+					t.Errorf("failed operation has unexpected result: %+v", failed)
+				}
+
+				ctx := newTestRequest(http.MethodGet, "/", "").Context()
+				page, err := uc.LoadAddrBookContacts(ctx, &model.ContactListQuery{Limit: 10})
+				if err != nil {
+					t.Fatal(err)
+				}
+				var names []string
+				for _, c := range page.Contacts {
+					names = append(names, c.FirstName)
+				}
+				sort.Strings(names)
+				wantNames := []string{"Ann", "John"}
+				if atomic {
+					wantNames = []string{"Jane", "John"} // nothing is changed
+				}
+				if !reflect.DeepEqual(names, wantNames) {
+					t.Errorf("contacts after batch are %v, want %v", names, wantNames)
+				}
+			})
+		}
+	}
+}
+
+func TestBatchContactsTooLarge(t *testing.T) {
+	uc := newTestUseCases(t)
+	c := addTestContact(t, uc, "John")
+	ops := make([]string, model.MaxContactBatchSize+1)
+	for i := range ops {
+		ops[i] = fmt.Sprintf(`{"op": "delete", "id": %q}`, c.ID)
+	}
+	code, _ := postBatch(t, uc, fmt.Sprintf(`{"operations": [%s]}`, strings.Join(ops, ",")))
+	if code != http.StatusBadRequest {
+		t.Errorf("batch of %d operations is responded with status %d, want %d", len(ops), code, http.StatusBadRequest)
+	}
+	if _, err := uc.LoadAddrBookContactByID(newTestRequest(http.MethodGet, "/", "").Context(), c.ID); err != nil {
+		t.Errorf("contact is deleted by rejected batch: %v", err)
+	}
+}
+
+func TestListContactsByCustomField(t *testing.T) {
+	uc := newTestUseCases(t)
+	ctx := newTestRequestAs(model.RoleAdmin, http.MethodPost, "/", "").Context()
+	for _, f := range []*model.CustomFieldDefinitionToSave{
+		{Name: "tier", Type: model.CustomFieldTypeEnum, EnumValues: []string{"silver", "gold"}},
+		{Name: "score", Type: model.CustomFieldTypeNumber},
+	} {
+		if _, err := uc.AddCustomField(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []*model.ContactToSave{
+		{FirstName: "John", LastName: "Doe", CustomFields: map[string]any{"tier": "gold", "score": 42.0}},
+		{FirstName: "Jane", LastName: "Doe", CustomFields: map[string]any{"tier": "silver", "score": 7.0}},
+		{FirstName: "Ann", LastName: "Lee"},
+	} {
+		if _, err := uc.AddAddrBookContact(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query      string
+		wantStatus int
+		wantNames  []string
+	}{
+		{query: "cf.tier=gold", wantStatus: http.StatusOK, wantNames: []string{"John"}},
+		{query: "cf.tier=silver", wantStatus: http.StatusOK, wantNames: []string{"Jane"}},
+		{query: "cf.score=42.0", wantStatus: http.StatusOK, wantNames: []string{"John"}}, // compared in canonical form
+		{query: "cf.score=1", wantStatus: http.StatusOK, wantNames: []string{}},
+		{query: "cf.tier=bronze", wantStatus: http.StatusBadRequest},
+		{query: "cf.score=many", wantStatus: http.StatusBadRequest},
+		{query: "cf.nickname=JJ", wantStatus: http.StatusBadRequest},
+		{query: "cf.tier=gold&cf.score=42", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ListContacts(uc)(w, newTestRequest(http.MethodGet, "/api/contacts?"+tt.query, ""))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("list is responded with status %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			list := &ContactListRest{}
+			if err := json.Unmarshal(w.Body.Bytes(), list); err != nil {
+				t.Fatalf("error decoding response %s: %v", w.Body.String(), err)
+			}
+			names := []string{}
+			for _, c := range list.Contacts {
+				names = append(names, c.FirstName)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) {
+				t.Errorf("listed contacts are %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+// newTestRouter serves the routes requested with X-API-Key header, the key is authenticated the same way as
+// authentication middleware of API server does
+func newTestRouter(uc *usecase.UseCases) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := app.ContextWithLogger(r.Context(), zap.S())
+			key, err := uc.AuthenticateAPIKey(ctx, r.Header.Get("X-API-Key"))
+			if err != nil || key == nil {
+				RenderError(w, r, model.ErrUnauthenticated)
+				return
+			}
+			ctx = app.ContextWithIdentity(ctx, &app.Identity{
+				Subject:  "apikey:" + key.ID,
+				Tenant:   key.Tenant,
+				Roles:    []string{string(key.Role)},
+				APIKeyID: key.ID,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	})
+	r.Get("/debug/vars", GetDebugVars(uc))
+	r.Post("/api/contacts", CreateContact(uc))
+	r.Get("/api/contacts", ListContacts(uc))
+	r.Get("/api/contacts/{contactId}", GetContact(uc))
+	r.Put("/api/contacts/{contactId}", UpdateContact(uc))
+	r.Patch("/api/contacts/{contactId}", PatchContact(uc))
+	r.Delete("/api/contacts/{contactId}", DeleteContact(uc))
+	r.Post("/api/contacts/{contactId}/restore", RestoreContact(uc))
+	r.Post("/api/contacts/{contactId}/revert", RevertContact(uc))
+	r.Post("/api/contacts:batch", BatchContacts(uc))
+	r.Get("/api/custom-fields", ListCustomFields(uc))
+	r.Post("/api/custom-fields", CreateCustomField(uc))
+	r.Delete("/api/custom-fields/{fieldId}", DeleteCustomField(uc))
+	return r
+}
+
+// newTestAPIKey returns raw API key giving access with the role to the test tenant
+func newTestAPIKey(t *testing.T, uc *usecase.UseCases, role model.Role) string {
+	t.Helper()
+	admin := newTestRequestAs(model.RoleAdmin, http.MethodPost, "/", "").Context()
+	_, rawKey, err := uc.CreateAPIKey(admin, string(role), "tenant-a", role)
+	if err != nil {
+		t.Fatalf("error creating API key: %v", err)
+	}
+	return rawKey
+}
+
+func TestRolesOfAPIKeys(t *testing.T) {
+	uc := newTestUseCases(t)
+	contact := addTestContact(t, uc, "John")
+	keys := make(map[model.Role]string)
+	for _, role := range []model.Role{model.RoleReader, model.RoleEditor, model.RoleAdmin} {
+		keys[role] = newTestAPIKey(t, uc, role)
+	}
+	contactPath := "/api/contacts/" + contact.ID
+	tests := []struct {
+		method      string
+		target      string
+		body        string
+		contentType string
+		wantStatus  map[model.Role]int
+	}{
+		{
+			method:     http.MethodGet,
+			target:     contactPath,
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusOK, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:     http.MethodGet,
+			target:     "/api/contacts",
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusOK},
+		},
+		{
+			method:     http.MethodPost,
+			target:     "/api/contacts",
+			body:       `{"first_name": "Jane", "last_name": "Doe"}`,
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusCreated},
+		},
+		{
+			method:     http.MethodPut,
+			target:     contactPath,
+			body:       `{"first_name": "Johnny", "last_name": "Doe"}`,
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:      http.MethodPatch,
+			target:      contactPath,
+			body:        `{"last_name": "Smith"}`,
+			contentType: "application/merge-patch+json",
+			wantStatus:  map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:     http.MethodPost,
+			target:     contactPath + "/revert?revision=1",
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:     http.MethodPost,
+			target:     "/api/contacts:batch",
+			body:       `{"operations": [{"op": "create", "contact": {"first_name": "Ann", "last_name": "Lee"}}]}`,
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:     http.MethodDelete,
+			target:     contactPath,
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusNoContent},
+		},
+		{
+			method:     http.MethodPost,
+			target:     contactPath + "/restore",
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusForbidden, model.RoleEditor: http.StatusOK},
+		},
+		{
+			method:     http.MethodGet,
+			target:     "/api/custom-fields",
+			wantStatus: map[model.Role]int{model.RoleReader: http.StatusOK},
+		},
+		{
+			method: http.MethodPost,
+			target: "/api/custom-fields",
+			body:   `{"name": "tier", "type": "string"}`,
+			wantStatus: map[model.Role]int{
+				model.RoleReader: http.StatusForbidden,
+				model.RoleEditor: http.StatusForbidden,
+				model.RoleAdmin:  http.StatusCreated,
+			},
+		},
+		{
+			method: http.MethodDelete,
+			target: "/api/custom-fields/1",
+			wantStatus: map[model.Role]int{
+				model.RoleReader: http.StatusForbidden,
+				model.RoleEditor: http.StatusForbidden,
+				model.RoleAdmin:  http.StatusNoContent,
+			},
+		},
+		{
+			method: http.MethodGet,
+			target: "/debug/vars",
+			wantStatus: map[model.Role]int{
+				model.RoleReader: http.StatusForbidden,
+				model.RoleEditor: http.StatusForbidden,
+				model.RoleAdmin:  http.StatusOK,
+			},
+		},
+	}
+	router := newTestRouter(uc)
+	// requests are made in order of roles, so that the request which is forbidden does not change anything
+	for _, tt := range tests {
+		for _, role := range []model.Role{model.RoleReader, model.RoleEditor, model.RoleAdmin} {
+			want, ok := tt.wantStatus[role]
+			if !ok {
+				continue
+			}
+			t.Run(fmt.Sprintf("%s %s by %s", tt.method, tt.target, role), func(t *testing.T) {
+				r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+				r.Header.Set("Content-Type", "application/json")
+				if tt.contentType != "" {
+					r.Header.Set("Content-Type", tt.contentType)
+				}
+				r.Header.Set("X-API-Key", keys[role])
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, r)
+				if w.Code != want {
+					t.Errorf("request is responded with status %d, want %d: %s", w.Code, want, w.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestRevertContactAfterCustomFieldIsDeleted(t *testing.T) {
+	uc := newTestUseCases(t)
+	admin := newTestRequestAs(model.RoleAdmin, http.MethodPost, "/", "").Context()
+	if _, err := uc.AddCustomField(admin, &model.CustomFieldDefinitionToSave{Name: "tier", Type: model.CustomFieldTypeString}); err != nil {
+		t.Fatal(err)
+	}
+	score, err := uc.AddCustomField(admin, &model.CustomFieldDefinitionToSave{Name: "score", Type: model.CustomFieldTypeNumber})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := uc.AddAddrBookContact(admin, &model.ContactToSave{
+		FirstName:    "John",
+		LastName:     "Doe",
+		Phones:       []*model.ContactPhoneToSave{{PhoneType: model.ContactPhoneTypeHome, PhoneNumber: "503-555-7777"}},
+		CustomFields: map[string]any{"tier": "gold", "score": 1.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = uc.UpdateAddrBookContact(admin, created.ID, &model.ContactToSave{
+		FirstName:    "Johnny",
+		LastName:     "Doe",
+		CustomFields: map[string]any{"tier": "silver", "score": 2.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = uc.DeleteCustomField(admin, score.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/contacts/"+created.ID+"/revert?revision=1", nil)
+	r.Header.Set("X-API-Key", newTestAPIKey(t, uc, model.RoleEditor))
+	w := httptest.NewRecorder()
+	newTestRouter(uc).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("revert is responded with status %d: %s", w.Code, w.Body.String())
+	}
+	reverted := &ContactRest{}
+	if err = json.Unmarshal(w.Body.Bytes(), reverted); err != nil {
+		t.Fatalf("error decoding response %s: %v", w.Body.String(), err)
+	}
+	if reverted.FirstName != "John" || len(reverted.Phones) != 1 || reverted.Phones[0].E164 != "+15035557777" {
+		t.Errorf("names and phones are not reverted: %+v", reverted)
+	}
+	// value of deleted custom field is dropped instead of failing validation of unknown field
+	if want := map[string]any{"tier": "gold"}; !reflect.DeepEqual(reverted.CustomFields, want) {
+		t.Errorf("reverted custom fields are %v, want %v", reverted.CustomFields, want)
+	}
+
+	history, err := uc.LoadAddrBookContactHistory(admin, created.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("history has %d revisions, want 3", len(history))
+	}
+	if last := history[0]; last.Revision != 3 || last.Action != model.ContactRevisionReverted || last.RevertedTo != 1 {
+		t.Errorf("the last revision is %d %s to %d, want 3 reverted to 1", last.Revision, last.Action, last.RevertedTo)
+	}
+	if loaded, err := uc.LoadAddrBookContactByID(admin, created.ID); err != nil || loaded.FirstName != "John" {
+		t.Errorf("reverted contact is loaded as %+v, error %v", loaded, err)
+	}
+}
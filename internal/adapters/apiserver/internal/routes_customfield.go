@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
+	"net/http"
+)
+
+func ListCustomFields(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fields, err := uc.LoadCustomFields(r.Context())
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, customFieldListModelToRest(fields)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func CreateCustomField(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &CustomFieldToSaveRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
+			return
+		}
+		field, err := uc.AddCustomField(r.Context(), req.toModel())
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusCreated)
+		_ = render.Render(w, r, customFieldModelToRest(field))
+	}
+}
+
+func DeleteCustomField(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fieldId := chi.URLParam(r, "fieldId")
+		if err := uc.DeleteCustomField(r.Context(), fieldId); err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Bind required to properly deserialize POST body to CustomFieldToSaveRest value
+func (u *CustomFieldToSaveRest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize CustomFieldRest value into HTTP body response
+func (rd *CustomFieldRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize CustomFieldListRest value into HTTP body response
+func (rd *CustomFieldListRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"fmt"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
+	"net/http"
+)
+
+func ListGroups(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := uc.LoadGroups(r.Context())
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusOK)
+		if err = render.Render(w, r, groupListModelToRest(groups)); err != nil {
+			RenderError(w, r, err)
+		}
+	}
+}
+
+func GetGroup(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		group, err := uc.LoadGroupByID(r.Context(), chi.URLParam(r, "groupId"))
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		_ = render.Render(w, r, groupModelToRest(group))
+	}
+}
+
+func CreateGroup(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &GroupToSaveRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
+			return
+		}
+		group, err := uc.AddGroup(r.Context(), &model.GroupToSave{Name: req.Name})
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		render.Status(r, http.StatusCreated)
+		_ = render.Render(w, r, groupModelToRest(group))
+	}
+}
+
+func UpdateGroup(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &GroupToSaveRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
+			return
+		}
+		group, err := uc.UpdateGroup(r.Context(), chi.URLParam(r, "groupId"), &model.GroupToSave{Name: req.Name})
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		_ = render.Render(w, r, groupModelToRest(group))
+	}
+}
+
+func DeleteGroup(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := uc.DeleteGroup(r.Context(), chi.URLParam(r, "groupId")); err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func AddGroupMembers(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &GroupMembersRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
+			return
+		}
+		if err := uc.AddGroupMembers(r.Context(), chi.URLParam(r, "groupId"), req.ContactIDs); err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func RemoveGroupMembers(uc *usecase.UseCases) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &GroupMembersRest{}
+		if err := render.Bind(r, req); err != nil {
+			RenderError(w, r, model.NewValidationError("body", fmt.Sprintf("malformed request body: %v", err)))
+			return
+		}
+		if err := uc.RemoveGroupMembers(r.Context(), chi.URLParam(r, "groupId"), req.ContactIDs); err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Bind required to properly deserialize request body to GroupToSaveRest value
+func (u *GroupToSaveRest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Bind required to properly deserialize request body to GroupMembersRest value
+func (u *GroupMembersRest) Bind(r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize GroupRest value into HTTP body response
+func (rd *GroupRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// Render required to properly serialize GroupListRest value into HTTP body response
+func (rd *GroupListRest) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
@@ -1,7 +1,9 @@
 package internal
 
 import (
+	"expvar"
 	"github.com/go-chi/render"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/usecase"
 	"net/http"
 )
 
@@ -18,6 +20,18 @@ func GetVersion() http.HandlerFunc {
 	}
 }
 
+// GetDebugVars serves expvar variables (cache failure counters, memory statistics, ...) to admins only
+func GetDebugVars(uc *usecase.UseCases) http.HandlerFunc {
+	vars := expvar.Handler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := uc.AuthorizeDiagnostics(r.Context()); err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		vars.ServeHTTP(w, r)
+	}
+}
+
 func (rd *VersionRest) Render(w http.ResponseWriter, r *http.Request) error {
 	// Pre-processing before a response is marshalled and sent across the wire
 	return nil
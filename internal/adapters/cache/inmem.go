@@ -7,9 +7,12 @@ import (
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
 	"github.com/vmihailenco/msgpack/v5"
 	"go.uber.org/zap"
+	"sync"
 )
 
 type inMemCacheAdapter struct {
+	// mu guards chunks, they are read by tiered cache invalidations which may arrive while partitions are registered
+	mu     sync.RWMutex
 	chunks map[string]inMemCacheChunk
 }
 
@@ -30,6 +33,8 @@ func (adp *inMemCacheAdapter) Close() {
 
 func (adp *inMemCacheAdapter) Register(partition *outport.CachePartition) {
 	ns := partition.Namespace
+	adp.mu.Lock()
+	defer adp.mu.Unlock()
 	if _, ok := adp.chunks[ns]; ok {
 		panic(fmt.Sprintf("Cache partition with namespace=%s was already registered", ns))
 	}
@@ -40,38 +45,50 @@ func (adp *inMemCacheAdapter) Register(partition *outport.CachePartition) {
 	}
 }
 
-func (adp *inMemCacheAdapter) mustGetCacheChunk(ns string) inMemCacheChunk {
+func (adp *inMemCacheAdapter) getCacheChunk(ns string) (inMemCacheChunk, error) {
+	adp.mu.RLock()
+	defer adp.mu.RUnlock()
 	if chunk, ok := adp.chunks[ns]; ok {
-		return chunk
+		return chunk, nil
 	}
-	panic(fmt.Sprintf("Cache partition with namespace=%s was not registered", ns))
+	return inMemCacheChunk{}, fmt.Errorf("cache partition with namespace=%s was not registered", ns)
 }
 
-func (adp *inMemCacheAdapter) Set(_ context.Context, key outport.CacheKey, value any) {
+func (adp *inMemCacheAdapter) Set(_ context.Context, key outport.CacheKey, value any) error {
 	zap.S().Debugf("Set item in in-mem cache by cacheKey=%s", key)
-	chunk := adp.mustGetCacheChunk(key.Namespace)
+	chunk, err := adp.getCacheChunk(key.Namespace)
+	if err != nil {
+		return err
+	}
 	result, err := msgpack.Marshal(value)
 	if err != nil {
-		panic(fmt.Errorf("error marshalling value: %w", err))
+		return fmt.Errorf("error marshalling value: %w", err)
 	}
 	chunk.cache.Set(key.EncodedKey, result)
+	return nil
 }
 
-func (adp *inMemCacheAdapter) Get(_ context.Context, key outport.CacheKey, value any) bool {
+func (adp *inMemCacheAdapter) Get(_ context.Context, key outport.CacheKey, value any) (bool, error) {
 	zap.S().Debugf("Get item from in-mem cache by cacheKey=%s", key)
-	chunk := adp.mustGetCacheChunk(key.Namespace)
+	chunk, err := adp.getCacheChunk(key.Namespace)
+	if err != nil {
+		return false, err
+	}
 	if data, ok := chunk.cache.Get(key.EncodedKey); ok {
-		err := msgpack.Unmarshal(data, value)
-		if err != nil {
-			panic(fmt.Errorf("error unmarshalling value: %w", err))
+		if err = msgpack.Unmarshal(data, value); err != nil {
+			return false, fmt.Errorf("error unmarshalling value: %w", err)
 		}
-		return true
+		return true, nil
 	}
-	return false
+	return false, nil
 }
 
-func (adp *inMemCacheAdapter) Del(_ context.Context, key outport.CacheKey) {
+func (adp *inMemCacheAdapter) Del(_ context.Context, key outport.CacheKey) error {
 	zap.S().Debugf("Delete item in in-mem cache by cacheKey=%s", key)
-	chunk := adp.mustGetCacheChunk(key.Namespace)
+	chunk, err := adp.getCacheChunk(key.Namespace)
+	if err != nil {
+		return err
+	}
 	chunk.cache.Del(key.EncodedKey)
+	return nil
 }
@@ -20,17 +20,16 @@ func (adp *noCacheAdapter) Close() {
 	// Nothing to do
 }
 
-func (adp *noCacheAdapter) Set(_ context.Context, key outport.CacheKey, _ any) {
-	adp.mustHaveCacheChunk(key.Namespace)
+func (adp *noCacheAdapter) Set(_ context.Context, key outport.CacheKey, _ any) error {
+	return adp.checkCacheChunk(key.Namespace)
 }
 
-func (adp *noCacheAdapter) Get(_ context.Context, key outport.CacheKey, _ any) bool {
-	adp.mustHaveCacheChunk(key.Namespace)
-	return false
+func (adp *noCacheAdapter) Get(_ context.Context, key outport.CacheKey, _ any) (bool, error) {
+	return false, adp.checkCacheChunk(key.Namespace)
 }
 
-func (adp *noCacheAdapter) Del(_ context.Context, key outport.CacheKey) {
-	adp.mustHaveCacheChunk(key.Namespace)
+func (adp *noCacheAdapter) Del(_ context.Context, key outport.CacheKey) error {
+	return adp.checkCacheChunk(key.Namespace)
 }
 
 func (adp *noCacheAdapter) Register(partition *outport.CachePartition) {
@@ -41,8 +40,9 @@ func (adp *noCacheAdapter) Register(partition *outport.CachePartition) {
 	adp.chunks[ns] = struct{}{}
 }
 
-func (adp *noCacheAdapter) mustHaveCacheChunk(ns string) {
+func (adp *noCacheAdapter) checkCacheChunk(ns string) error {
 	if _, ok := adp.chunks[ns]; !ok {
-		panic(fmt.Sprintf("cache partition with namespace=%s was not registered", ns))
+		return fmt.Errorf("cache partition with namespace=%s was not registered", ns)
 	}
+	return nil
 }
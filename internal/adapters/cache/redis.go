@@ -0,0 +1,113 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+	"io"
+	"net"
+)
+
+// redisCacheAdapter stores values in Redis, so that all service replicas share the same cache.
+type redisCacheAdapter struct {
+	client     *redis.Client
+	partitions map[string]*outport.CachePartition
+}
+
+func NewRedisCache(cfg app.CacheConfig) outport.Cache {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		zap.S().Warnf("Redis server %s is not available yet: %v", cfg.Address, err)
+	}
+	return &redisCacheAdapter{
+		client:     client,
+		partitions: make(map[string]*outport.CachePartition),
+	}
+}
+
+func (adp *redisCacheAdapter) Close() {
+	if err := adp.client.Close(); err != nil {
+		zap.S().Warnln("failed to close Redis client:", err)
+	}
+}
+
+func (adp *redisCacheAdapter) Register(partition *outport.CachePartition) {
+	ns := partition.Namespace
+	if _, ok := adp.partitions[ns]; ok {
+		panic(fmt.Sprintf("Cache partition with namespace=%s was already registered", ns))
+	}
+	adp.partitions[ns] = partition
+}
+
+func (adp *redisCacheAdapter) getPartition(ns string) (*outport.CachePartition, error) {
+	if partition, ok := adp.partitions[ns]; ok {
+		return partition, nil
+	}
+	return nil, fmt.Errorf("cache partition with namespace=%s was not registered", ns)
+}
+
+func (adp *redisCacheAdapter) Set(ctx context.Context, key outport.CacheKey, value any) error {
+	zap.S().Debugf("Set item in Redis cache by cacheKey=%s", key)
+	partition, err := adp.getPartition(key.Namespace)
+	if err != nil {
+		return err
+	}
+	result, err := msgpack.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error marshalling value: %w", err)
+	}
+	if err = adp.client.Set(ctx, key.EncodedKey, result, partition.Ttl).Err(); err != nil {
+		return redisErrorf("error setting item in Redis: %w", err)
+	}
+	return nil
+}
+
+func (adp *redisCacheAdapter) Get(ctx context.Context, key outport.CacheKey, value any) (bool, error) {
+	zap.S().Debugf("Get item from Redis cache by cacheKey=%s", key)
+	if _, err := adp.getPartition(key.Namespace); err != nil {
+		return false, err
+	}
+	data, err := adp.client.Get(ctx, key.EncodedKey).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, redisErrorf("error getting item from Redis: %w", err)
+	}
+	if err = msgpack.Unmarshal(data, value); err != nil {
+		return false, fmt.Errorf("error unmarshalling value: %w", err)
+	}
+	return true, nil
+}
+
+func (adp *redisCacheAdapter) Del(ctx context.Context, key outport.CacheKey) error {
+	zap.S().Debugf("Delete item in Redis cache by cacheKey=%s", key)
+	if _, err := adp.getPartition(key.Namespace); err != nil {
+		return err
+	}
+	if err := adp.client.Del(ctx, key.EncodedKey).Err(); err != nil {
+		return redisErrorf("error deleting item in Redis: %w", err)
+	}
+	return nil
+}
+
+// redisErrorf formats error of Redis request. Requests failed because Redis server cannot be reached are reported
+// as model.ErrUnavailable, so that they may be retried later.
+func redisErrorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, redis.ErrClosed) {
+		return fmt.Errorf("%w: %w", model.ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	Name   string
+	Phones []string
+}
+
+var testPartition = &outport.CachePartition{Namespace: "test", Ttl: time.Minute, LocalMaxItems: 100}
+
+func newTestRedisCache(t *testing.T) (*miniredis.Miniredis, outport.Cache) {
+	server := miniredis.RunT(t)
+	c := newTestRedisCacheAt(t, server.Addr())
+	c.Register(testPartition)
+	return server, c
+}
+
+// newTestRedisCacheAt returns Redis cache without registered partitions
+func newTestRedisCacheAt(t *testing.T, address string) outport.Cache {
+	c := NewRedisCache(app.CacheConfig{Address: address})
+	t.Cleanup(c.Close)
+	return c
+}
+
+func TestRedisCacheSetGetDel(t *testing.T) {
+	server, c := newTestRedisCache(t)
+	ctx := context.Background()
+	key := outport.CacheKey{Namespace: "test", EncodedKey: "test:tenant:1"}
+
+	var value testValue
+	if found, err := c.Get(ctx, key, &value); found || err != nil {
+		t.Fatalf("empty cache returned found=%t, error %v", found, err)
+	}
+	want := testValue{Name: "John", Phones: []string{"+15035557777"}}
+	if err := c.Set(ctx, key, &want); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists(key.EncodedKey) {
+		t.Errorf("item is not saved in Redis by key %s", key.EncodedKey)
+	}
+	if found, err := c.Get(ctx, key, &value); !found || err != nil || !reflect.DeepEqual(value, want) {
+		t.Errorf("Get returned %+v, found=%t, error %v", value, found, err)
+	}
+	if err := c.Del(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := c.Get(ctx, key, &value); found || err != nil {
+		t.Errorf("deleted item returned found=%t, error %v", found, err)
+	}
+}
+
+func TestRedisCacheTtl(t *testing.T) {
+	server, c := newTestRedisCache(t)
+	ctx := context.Background()
+	key := outport.CacheKey{Namespace: "test", EncodedKey: "test:tenant:1"}
+	if err := c.Set(ctx, key, &testValue{Name: "John"}); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.TTL(key.EncodedKey); ttl != testPartition.Ttl {
+		t.Errorf("item TTL is %s, want %s", ttl, testPartition.Ttl)
+	}
+	server.FastForward(testPartition.Ttl - time.Second)
+	var value testValue
+	if found, _ := c.Get(ctx, key, &value); !found {
+		t.Error("item expired before TTL")
+	}
+	server.FastForward(2 * time.Second)
+	if found, err := c.Get(ctx, key, &value); found || err != nil {
+		t.Errorf("expired item returned found=%t, error %v", found, err)
+	}
+}
+
+func TestRedisCacheUnregisteredPartition(t *testing.T) {
+	_, c := newTestRedisCache(t)
+	ctx := context.Background()
+	key := outport.CacheKey{Namespace: "unknown", EncodedKey: "unknown:tenant:1"}
+	var value testValue
+	if err := c.Set(ctx, key, &value); err == nil {
+		t.Error("Set succeeded for unregistered partition")
+	}
+	if _, err := c.Get(ctx, key, &value); err == nil {
+		t.Error("Get succeeded for unregistered partition")
+	}
+	if err := c.Del(ctx, key); err == nil {
+		t.Error("Del succeeded for unregistered partition")
+	}
+}
+
+func TestRedisCacheUnavailable(t *testing.T) {
+	server, c := newTestRedisCache(t)
+	ctx := context.Background()
+	key := outport.CacheKey{Namespace: "test", EncodedKey: "test:tenant:1"}
+	if err := c.Set(ctx, key, &testValue{Name: "John"}); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Close()
+	started := time.Now()
+	var value testValue
+	if found, err := c.Get(ctx, key, &value); found || !errors.Is(err, model.ErrUnavailable) {
+		t.Errorf("Get returned found=%t, error %v while Redis is down", found, err)
+	}
+	if err := c.Set(ctx, key, &value); !errors.Is(err, model.ErrUnavailable) {
+		t.Errorf("Set returned error %v while Redis is down", err)
+	}
+	if err := c.Del(ctx, key); !errors.Is(err, model.ErrUnavailable) {
+		t.Errorf("Del returned error %v while Redis is down", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("failed operations took %s", elapsed)
+	}
+
+	// client keeps failing fast after dial errors and checks connection in background, so it recovers shortly
+	if err := server.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	want := testValue{Name: "Jane"}
+	deadline := time.Now().Add(5 * time.Second)
+	for err := c.Set(ctx, key, &want); err != nil; err = c.Set(ctx, key, &want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Set failed after Redis restart: %v", err)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if found, err := c.Get(ctx, key, &value); !found || err != nil || !reflect.DeepEqual(value, want) {
+		t.Errorf("Get returned %+v, found=%t, error %v after Redis restart", value, found, err)
+	}
+}
@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"github.com/vmihailenco/msgpack/v5"
+	"go.uber.org/zap"
+)
+
+const redisInvalidationChannel = "apiserver:cache:invalidations"
+
+type invalidationMessage struct {
+	Origin     string // id of instance that published the message
+	Namespace  string
+	EncodedKey string
+}
+
+// redisInvalidationBus broadcasts cache invalidations through Redis pub/sub channel
+type redisInvalidationBus struct {
+	client     *redis.Client
+	instanceID string
+}
+
+func newRedisInvalidationBus(client *redis.Client) *redisInvalidationBus {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return &redisInvalidationBus{
+		client:     client,
+		instanceID: hex.EncodeToString(id),
+	}
+}
+
+func (b *redisInvalidationBus) Publish(ctx context.Context, key outport.CacheKey) error {
+	msg, err := msgpack.Marshal(&invalidationMessage{
+		Origin:     b.instanceID,
+		Namespace:  key.Namespace,
+		EncodedKey: key.EncodedKey,
+	})
+	if err != nil {
+		return fmt.Errorf("error marshalling cache invalidation message: %w", err)
+	}
+	if err = b.client.Publish(ctx, redisInvalidationChannel, msg).Err(); err != nil {
+		return redisErrorf("error publishing cache invalidation: %w", err)
+	}
+	return nil
+}
+
+func (b *redisInvalidationBus) Subscribe(handler func(key outport.CacheKey)) func() {
+	// go-redis client re-subscribes automatically when connection to Redis server is restored
+	sub := b.client.Subscribe(context.Background(), redisInvalidationChannel)
+	go func() {
+		for m := range sub.Channel() {
+			var msg invalidationMessage
+			if err := msgpack.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				zap.S().Errorln("error unmarshalling cache invalidation message:", err)
+				continue
+			}
+			if msg.Origin == b.instanceID {
+				continue
+			}
+			handler(outport.CacheKey{Namespace: msg.Namespace, EncodedKey: msg.EncodedKey})
+		}
+	}()
+	return func() {
+		if err := sub.Close(); err != nil {
+			zap.S().Warnln("failed to close cache invalidation subscription:", err)
+		}
+	}
+}
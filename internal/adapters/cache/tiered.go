@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"go.uber.org/zap"
+)
+
+// InvalidationBus delivers keys of changed cache items to all service instances
+type InvalidationBus interface {
+	Publish(ctx context.Context, key outport.CacheKey) error
+	// Subscribe invokes handler for every key published by other instances, returned function stops subscription
+	Subscribe(handler func(key outport.CacheKey)) (unsubscribe func())
+}
+
+// tieredCacheAdapter keeps recently used items in process memory (L1, TinyLFU limited by LocalMaxItems) in front
+// of a shared remote cache (L2, items expire after Ttl). Every Del is broadcast through invalidation bus, so that
+// other instances evict their L1 copies and read the new value from L2. Set only fills both layers, so it is not
+// broadcast: changed item must be deleted before the new value is set.
+type tieredCacheAdapter struct {
+	local       *inMemCacheAdapter
+	remote      outport.Cache
+	bus         InvalidationBus
+	unsubscribe func()
+}
+
+func NewTieredCache(remote outport.Cache, bus InvalidationBus) outport.Cache {
+	adp := &tieredCacheAdapter{
+		local:  NewInMemCache().(*inMemCacheAdapter),
+		remote: remote,
+		bus:    bus,
+	}
+	adp.unsubscribe = bus.Subscribe(adp.evictLocal)
+	return adp
+}
+
+// NewTieredRedisCache creates tiered cache with Redis as L2 cache and Redis pub/sub as invalidation bus
+func NewTieredRedisCache(cfg app.CacheConfig) outport.Cache {
+	remote := NewRedisCache(cfg).(*redisCacheAdapter)
+	return NewTieredCache(remote, newRedisInvalidationBus(remote.client))
+}
+
+func (adp *tieredCacheAdapter) Close() {
+	adp.unsubscribe()
+	adp.local.Close()
+	adp.remote.Close()
+}
+
+func (adp *tieredCacheAdapter) Register(partition *outport.CachePartition) {
+	adp.local.Register(partition)
+	adp.remote.Register(partition)
+}
+
+// Set updates both layers, other instances keep their L1 copies
+func (adp *tieredCacheAdapter) Set(ctx context.Context, key outport.CacheKey, value any) error {
+	return firstError(
+		adp.local.Set(ctx, key, value),
+		adp.remote.Set(ctx, key, value),
+	)
+}
+
+func (adp *tieredCacheAdapter) Get(ctx context.Context, key outport.CacheKey, value any) (bool, error) {
+	found, localErr := adp.local.Get(ctx, key, value)
+	if found && localErr == nil {
+		return true, nil
+	}
+	found, err := adp.remote.Get(ctx, key, value)
+	if err != nil || !found {
+		return false, firstError(localErr, err)
+	}
+	if localErr == nil {
+		localErr = adp.local.Set(ctx, key, value)
+	}
+	if localErr != nil {
+		// value was found in L2 anyway, so L1 failure is not reported to the caller
+		zap.S().Warnf("Local cache failed for cacheKey=%s: %v", key, localErr)
+	}
+	return true, nil
+}
+
+// Del deletes item from both layers and notifies other instances even if one of layers fails
+func (adp *tieredCacheAdapter) Del(ctx context.Context, key outport.CacheKey) error {
+	return firstError(
+		adp.local.Del(ctx, key),
+		adp.remote.Del(ctx, key),
+		adp.bus.Publish(ctx, key),
+	)
+}
+
+func (adp *tieredCacheAdapter) evictLocal(key outport.CacheKey) {
+	if _, err := adp.local.getCacheChunk(key.Namespace); err != nil {
+		// another instance may run a different version of service with partitions unknown to this one
+		return
+	}
+	zap.S().Debugf("Evict item from local cache by cacheKey=%s on invalidation from another instance", key)
+	if err := adp.local.Del(context.Background(), key); err != nil {
+		zap.S().Errorf("Failed to evict item from local cache by cacheKey=%s: %v", key, err)
+	}
+}
+
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
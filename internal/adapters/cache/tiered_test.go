@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBus delivers published keys to subscribers of other buses sharing the same network
+type testBus struct {
+	network   *testBusNetwork
+	handler   func(key outport.CacheKey)
+	published []outport.CacheKey
+}
+
+type testBusNetwork struct {
+	mu    sync.Mutex
+	buses []*testBus
+}
+
+func (n *testBusNetwork) newBus() *testBus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	b := &testBus{network: n}
+	n.buses = append(n.buses, b)
+	return b
+}
+
+func (b *testBus) Publish(_ context.Context, key outport.CacheKey) error {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+	b.published = append(b.published, key)
+	for _, other := range b.network.buses {
+		if other != b && other.handler != nil {
+			other.handler(key)
+		}
+	}
+	return nil
+}
+
+func (b *testBus) Subscribe(handler func(key outport.CacheKey)) func() {
+	b.network.mu.Lock()
+	defer b.network.mu.Unlock()
+	b.handler = handler
+	return func() {}
+}
+
+func TestTieredCacheInvalidatesOnDelOnly(t *testing.T) {
+	server, _ := newTestRedisCache(t)
+	network := &testBusNetwork{}
+	newInstance := func() (outport.Cache, *testBus) {
+		remote := newTestRedisCacheAt(t, server.Addr())
+		bus := network.newBus()
+		c := NewTieredCache(remote, bus)
+		c.Register(testPartition)
+		return c, bus
+	}
+	first, firstBus := newInstance()
+	second, _ := newInstance()
+	ctx := context.Background()
+	key := outport.CacheKey{Namespace: "test", EncodedKey: "test:tenant:1"}
+
+	// both instances fill their L1 caches, fills are not broadcast
+	if err := first.Set(ctx, key, &testValue{Name: "John"}); err != nil {
+		t.Fatal(err)
+	}
+	var value testValue
+	if found, err := second.Get(ctx, key, &value); !found || err != nil || value.Name != "John" {
+		t.Fatalf("second instance returned %+v, found=%t, error %v", value, found, err)
+	}
+	if err := second.Set(ctx, key, &testValue{Name: "John"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(firstBus.published) != 0 {
+		t.Errorf("Set published invalidations %v", firstBus.published)
+	}
+
+	// the first instance changes the item, the second one drops its L1 copy
+	if err := first.Del(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Set(ctx, key, &testValue{Name: "Jane"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(firstBus.published) != 1 {
+		t.Errorf("Del published %d invalidations, want 1", len(firstBus.published))
+	}
+	if found, err := second.Get(ctx, key, &value); !found || err != nil || value.Name != "Jane" {
+		t.Errorf("second instance returned %+v, found=%t, error %v after invalidation", value, found, err)
+	}
+}
+
+func TestTieredCacheInvalidationWhileRegistering(t *testing.T) {
+	server, _ := newTestRedisCache(t)
+	remote := newTestRedisCacheAt(t, server.Addr())
+	network := &testBusNetwork{}
+	publisher := network.newBus()
+	c := NewTieredCache(remote, network.newBus())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = publisher.Publish(context.Background(), outport.CacheKey{Namespace: fmt.Sprintf("ns%d", i)})
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		c.Register(&outport.CachePartition{Namespace: fmt.Sprintf("ns%d", i), Ttl: time.Minute, LocalMaxItems: 10})
+	}
+	<-done
+}
@@ -0,0 +1,180 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	jwksFetchTimeout = 10 * time.Second
+	// jwksMinRefetch limits how often keys are fetched, so that tokens with made up key ids
+	// (or unavailable identity provider) do not result in flood of requests
+	jwksMinRefetch = time.Minute
+)
+
+// keySet keeps signing keys of identity provider. Keys loaded from URL are reloaded periodically and
+// whenever token refers to unknown key (identity provider has rotated its keys).
+type keySet struct {
+	fetch      func(ctx context.Context) (*jose.JSONWebKeySet, error)
+	refresh    time.Duration // zero means keys are never reloaded
+	minRefetch time.Duration // least time between fetch attempts
+
+	mu          sync.Mutex
+	keys        *jose.JSONWebKeySet
+	loadedAt    time.Time
+	attemptedAt time.Time     // last time keys were fetched, successfully or not
+	fetchErr    error         // error of last fetch attempt
+	fetching    chan struct{} // closed when ongoing fetch completes, nil if keys are not being fetched
+}
+
+func newFileKeySet(filename string) (*keySet, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseKeySet(data)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %w", filename, err)
+	}
+	return &keySet{keys: keys, loadedAt: time.Now()}, nil
+}
+
+func newURLKeySet(url string, refresh time.Duration) *keySet {
+	client := &http.Client{Timeout: jwksFetchTimeout}
+	fetch := func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("fetching signing keys failed: %w", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetching signing keys from %s failed with status %s", url, resp.Status)
+		}
+		data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return nil, fmt.Errorf("fetching signing keys failed: %w", err)
+		}
+		keys, err := parseKeySet(data)
+		if err != nil {
+			return nil, fmt.Errorf("signing keys from %s: %w", url, err)
+		}
+		return keys, nil
+	}
+	return &keySet{fetch: fetch, refresh: refresh, minRefetch: jwksMinRefetch}
+}
+
+func parseKeySet(data []byte) (*jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %w", err)
+	}
+	if len(keys.Keys) == 0 {
+		return nil, errors.New("JWKS document contains no keys")
+	}
+	return &keys, nil
+}
+
+// key returns public key to verify token signature. Empty key id is accepted only if there is a single signing key.
+func (ks *keySet) key(ctx context.Context, kid string) (*jose.JSONWebKey, error) {
+	ks.mu.Lock()
+	keys, loadedAt := ks.keys, ks.loadedAt
+	ks.mu.Unlock()
+
+	if keys == nil || (ks.refresh > 0 && time.Since(loadedAt) > ks.refresh) {
+		var fetchErr error
+		keys, _, fetchErr = ks.reload(ctx)
+		if keys == nil {
+			return nil, fmt.Errorf("%w: signing keys of identity provider are not available: %v",
+				model.ErrUnavailable, fetchErr)
+		}
+	}
+	key := findSigningKey(keys, kid)
+	if key == nil {
+		if reloaded, ok, _ := ks.reload(ctx); ok {
+			key = findSigningKey(reloaded, kid)
+		}
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: unknown signing key kid=%q", model.ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// reload fetches keys from URL unless it was attempted recently, previously loaded keys are kept if fetching fails.
+// Keys are fetched without holding the lock, concurrent callers wait for the same fetch.
+// It returns current keys, true if they were reloaded, and error of the last fetch attempt.
+func (ks *keySet) reload(ctx context.Context) (*jose.JSONWebKeySet, bool, error) {
+	ks.mu.Lock()
+	if done := ks.fetching; done != nil {
+		ks.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+		ks.mu.Lock()
+		defer ks.mu.Unlock()
+		return ks.keys, ks.fetchErr == nil && ks.loadedAt == ks.attemptedAt, ks.fetchErr
+	}
+	if ks.fetch == nil || time.Since(ks.attemptedAt) < ks.minRefetch {
+		defer ks.mu.Unlock()
+		return ks.keys, false, ks.fetchErr
+	}
+	attemptedAt := time.Now()
+	done := make(chan struct{})
+	ks.attemptedAt, ks.fetching = attemptedAt, done
+	ks.mu.Unlock()
+
+	// fetch is shared with other callers, so it is not canceled with the request of this one
+	fetchCtx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	keys, err := ks.fetch(fetchCtx)
+	cancel()
+	if err != nil {
+		app.Logger(ctx).Warnln(err)
+	} else {
+		app.Logger(ctx).Infof("Loaded %d signing keys of identity provider", len(keys.Keys))
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.fetchErr, ks.fetching = err, nil
+	close(done)
+	if err != nil {
+		return ks.keys, false, err
+	}
+	ks.keys, ks.loadedAt = keys, attemptedAt
+	return keys, true, nil
+}
+
+func findSigningKey(keys *jose.JSONWebKeySet, kid string) *jose.JSONWebKey {
+	var found *jose.JSONWebKey
+	for i := range keys.Keys {
+		k := &keys.Keys[i]
+		if k.Use == "enc" || !k.IsPublic() || !k.Valid() {
+			continue
+		}
+		if kid != "" && k.KeyID == kid {
+			return k
+		}
+		if kid == "" {
+			if found != nil {
+				return nil // ambiguous
+			}
+			found = k
+		}
+	}
+	return found
+}
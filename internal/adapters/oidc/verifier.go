@@ -0,0 +1,152 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	defaultJWKSRefresh = time.Hour
+	defaultClockSkew   = time.Minute
+	defaultRolesClaim  = "roles"
+)
+
+// signingAlgorithms lists accepted token signature algorithms. Symmetric algorithms are not accepted,
+// because the keys are published by identity provider.
+var signingAlgorithms = map[string]bool{
+	string(jose.RS256): true, string(jose.RS384): true, string(jose.RS512): true,
+	string(jose.PS256): true, string(jose.PS384): true, string(jose.PS512): true,
+	string(jose.ES256): true, string(jose.ES384): true, string(jose.ES512): true,
+	string(jose.EdDSA): true,
+}
+
+// tokenVerifier validates JWT signature with keys published by identity provider (JWKS),
+// as well as issuer, audience and expiry claims
+type tokenVerifier struct {
+	issuer      string
+	audience    string
+	clockSkew   time.Duration
+	tenantClaim string
+	rolesClaim  string
+	keys        *keySet
+}
+
+func NewTokenVerifier(cfg app.OIDCConfig) outport.TokenVerifier {
+	if cfg.Issuer == "" || cfg.Audience == "" {
+		zap.S().Fatalln("oidc.issuer and oidc.audience settings are required to accept bearer tokens")
+	}
+	clockSkew := cfg.ClockSkew
+	if clockSkew == 0 {
+		clockSkew = defaultClockSkew
+	}
+	rolesClaim := cfg.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = defaultRolesClaim
+	}
+	v := &tokenVerifier{
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		clockSkew:   clockSkew,
+		tenantClaim: cfg.TenantClaim,
+		rolesClaim:  rolesClaim,
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := newFileKeySet(cfg.JWKSFile)
+		if err != nil {
+			zap.S().Fatalln("failed to load signing keys:", err)
+		}
+		v.keys = keys
+	} else {
+		refresh := cfg.JWKSRefresh
+		if refresh == 0 {
+			refresh = defaultJWKSRefresh
+		}
+		v.keys = newURLKeySet(cfg.JWKSURL, refresh)
+	}
+	zap.S().Infof("Bearer tokens are accepted from issuer=%s for audience=%s", cfg.Issuer, cfg.Audience)
+	return v
+}
+
+func (v *tokenVerifier) VerifyToken(ctx context.Context, rawToken string) (*app.Identity, error) {
+	tok, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidToken, err)
+	}
+	if len(tok.Headers) != 1 {
+		return nil, fmt.Errorf("%w: token must have exactly one signature", model.ErrInvalidToken)
+	}
+	header := tok.Headers[0]
+	if !signingAlgorithms[header.Algorithm] {
+		return nil, fmt.Errorf("%w: unsupported signing algorithm %q", model.ErrInvalidToken, header.Algorithm)
+	}
+	key, err := v.keys.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+		return nil, fmt.Errorf("%w: key kid=%s is not used with %s algorithm",
+			model.ErrInvalidToken, key.KeyID, header.Algorithm)
+	}
+
+	var std jwt.Claims
+	claims := make(map[string]any)
+	if err = tok.Claims(key.Key, &std, &claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidToken, err)
+	}
+	if std.Expiry == nil {
+		return nil, fmt.Errorf("%w: token has no expiry", model.ErrInvalidToken)
+	}
+	err = std.ValidateWithLeeway(jwt.Expected{
+		Issuer:   v.issuer,
+		Audience: jwt.Audience{v.audience},
+		Time:     time.Now(),
+	}, v.clockSkew)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", model.ErrInvalidToken, err)
+	}
+	if std.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", model.ErrInvalidToken)
+	}
+	return &app.Identity{
+		Subject: std.Subject,
+		Tenant:  v.tenantOf(std.Subject, claims),
+		Roles:   v.rolesOf(claims),
+		Claims:  claims,
+	}, nil
+}
+
+// tenantOf returns organisation from tenant claim if it is present, otherwise subject owns address book
+func (v *tokenVerifier) tenantOf(subject string, claims map[string]any) string {
+	if v.tenantClaim != "" {
+		if tenant, ok := claims[v.tenantClaim].(string); ok && tenant != "" {
+			return tenant
+		}
+	}
+	return subject
+}
+
+// rolesOf returns roles from roles claim, which is either a list of strings or a space separated string
+func (v *tokenVerifier) rolesOf(claims map[string]any) []string {
+	switch roles := claims[v.rolesClaim].(type) {
+	case string:
+		return strings.Fields(roles)
+	case []any:
+		var result []string
+		for _, role := range roles {
+			if s, ok := role.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,249 @@
+package oidc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	testIssuer   = "https://idp.example.com/"
+	testAudience = "addrbook-api"
+)
+
+// testIdP publishes public keys of its current signing keys as JWKS document and counts fetches
+type testIdP struct {
+	mu      sync.Mutex
+	keys    []jose.JSONWebKey // private keys
+	fetches atomic.Int32
+	server  *httptest.Server
+}
+
+func newTestIdP(t *testing.T, keys ...jose.JSONWebKey) *testIdP {
+	idp := &testIdP{keys: keys}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.fetches.Add(1)
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		var published jose.JSONWebKeySet
+		for _, k := range idp.keys {
+			published.Keys = append(published.Keys, k.Public())
+		}
+		_ = json.NewEncoder(w).Encode(published)
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) rotate(keys ...jose.JSONWebKey) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.keys = keys
+}
+
+func (idp *testIdP) verifier() *tokenVerifier {
+	return NewTokenVerifier(app.OIDCConfig{
+		Issuer:   testIssuer,
+		Audience: testAudience,
+		JWKSURL:  idp.server.URL,
+	}).(*tokenVerifier)
+}
+
+func newRSAKey(t *testing.T, kid string) jose.JSONWebKey {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jose.JSONWebKey{Key: private, KeyID: kid, Algorithm: string(jose.RS256), Use: "sig"}
+}
+
+func newECKey(t *testing.T, kid string) jose.JSONWebKey {
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jose.JSONWebKey{Key: private, KeyID: kid, Use: "sig"}
+}
+
+func validClaims() jwt.Claims {
+	now := time.Now()
+	return jwt.Claims{
+		Issuer:   testIssuer,
+		Audience: jwt.Audience{testAudience},
+		Subject:  "user-1",
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(now.Add(time.Hour)),
+	}
+}
+
+func signToken(t *testing.T, key jose.JSONWebKey, alg jose.SignatureAlgorithm, claims jwt.Claims) string {
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: alg, Key: key},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestVerifyToken(t *testing.T) {
+	key := newRSAKey(t, "key-1")
+	v := newTestIdP(t, key).verifier()
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	identity, err := v.VerifyToken(ctx, signToken(t, key, jose.RS256, validClaims()))
+	if err != nil {
+		t.Fatalf("valid token is rejected: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Tenant != "user-1" {
+		t.Errorf("token is verified as subject=%s tenant=%s", identity.Subject, identity.Tenant)
+	}
+}
+
+func TestVerifyTokenRejectsInvalidClaims(t *testing.T) {
+	key := newRSAKey(t, "key-1")
+	v := newTestIdP(t, key).verifier()
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	tests := map[string]func(c *jwt.Claims){
+		"issuer":   func(c *jwt.Claims) { c.Issuer = "https://other.example.com/" },
+		"audience": func(c *jwt.Claims) { c.Audience = jwt.Audience{"other-api"} },
+		"expired": func(c *jwt.Claims) {
+			c.IssuedAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			c.Expiry = jwt.NewNumericDate(time.Now().Add(-defaultClockSkew - time.Minute))
+		},
+		"no expiry":  func(c *jwt.Claims) { c.Expiry = nil },
+		"no subject": func(c *jwt.Claims) { c.Subject = "" },
+	}
+	for name, modify := range tests {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims()
+			modify(&claims)
+			_, err := v.VerifyToken(ctx, signToken(t, key, jose.RS256, claims))
+			if !errors.Is(err, model.ErrInvalidToken) {
+				t.Errorf("token is not rejected as invalid, error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyTokenAcceptsOnlyAllowedAlgorithms(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa")
+	ecKey := newECKey(t, "ec")
+	v := newTestIdP(t, rsaKey, ecKey).verifier()
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	if _, err := v.VerifyToken(ctx, signToken(t, ecKey, jose.ES256, validClaims())); err != nil {
+		t.Errorf("ES256 token is rejected: %v", err)
+	}
+	// symmetric algorithms are not accepted, otherwise published public key could be used as HMAC secret
+	hmacKey := jose.JSONWebKey{Key: []byte("0123456789abcdef0123456789abcdef"), KeyID: "rsa"}
+	if _, err := v.VerifyToken(ctx, signToken(t, hmacKey, jose.HS256, validClaims())); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("HS256 token is not rejected as invalid, error: %v", err)
+	}
+	// key published for RS256 is not used with other algorithms
+	if _, err := v.VerifyToken(ctx, signToken(t, rsaKey, jose.PS256, validClaims())); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("PS256 token signed with RS256 key is not rejected as invalid, error: %v", err)
+	}
+}
+
+func TestVerifyTokenAfterKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t, "key-1"), newRSAKey(t, "key-2")
+	idp := newTestIdP(t, oldKey)
+	v := idp.verifier()
+	v.keys.minRefetch = 0
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	if _, err := v.VerifyToken(ctx, signToken(t, oldKey, jose.RS256, validClaims())); err != nil {
+		t.Fatalf("token signed with old key is rejected: %v", err)
+	}
+	idp.rotate(newKey)
+	if _, err := v.VerifyToken(ctx, signToken(t, newKey, jose.RS256, validClaims())); err != nil {
+		t.Fatalf("token signed with new key is rejected: %v", err)
+	}
+	if fetches := idp.fetches.Load(); fetches != 2 {
+		t.Errorf("keys were fetched %d times, want 2", fetches)
+	}
+	if _, err := v.VerifyToken(ctx, signToken(t, oldKey, jose.RS256, validClaims())); !errors.Is(err, model.ErrInvalidToken) {
+		t.Errorf("token signed with retired key is not rejected as invalid, error: %v", err)
+	}
+}
+
+func TestUnknownKeyReloadsAreRateLimited(t *testing.T) {
+	key, unknownKey := newRSAKey(t, "key-1"), newRSAKey(t, "unknown")
+	idp := newTestIdP(t, key)
+	v := idp.verifier()
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	if _, err := v.VerifyToken(ctx, signToken(t, key, jose.RS256, validClaims())); err != nil {
+		t.Fatalf("valid token is rejected: %v", err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := v.VerifyToken(ctx, signToken(t, unknownKey, jose.RS256, validClaims())); !errors.Is(err, model.ErrInvalidToken) {
+				t.Errorf("token with unknown key is not rejected as invalid, error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches := idp.fetches.Load(); fetches != 1 {
+		t.Errorf("keys were fetched %d times, want unknown key ids to be ignored within %v", fetches, jwksMinRefetch)
+	}
+
+	v.keys.mu.Lock()
+	v.keys.attemptedAt = v.keys.attemptedAt.Add(-jwksMinRefetch)
+	v.keys.mu.Unlock()
+	_, _ = v.VerifyToken(ctx, signToken(t, unknownKey, jose.RS256, validClaims()))
+	if fetches := idp.fetches.Load(); fetches != 2 {
+		t.Errorf("keys were fetched %d times, want reload once %v has passed", fetches, jwksMinRefetch)
+	}
+}
+
+func TestConcurrentReloadsShareFetch(t *testing.T) {
+	key := newRSAKey(t, "key-1")
+	idp := newTestIdP(t, key)
+	release := make(chan struct{})
+	fetch := newURLKeySet(idp.server.URL, 0).fetch
+	ks := &keySet{refresh: 0, fetch: func(ctx context.Context) (*jose.JSONWebKeySet, error) {
+		<-release
+		return fetch(ctx)
+	}}
+	ctx := app.BackgroundContextWithDefaultLogger()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := ks.key(ctx, "key-1"); err != nil {
+				t.Errorf("key is not loaded: %v", err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond) // let all callers wait for the fetch
+	close(release)
+	wg.Wait()
+	if fetches := idp.fetches.Load(); fetches != 1 {
+		t.Errorf("keys were fetched %d times, want 1", fetches)
+	}
+}
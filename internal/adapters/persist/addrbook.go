@@ -2,6 +2,7 @@ package persist
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/samber/lo"
 	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/cache"
@@ -10,11 +11,14 @@ import (
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"time"
 )
 
 type addrBookAdapter struct {
-	repo             *repo.AddrBookRepo
-	contactByIdCache cache.ContactByIdPartition
+	repo                 *repo.AddrBookRepo
+	groupRepo            *repo.GroupRepo
+	contactByIdCache     cache.ContactByIdPartition
+	contactsByPhoneCache cache.ContactsByPhonePartition
 }
 
 func NewAddrBookAdapter(
@@ -22,82 +26,455 @@ func NewAddrBookAdapter(
 	c outport.Cache,
 ) outport.AddrBook {
 	return &addrBookAdapter{
-		repo:             repo.NewAddrBookRepo(p.DB()),
-		contactByIdCache: cache.RegisterContactByID(c),
+		repo:                 repo.NewAddrBookRepo(p.DB()),
+		groupRepo:            repo.NewGroupRepo(p.DB()),
+		contactByIdCache:     cache.RegisterContactByID(c),
+		contactsByPhoneCache: cache.RegisterContactsByPhone(c),
 	}
 }
 
-func (a *addrBookAdapter) LoadAllContacts(ctx context.Context) ([]*model.Contact, error) {
-	all, err := a.repo.SelectAllContacts(ctx)
+// errNoTenant prevents access to address books by callers (or background jobs) without tenant, the caller may be
+// authenticated, but it is not allowed to access any address book
+var errNoTenant = fmt.Errorf("%w: caller tenant is unknown, address book is not available", model.ErrForbidden)
+
+// tenantOf returns tenant of the caller, every operation is scoped to address book of this tenant,
+// so contacts of other tenants look as if they do not exist
+func tenantOf(ctx context.Context) (string, error) {
+	tenant := app.Tenant(ctx)
+	if tenant == "" {
+		return "", errNoTenant
+	}
+	return tenant, nil
+}
+
+// actorOf returns subject of the caller which is recorded in contact revisions
+func actorOf(ctx context.Context) string {
+	if identity := app.CallerIdentity(ctx); identity != nil {
+		return identity.Subject
+	}
+	return ""
+}
+
+func (a *addrBookAdapter) LoadContacts(ctx context.Context, q *model.ContactListQuery) (*model.ContactListPage, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	params, err := mapper.ContactListQueryToParams(q)
+	if err != nil {
+		return nil, err
+	}
+	params.Filter.TenantID = tenant
+	// one extra contact is requested to find out whether there is a next page
+	params.Limit = q.Limit + 1
+	entities, err := a.repo.SelectContactsPage(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	total, err := a.repo.CountContacts(ctx, params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &model.ContactListPage{TotalCount: total}
+	if len(entities) > q.Limit {
+		entities = entities[:q.Limit]
+		page.NextCursor = mapper.EncodeContactListCursor(params.Order, params.Order.CursorOf(entities[len(entities)-1]))
+	}
+	page.Contacts = lo.Map(entities, func(item *repo.ContactWithPhonesEntity, _ int) *model.Contact {
+		return mapper.ContactEntityToModel(item)
+	})
+	return page, nil
+}
+
+func (a *addrBookAdapter) SearchContacts(ctx context.Context, text string, limit int) ([]*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := a.repo.SearchContacts(ctx, tenant, text, limit)
 	if err != nil {
 		return nil, err
 	}
-	return lo.Map(all, func(item *repo.ContactWithPhonesEntity, _ int) *model.Contact {
+	return lo.Map(entities, func(item *repo.ContactWithPhonesEntity, _ int) *model.Contact {
 		return mapper.ContactEntityToModel(item)
 	}), nil
 }
 
 func (a *addrBookAdapter) LoadContactByID(ctx context.Context, ID string) (*model.Contact, error) {
-	if cachedContact := a.contactByIdCache.Get(ctx, ID); cachedContact != nil {
-		return cachedContact, nil
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
 	}
 	repoID, err := mapper.ModelIdToRepoId(ID)
 	if err != nil {
-		panic(fmt.Sprintf("invalid contact ID: %s", ID))
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil // no error is needed, we assume that record does not exist
 	}
-	entity, err := a.repo.SelectContactByID(ctx, repoID)
+	return a.contactByIdCache.GetOrBuild(ctx, tenant, ID, func(ctx context.Context) (*model.Contact, bool, error) {
+		contact, err := a.selectContactByID(ctx, tenant, repoID)
+		return contact, contact == nil, err
+	})
+}
+
+func (a *addrBookAdapter) LoadCurrentContactByID(ctx context.Context, ID string) (*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil // no error is needed, we assume that record does not exist
+	}
+	return a.selectContactByID(ctx, tenant, repoID)
+}
+
+func (a *addrBookAdapter) selectContactByID(ctx context.Context, tenant string, repoID int64) (*model.Contact, error) {
+	entity, err := a.repo.SelectContactByID(ctx, tenant, repoID)
 	if err != nil || entity == nil {
 		return nil, err
 	}
-	m := mapper.ContactEntityToModel(entity)
-	a.contactByIdCache.Set(ctx, m)
-	return m, nil
+	return mapper.ContactEntityToModel(entity), nil
+}
+
+func (a *addrBookAdapter) FindContactsByPhone(ctx context.Context, phoneE164 string) ([]*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a.contactsByPhoneCache.GetOrBuild(ctx, tenant, phoneE164, func(ctx context.Context) (*[]*model.Contact, bool, error) {
+		entities, err := a.repo.SelectContactsByPhone(ctx, tenant, phoneE164)
+		if err != nil {
+			return nil, true, err
+		}
+		contacts := lo.Map(entities, func(item *repo.ContactWithPhonesEntity, _ int) *model.Contact {
+			return mapper.ContactEntityToModel(item)
+		})
+		return &contacts, false, nil
+	})
 }
 
 func (a *addrBookAdapter) AddContact(ctx context.Context, c *model.ContactToSave) (*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	entity := mapper.ContactToSaveModelToEntity(c)
-	entity, err := a.repo.AddContact(ctx, entity)
+	entity, err = a.repo.AddContact(ctx, tenant, actorOf(ctx), entity)
 	if err != nil {
 		return nil, err
 	}
+	// saved custom field values have field ids only, names and types of the fields are selected with contact
+	entity, err = a.repo.SelectContactByID(ctx, tenant, entity.ID)
+	if err != nil || entity == nil {
+		return nil, err
+	}
 	contact := mapper.ContactEntityToModel(entity)
-	a.contactByIdCache.Set(ctx, contact)
+	a.contactByIdCache.Set(ctx, tenant, contact)
+	a.forgetPhonesOf(ctx, tenant, entity)
 	return contact, nil
 }
 
 func (a *addrBookAdapter) UpdateContact(ctx context.Context, ID string, c *model.ContactToSave) (*model.Contact, error) {
-	var err error
+	return a.replaceContact(ctx, ID, c, func(tenant string, entity *repo.ContactWithPhonesEntity) (bool, error) {
+		return a.repo.UpdateContact(ctx, tenant, actorOf(ctx), entity)
+	})
+}
+
+func (a *addrBookAdapter) RevertContact(
+	ctx context.Context,
+	ID string,
+	revision int,
+	c *model.ContactToSave,
+) (*model.Contact, error) {
+	return a.replaceContact(ctx, ID, c, func(tenant string, entity *repo.ContactWithPhonesEntity) (bool, error) {
+		return a.repo.RevertContact(ctx, tenant, actorOf(ctx), entity, revision)
+	})
+}
+
+// replaceContact saves contact with given save function and refreshes cached contact
+func (a *addrBookAdapter) replaceContact(
+	ctx context.Context,
+	ID string,
+	c *model.ContactToSave,
+	save func(tenant string, entity *repo.ContactWithPhonesEntity) (found bool, err error),
+) (*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
 	entity := mapper.ContactToSaveModelToEntity(c)
 	entity.ID, err = mapper.ModelIdToRepoId(ID)
 	if err != nil {
 		app.Logger(ctx).Debugln("error parsing id:", ID)
 		return nil, nil
 	}
-	found, err := a.repo.UpdateContact(ctx, entity)
+	// phones of the contact before update are needed to invalidate contacts cached by these phones
+	oldEntity, err := a.repo.SelectContactByID(ctx, tenant, entity.ID)
+	if err != nil || oldEntity == nil {
+		return nil, err
+	}
+	found, err := save(tenant, entity)
+	err = versionError(ctx, err, ID)
 	if err == nil {
 		if !found {
 			return nil, nil
 		}
-		entity, err = a.repo.SelectContactByID(ctx, entity.ID)
+		entity, err = a.repo.SelectContactByID(ctx, tenant, entity.ID)
 		if err != nil || entity == nil {
 			return nil, err
 		}
 		contact := mapper.ContactEntityToModel(entity)
-		a.contactByIdCache.Set(ctx, contact)
+		a.contactByIdCache.Set(ctx, tenant, contact)
+		a.forgetPhonesOf(ctx, tenant, oldEntity, entity)
 		return contact, nil
 	}
 	return nil, err
 }
 
-func (a *addrBookAdapter) DeleteContact(ctx context.Context, ID string) (found bool, err error) {
+func (a *addrBookAdapter) DeleteContact(ctx context.Context, ID string, version int) (found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
 	repoID, err := mapper.ModelIdToRepoId(ID)
 	if err != nil {
 		app.Logger(ctx).Debugln("error parsing id:", ID)
 		return false, nil // no error is needed, we assume that record does not exist
 	}
-	found, err = a.repo.DeleteContact(ctx, repoID)
-	if found {
-		a.contactByIdCache.Del(ctx, ID)
+	oldEntity, err := a.repo.SelectContactByID(ctx, tenant, repoID)
+	if err != nil || oldEntity == nil {
+		return false, err
+	}
+	found, err = a.repo.DeleteContact(ctx, tenant, actorOf(ctx), repoID, version)
+	err = versionError(ctx, err, ID)
+	if found && err == nil {
+		a.contactByIdCache.Del(ctx, tenant, ID)
+		a.forgetPhonesOf(ctx, tenant, oldEntity)
 	}
 	return
 }
+
+// contactChange is a contact changed in transaction, it is refreshed in caches after the transaction is committed
+type contactChange struct {
+	oldEntity *repo.ContactWithPhonesEntity // nil for created contact
+	contact   *model.Contact                // nil for deleted contact
+	entity    *repo.ContactWithPhonesEntity // nil for deleted contact
+}
+
+func (a *addrBookAdapter) ApplyContactOperations(
+	ctx context.Context,
+	ops []*model.ContactOperation,
+) ([]*model.ContactOperationResult, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t := a.repo.BeginTx(ctx, tenant, actorOf(ctx))
+	defer t.Rollback()
+
+	results := make([]*model.ContactOperationResult, 0, len(ops))
+	changes := make([]*contactChange, 0, len(ops))
+	for _, op := range ops {
+		change, err := a.applyContactOperation(ctx, t, op)
+		if err != nil && !errors.Is(err, model.ErrNotFound) && !errors.Is(err, model.ErrPreconditionFailed) {
+			return nil, err
+		}
+		results = append(results, &model.ContactOperationResult{Contact: change.contact, Err: err})
+		if err != nil {
+			return results, nil // transaction is rolled back
+		}
+		changes = append(changes, change)
+	}
+	if err = t.Commit(); err != nil {
+		return nil, err
+	}
+	for i, change := range changes {
+		if change.contact != nil {
+			a.contactByIdCache.Set(ctx, tenant, change.contact)
+			a.forgetPhonesOf(ctx, tenant, change.entity)
+		} else {
+			a.contactByIdCache.Del(ctx, tenant, ops[i].ID)
+		}
+		if change.oldEntity != nil {
+			a.forgetPhonesOf(ctx, tenant, change.oldEntity)
+		}
+	}
+	return results, nil
+}
+
+// applyContactOperation applies operation within transaction, missing contact is reported as model.ErrNotFound
+func (a *addrBookAdapter) applyContactOperation(
+	ctx context.Context,
+	t *repo.AddrBookTx,
+	op *model.ContactOperation,
+) (*contactChange, error) {
+	change := &contactChange{}
+	var err error
+	var repoID int64
+	if op.Type != model.ContactOperationCreate {
+		if repoID, err = mapper.ModelIdToRepoId(op.ID); err != nil {
+			app.Logger(ctx).Debugln("error parsing id:", op.ID)
+		} else if change.oldEntity, err = t.SelectContactByID(ctx, repoID); err != nil {
+			return change, err
+		}
+		if change.oldEntity == nil {
+			return change, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, op.ID)
+		}
+	}
+	switch op.Type {
+	case model.ContactOperationCreate:
+		var entity *repo.ContactWithPhonesEntity
+		if entity, err = t.AddContact(ctx, mapper.ContactToSaveModelToEntity(op.Contact)); err != nil {
+			return change, err
+		}
+		repoID = entity.ID
+	case model.ContactOperationUpdate:
+		entity := mapper.ContactToSaveModelToEntity(op.Contact)
+		entity.ID = repoID
+		if _, err = t.UpdateContact(ctx, entity); err != nil {
+			return change, versionError(ctx, err, op.ID)
+		}
+	case model.ContactOperationDelete:
+		if _, err = t.DeleteContact(ctx, repoID, op.Version); err != nil {
+			return change, versionError(ctx, err, op.ID)
+		}
+		return change, nil
+	}
+	// saved custom field values have field ids only, names and types of the fields are selected with contact
+	if change.entity, err = t.SelectContactByID(ctx, repoID); err != nil {
+		return change, err
+	}
+	change.contact = mapper.ContactEntityToModel(change.entity)
+	return change, nil
+}
+
+func (a *addrBookAdapter) LoadTrashedContacts(ctx context.Context, limit int) ([]*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := a.repo.SelectTrashedContacts(ctx, tenant, limit)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(entities, func(item *repo.ContactWithPhonesEntity, _ int) *model.Contact {
+		return mapper.ContactEntityToModel(item)
+	}), nil
+}
+
+func (a *addrBookAdapter) RestoreContact(ctx context.Context, ID string) (*model.Contact, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil // no error is needed, we assume that record does not exist
+	}
+	found, err := a.repo.RestoreContact(ctx, tenant, actorOf(ctx), repoID)
+	if err != nil || !found {
+		return nil, err
+	}
+	entity, err := a.repo.SelectContactByID(ctx, tenant, repoID)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	contact := mapper.ContactEntityToModel(entity)
+	a.contactByIdCache.Set(ctx, tenant, contact)
+	a.forgetPhonesOf(ctx, tenant, entity)
+	return contact, nil
+}
+
+func (a *addrBookAdapter) LoadContactRevisions(
+	ctx context.Context,
+	ID string,
+	limit int,
+) (revisions []*model.ContactRevision, found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, false, nil
+	}
+	entities, found, err := a.repo.SelectContactRevisions(ctx, tenant, repoID, limit)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	return lo.Map(entities, func(item *repo.ContactRevisionEntity, _ int) *model.ContactRevision {
+		return mapper.ContactRevisionEntityToModel(ID, item)
+	}), true, nil
+}
+
+func (a *addrBookAdapter) LoadContactRevision(ctx context.Context, ID string, revision int) (*model.ContactRevision, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil
+	}
+	entity, err := a.repo.SelectContactRevision(ctx, tenant, repoID, revision)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	return mapper.ContactRevisionEntityToModel(ID, entity), nil
+}
+
+func (a *addrBookAdapter) LoadContactRevisionAsOf(ctx context.Context, ID string, asOf time.Time) (*model.ContactRevision, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil
+	}
+	entity, err := a.repo.SelectContactRevisionAsOf(ctx, tenant, repoID, asOf)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	return mapper.ContactRevisionEntityToModel(ID, entity), nil
+}
+
+// purgeBatchSize limits number of contacts selected for purging at once
+const purgeBatchSize = 100
+
+// PurgeContacts is called by background job, so it is not scoped to a tenant. Contacts in trash are
+// not cached, so caches are left intact.
+func (a *addrBookAdapter) PurgeContacts(ctx context.Context, deletedBefore time.Time) (count int, err error) {
+	for {
+		purged, err := a.repo.PurgeContacts(ctx, deletedBefore, purgeBatchSize)
+		count += len(purged)
+		if err != nil || len(purged) < purgeBatchSize {
+			return count, err
+		}
+	}
+}
+
+// versionError translates version mismatch reported by repository into model error
+func versionError(ctx context.Context, err error, ID string) error {
+	if errors.Is(err, repo.ErrVersionMismatch) {
+		app.Logger(ctx).Debugln(err)
+		return fmt.Errorf("%w: contact id=%s was changed", model.ErrPreconditionFailed, ID)
+	}
+	return err
+}
+
+// forgetPhonesOf invalidates contacts cached by phone numbers of the contacts
+func (a *addrBookAdapter) forgetPhonesOf(ctx context.Context, tenant string, entities ...*repo.ContactWithPhonesEntity) {
+	for _, e := range entities {
+		for _, ph := range e.Phones {
+			if ph.PhoneE164 != "" {
+				a.contactsByPhoneCache.Del(ctx, tenant, ph.PhoneE164)
+			}
+		}
+	}
+}
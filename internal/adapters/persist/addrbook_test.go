@@ -0,0 +1,67 @@
+package persist
+
+import (
+	"errors"
+	cacheadapter "github.com/skvenkat/golang-chi-rest-api/internal/adapters/cache"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCurrentContactBypassesCache(t *testing.T) {
+	if !sqliteFts5Enabled {
+		t.Skip("SQLite tests require -tags sqlite_fts5")
+	}
+	p := NewPersistence(&app.Config{Database: app.DatabaseConfig{
+		Driver:   "sqlite",
+		Filename: filepath.Join(t.TempDir(), "test.db"),
+	}})
+	t.Cleanup(p.Close)
+	ctx := app.ContextWithIdentity(app.BackgroundContextWithDefaultLogger(), &app.Identity{Subject: "tester", Tenant: "tenant-a"})
+
+	// replicas share database, but each one has its own cache
+	replica := NewAddrBookAdapter(p, cacheadapter.NewInMemCache())
+	other := NewAddrBookAdapter(p, cacheadapter.NewInMemCache())
+	created, err := replica.AddContact(ctx, &model.ContactToSave{FirstName: "John", LastName: "Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := replica.LoadContactByID(ctx, created.ID); err != nil || cached.Version != 1 {
+		t.Fatalf("contact is not loaded: %+v, %v", cached, err)
+	}
+	if _, err = other.UpdateContact(ctx, created.ID, &model.ContactToSave{FirstName: "Johnny", LastName: "Doe", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := replica.LoadCurrentContactByID(ctx, created.ID)
+	if err != nil || current == nil {
+		t.Fatalf("contact is not loaded: %v", err)
+	}
+	if current.Version != 2 || current.FirstName != "Johnny" {
+		t.Errorf("contact changed by other replica is loaded as version=%d first name=%s", current.Version, current.FirstName)
+	}
+	if missing, err := replica.LoadCurrentContactByID(ctx, "999"); err != nil || missing != nil {
+		t.Errorf("missing contact is loaded as %+v, error %v", missing, err)
+	}
+}
+
+func TestCallerWithoutTenantIsForbidden(t *testing.T) {
+	if !sqliteFts5Enabled {
+		t.Skip("SQLite tests require -tags sqlite_fts5")
+	}
+	p := NewPersistence(&app.Config{Database: app.DatabaseConfig{
+		Driver:   "sqlite",
+		Filename: filepath.Join(t.TempDir(), "test.db"),
+	}})
+	t.Cleanup(p.Close)
+	ctx := app.ContextWithIdentity(app.BackgroundContextWithDefaultLogger(), &app.Identity{Subject: "tester"})
+
+	addrBook := NewAddrBookAdapter(p, cacheadapter.NewInMemCache())
+	if _, err := addrBook.LoadContactByID(ctx, "1"); !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("contact is loaded by caller without tenant with error %v, want %v", err, model.ErrForbidden)
+	}
+	if _, err := NewCustomFieldsAdapter(p, addrBook).LoadCustomFields(ctx); !errors.Is(err, model.ErrForbidden) {
+		t.Errorf("custom fields are loaded by caller without tenant with error %v, want %v", err, model.ErrForbidden)
+	}
+}
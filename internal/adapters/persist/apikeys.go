@@ -0,0 +1,56 @@
+package persist
+
+import (
+	"context"
+	"github.com/samber/lo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/mapper"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+)
+
+type apiKeysAdapter struct {
+	repo *repo.APIKeyRepo
+}
+
+func NewAPIKeysAdapter(p outport.Persistence) outport.APIKeys {
+	return &apiKeysAdapter{
+		repo: repo.NewAPIKeyRepo(p.DB()),
+	}
+}
+
+func (a *apiKeysAdapter) AddAPIKey(ctx context.Context, k *model.APIKeyToSave) (*model.APIKey, error) {
+	entity, err := a.repo.AddAPIKey(ctx, k.Name, k.Tenant, string(k.Role), k.Prefix, k.KeyHash)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.APIKeyEntityToModel(entity), nil
+}
+
+func (a *apiKeysAdapter) LoadAllAPIKeys(ctx context.Context) ([]*model.APIKey, error) {
+	entities, err := a.repo.SelectAllAPIKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(entities, func(item *repo.APIKeyEntity, _ int) *model.APIKey {
+		return mapper.APIKeyEntityToModel(item)
+	}), nil
+}
+
+func (a *apiKeysAdapter) LoadActiveAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	entity, err := a.repo.SelectActiveAPIKeyByHash(ctx, keyHash)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	return mapper.APIKeyEntityToModel(entity), nil
+}
+
+func (a *apiKeysAdapter) RevokeAPIKey(ctx context.Context, ID string) (found bool, err error) {
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return false, nil // no error is needed, we assume that record does not exist
+	}
+	return a.repo.RevokeAPIKey(ctx, repoID)
+}
@@ -0,0 +1,71 @@
+package persist
+
+import (
+	"context"
+	"github.com/samber/lo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/mapper"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+)
+
+type customFieldsAdapter struct {
+	repo     *repo.CustomFieldRepo
+	addrBook *addrBookAdapter
+}
+
+// NewCustomFieldsAdapter returns custom fields adapter, cached contacts include values of custom fields, so they are
+// forgotten by addrBook adapter (returned by NewAddrBookAdapter) when custom field is deleted
+func NewCustomFieldsAdapter(p outport.Persistence, addrBook outport.AddrBook) outport.CustomFields {
+	return &customFieldsAdapter{
+		repo:     repo.NewCustomFieldRepo(p.DB()),
+		addrBook: addrBook.(*addrBookAdapter),
+	}
+}
+
+func (a *customFieldsAdapter) LoadCustomFields(ctx context.Context) ([]*model.CustomFieldDefinition, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := a.repo.SelectCustomFields(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(entities, func(item *repo.CustomFieldEntity, _ int) *model.CustomFieldDefinition {
+		return mapper.CustomFieldEntityToModel(item)
+	}), nil
+}
+
+func (a *customFieldsAdapter) AddCustomField(
+	ctx context.Context,
+	d *model.CustomFieldDefinitionToSave,
+) (*model.CustomFieldDefinition, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := a.repo.AddCustomField(ctx, tenant, mapper.CustomFieldToSaveModelToEntity(d))
+	if err != nil {
+		return nil, err
+	}
+	return mapper.CustomFieldEntityToModel(entity), nil
+}
+
+func (a *customFieldsAdapter) DeleteCustomField(ctx context.Context, ID string) (found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return false, nil // no error is needed, we assume that record does not exist
+	}
+	contactIds, found, err := a.repo.DeleteCustomField(ctx, tenant, repoID)
+	if found {
+		a.addrBook.forgetContacts(ctx, tenant, contactIds)
+	}
+	return found, err
+}
@@ -0,0 +1,50 @@
+package persist
+
+import (
+	cacheadapter "github.com/skvenkat/golang-chi-rest-api/internal/adapters/cache"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteCustomFieldForgetsCachedContacts(t *testing.T) {
+	if !sqliteFts5Enabled {
+		t.Skip("SQLite tests require -tags sqlite_fts5")
+	}
+	p := NewPersistence(&app.Config{Database: app.DatabaseConfig{
+		Driver:   "sqlite",
+		Filename: filepath.Join(t.TempDir(), "test.db"),
+	}})
+	t.Cleanup(p.Close)
+	ctx := app.ContextWithIdentity(app.BackgroundContextWithDefaultLogger(), &app.Identity{Subject: "tester", Tenant: "tenant-a"})
+	addrBook := NewAddrBookAdapter(p, cacheadapter.NewInMemCache())
+	fields := NewCustomFieldsAdapter(p, addrBook)
+
+	tier, err := fields.AddCustomField(ctx, &model.CustomFieldDefinitionToSave{Name: "tier", Type: model.CustomFieldTypeString})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := addrBook.AddContact(ctx, &model.ContactToSave{
+		FirstName:         "John",
+		LastName:          "Doe",
+		CustomFieldValues: []*model.CustomFieldValue{{FieldID: tier.ID, Value: "gold"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached, err := addrBook.LoadContactByID(ctx, created.ID); err != nil || cached.CustomFields["tier"] != "gold" {
+		t.Fatalf("contact is not loaded: %+v, %v", cached, err)
+	}
+	if found, err := fields.DeleteCustomField(ctx, tier.ID); err != nil || !found {
+		t.Fatalf("custom field is not deleted: %v", err)
+	}
+
+	loaded, err := addrBook.LoadContactByID(ctx, created.ID)
+	if err != nil || loaded == nil {
+		t.Fatalf("contact is not loaded: %v", err)
+	}
+	if _, ok := loaded.CustomFields["tier"]; ok || loaded.Version != 2 {
+		t.Errorf("contact is loaded as version=%d with custom fields %v after field was deleted", loaded.Version, loaded.CustomFields)
+	}
+}
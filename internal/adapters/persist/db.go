@@ -3,55 +3,85 @@ package persist
 import (
 	"context"
 	"fmt"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/migrations"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
 	"go.uber.org/zap"
 )
 
-const createTablesSql =
-/*language=sqlite*/ `
-CREATE TABLE IF NOT EXISTS contacts(
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    first_name TEXT NOT NULL,
-    last_name TEXT NOT NULL
-);
-CREATE TABLE IF NOT EXISTS phones(
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    type TEXT NOT NULL,
-    phone_number TEXT NOT NULL,
-    contact_id BIGINT NOT NULL REFERENCES contacts(id)
-)
-`
-
 type dbAdapter struct {
 	db *sqlx.DB
 }
 
-// NewPersistence connects to SQLite database and returns Persistence interface that wraps database reference
+// NewPersistence connects to database configured by database.driver setting, applies pending schema
+// migrations and returns Persistence interface that wraps database reference
 func NewPersistence(cfg *app.Config) outport.Persistence {
-	dbcfg := cfg.Database
-	connStr := fmt.Sprintf("%s", dbcfg.Filename)
-	zap.S().Infoln("establishing connection to SQLite database...")
-	db, err := sqlx.ConnectContext(context.Background(), "sqlite3", connStr)
-	if err != nil {
-		zap.S().Fatalf("error connecting to database (file=%s): %s\n", dbcfg.Filename, err)
-	}
+	db, dialect := connect(cfg)
 	zap.S().Infoln("connection to database was successfully established, performing initialization...")
 
-	tx := db.MustBegin()
-	defer tx.Rollback()
-	tx.MustExec(createTablesSql)
-	err = tx.Commit()
+	migrator, err := migrations.NewMigrator(db, migrations.Scripts(dialect))
+	if err != nil {
+		zap.S().Fatalln("failed to load database migrations:", err)
+	}
+	applied, err := migrator.Up(context.Background())
 	if err != nil {
-		zap.S().Fatalln("failed to commit transaction while creating database:", err)
+		zap.S().Fatalln("failed to migrate database:", err)
 	}
-	zap.S().Infoln("db initialization was successfully performed")
+	zap.S().Infof("db initialization was successfully performed, %d migration(s) applied", len(applied))
+	normalizePhones(db, cfg.Phones.DefaultRegion)
+	recordMissingRevisions(db)
 
 	return &dbAdapter{db: db}
 }
 
+// NewMigrator connects to database and returns migrator for its schema, returned function closes connection
+func NewMigrator(cfg *app.Config) (*migrations.Migrator, func()) {
+	db, dialect := connect(cfg)
+	migrator, err := migrations.NewMigrator(db, migrations.Scripts(dialect))
+	if err != nil {
+		zap.S().Fatalln("failed to load database migrations:", err)
+	}
+	return migrator, dbAdapter{db: db}.Close
+}
+
+// connect establishes connection to configured database and returns it along with migrations dialect name
+func connect(cfg *app.Config) (*sqlx.DB, string) {
+	dbcfg := cfg.Database
+	var db *sqlx.DB
+	var dialect string
+	var err error
+	switch dbcfg.Driver {
+	case "", "sqlite":
+		zap.S().Infoln("establishing connection to SQLite database...")
+		if !sqliteFts5Enabled {
+			zap.S().Fatalln("full-text search requires SQLite FTS5 extension, build application with -tags sqlite_fts5 (make build passes it)")
+		}
+		db, err = sqlx.ConnectContext(context.Background(), "sqlite3", dbcfg.Filename)
+		if err != nil {
+			zap.S().Fatalf("error connecting to database (file=%s): %s\n", dbcfg.Filename, err)
+		}
+		dialect = migrations.DialectSQLite
+	case "postgres":
+		zap.S().Infoln("establishing connection to PostgreSQL database...")
+		db, err = sqlx.ConnectContext(context.Background(), "pgx", dbcfg.DSN)
+		if err != nil {
+			zap.S().Fatalf("error connecting to PostgreSQL database: %s\n", err)
+		}
+		dialect = migrations.DialectPostgres
+	default:
+		zap.S().Fatalf("unknown database driver: %s", dbcfg.Driver)
+	}
+	db.SetMaxOpenConns(dbcfg.MaxOpenConns)
+	if dbcfg.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(dbcfg.MaxIdleConns)
+	}
+	db.SetConnMaxLifetime(dbcfg.ConnMaxLifetime)
+	return db, dialect
+}
+
 func (d dbAdapter) DB() *sqlx.DB {
 	return d.db
 }
@@ -59,6 +89,6 @@ func (d dbAdapter) DB() *sqlx.DB {
 func (d dbAdapter) Close() {
 	err := d.DB().Close()
 	if err != nil {
-		fmt.Println("failed to close sqlite database:", err)
+		fmt.Println("failed to close database:", err)
 	}
 }
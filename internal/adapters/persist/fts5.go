@@ -0,0 +1,6 @@
+//go:build sqlite_fts5 || fts5
+
+package persist
+
+// sqliteFts5Enabled reports whether SQLite driver was compiled with FTS5 extension
+const sqliteFts5Enabled = true
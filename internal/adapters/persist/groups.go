@@ -0,0 +1,184 @@
+package persist
+
+import (
+	"context"
+	"github.com/samber/lo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/mapper"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// Groups are managed by address book adapter, because cached contacts include their groups, and so they
+// must be invalidated when group is renamed or deleted, or when group membership changes.
+
+func (a *addrBookAdapter) LoadGroups(ctx context.Context) ([]*model.Group, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entities, err := a.groupRepo.SelectGroups(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(entities, func(item *repo.GroupEntity, _ int) *model.Group {
+		return mapper.GroupEntityToModel(item)
+	}), nil
+}
+
+func (a *addrBookAdapter) LoadGroupByID(ctx context.Context, ID string) (*model.Group, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil // no error is needed, we assume that record does not exist
+	}
+	entity, err := a.groupRepo.SelectGroupByID(ctx, tenant, repoID)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	return mapper.GroupEntityToModel(entity), nil
+}
+
+func (a *addrBookAdapter) AddGroup(ctx context.Context, g *model.GroupToSave) (*model.Group, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entity, err := a.groupRepo.AddGroup(ctx, tenant, g.Name)
+	if err != nil {
+		return nil, err
+	}
+	return mapper.GroupEntityToModel(entity), nil
+}
+
+func (a *addrBookAdapter) UpdateGroup(ctx context.Context, ID string, g *model.GroupToSave) (*model.Group, error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return nil, nil
+	}
+	found, err := a.groupRepo.UpdateGroup(ctx, tenant, repoID, g.Name)
+	if err != nil || !found {
+		return nil, err
+	}
+	// cached member contacts have old name of the group
+	memberIds, err := a.groupRepo.SelectGroupMemberIds(ctx, repoID)
+	if err != nil {
+		return nil, err
+	}
+	a.forgetContacts(ctx, tenant, memberIds)
+	entity, err := a.groupRepo.SelectGroupByID(ctx, tenant, repoID)
+	if err != nil || entity == nil {
+		return nil, err
+	}
+	return mapper.GroupEntityToModel(entity), nil
+}
+
+func (a *addrBookAdapter) DeleteGroup(ctx context.Context, ID string) (found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(ID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", ID)
+		return false, nil // no error is needed, we assume that record does not exist
+	}
+	// members are selected before group is deleted, group id is checked to belong to the tenant by DeleteGroup
+	memberIds, err := a.groupRepo.SelectGroupMemberIds(ctx, repoID)
+	if err != nil {
+		return false, err
+	}
+	found, err = a.groupRepo.DeleteGroup(ctx, tenant, repoID)
+	if found {
+		a.forgetContacts(ctx, tenant, memberIds)
+	}
+	return
+}
+
+func (a *addrBookAdapter) AddGroupMembers(
+	ctx context.Context,
+	groupID string,
+	contactIDs []string,
+) (missing []string, found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(groupID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", groupID)
+		return nil, false, nil
+	}
+	repoContactIds, invalid := contactIdsToRepoIds(contactIDs)
+	if len(invalid) > 0 {
+		// contacts with invalid ids do not exist, but group must be checked to exist anyway
+		group, err := a.groupRepo.SelectGroupByID(ctx, tenant, repoID)
+		return invalid, group != nil, err
+	}
+	missingIds, found, err := a.groupRepo.AddGroupMembers(ctx, tenant, repoID, lo.Keys(repoContactIds))
+	if err != nil || !found {
+		return nil, found, err
+	}
+	if len(missingIds) > 0 {
+		return lo.Map(missingIds, func(item int64, _ int) string { return repoContactIds[item] }), true, nil
+	}
+	a.forgetContacts(ctx, tenant, lo.Keys(repoContactIds))
+	return nil, true, nil
+}
+
+func (a *addrBookAdapter) RemoveGroupMembers(ctx context.Context, groupID string, contactIDs []string) (found bool, err error) {
+	tenant, err := tenantOf(ctx)
+	if err != nil {
+		return false, err
+	}
+	repoID, err := mapper.ModelIdToRepoId(groupID)
+	if err != nil {
+		app.Logger(ctx).Debugln("error parsing id:", groupID)
+		return false, nil
+	}
+	// contacts with invalid ids cannot be members, so they are skipped
+	repoContactIds, _ := contactIdsToRepoIds(contactIDs)
+	found, err = a.groupRepo.RemoveGroupMembers(ctx, tenant, repoID, lo.Keys(repoContactIds))
+	if found {
+		a.forgetContacts(ctx, tenant, lo.Keys(repoContactIds))
+	}
+	return
+}
+
+// contactIdsToRepoIds maps repo ids of contacts to their model ids, model ids which cannot be repo ids are
+// returned separately
+func contactIdsToRepoIds(IDs []string) (repoIds map[int64]string, invalid []string) {
+	repoIds = make(map[int64]string, len(IDs))
+	for _, ID := range IDs {
+		if repoID, err := mapper.ModelIdToRepoId(ID); err == nil {
+			repoIds[repoID] = ID
+		} else {
+			invalid = append(invalid, ID)
+		}
+	}
+	return repoIds, invalid
+}
+
+// forgetContacts invalidates cached contacts, both by their ids and by their phone numbers
+func (a *addrBookAdapter) forgetContacts(ctx context.Context, tenant string, ids []int64) {
+	for _, id := range ids {
+		a.contactByIdCache.Del(ctx, tenant, mapper.RepoIdToModelId(id))
+	}
+	phones, err := a.repo.SelectPhoneE164sByContactIds(ctx, ids)
+	if err != nil {
+		app.Logger(ctx).Warnf("Contacts cached by phone numbers are not invalidated: %v", err)
+		return
+	}
+	for _, phone := range phones {
+		a.contactsByPhoneCache.Del(ctx, tenant, phone)
+	}
+}
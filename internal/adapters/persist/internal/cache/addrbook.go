@@ -10,7 +10,8 @@ import (
 const nsAddrBookContactByID = "ContactById"
 
 type ContactByIdPartition struct {
-	cache outport.Cache
+	cache     outport.Cache
+	partition *outport.CachePartition
 }
 
 // RegisterContactByID registers cache to get/set/delete contact record by ID.
@@ -19,25 +20,81 @@ func RegisterContactByID(cache outport.Cache) ContactByIdPartition {
 		Namespace:     nsAddrBookContactByID,
 		Ttl:           30 * time.Second,
 		LocalMaxItems: 1000,
+		RefreshAfter:  20 * time.Second,
 	}
 	cache.Register(prt)
-	return ContactByIdPartition{cache: cache}
+	return ContactByIdPartition{cache: cache, partition: prt}
 }
 
-// Get returns contact record from cache by ID
-func (pr ContactByIdPartition) Get(ctx context.Context, ID string) *model.Contact {
-	key := BuildCacheKey(nsAddrBookContactByID, ID)
+// Get returns contact record of the tenant from cache by ID
+func (pr ContactByIdPartition) Get(ctx context.Context, tenant string, ID string) *model.Contact {
+	key := BuildCacheKey(nsAddrBookContactByID, tenant, ID)
 	return Get[model.Contact](ctx, pr.cache, key)
 }
 
-// Set adds/updates contact record by ID
-func (pr ContactByIdPartition) Set(ctx context.Context, c *model.Contact) {
-	key := BuildCacheKey(nsAddrBookContactByID, c.ID)
+// GetOrBuild returns contact record of the tenant from cache by ID, if it is not cached yet then it is loaded by builder
+func (pr ContactByIdPartition) GetOrBuild(
+	ctx context.Context,
+	tenant string,
+	ID string,
+	builder func(ctx context.Context) (c *model.Contact, doNotSave bool, err error),
+) (*model.Contact, error) {
+	key := BuildCacheKey(nsAddrBookContactByID, tenant, ID)
+	return GetOrBuild[model.Contact](ctx, pr.cache, pr.partition, key, builder)
+}
+
+// Set adds/updates contact record of the tenant by ID after the contact was saved. The record is deleted first,
+// so that other service instances drop their copies of it.
+func (pr ContactByIdPartition) Set(ctx context.Context, tenant string, c *model.Contact) {
+	key := BuildCacheKey(nsAddrBookContactByID, tenant, c.ID)
+	Del(ctx, pr.cache, key)
 	Set(ctx, pr.cache, key, c)
 }
 
-// Del deletes contact record by ID
-func (pr ContactByIdPartition) Del(ctx context.Context, ID string) {
-	key := BuildCacheKey(nsAddrBookContactByID, ID)
-	pr.cache.Del(ctx, key)
+// Del deletes contact record of the tenant by ID
+func (pr ContactByIdPartition) Del(ctx context.Context, tenant string, ID string) {
+	key := BuildCacheKey(nsAddrBookContactByID, tenant, ID)
+	Del(ctx, pr.cache, key)
+}
+
+const nsAddrBookContactsByPhone = "ContactsByPhone"
+
+type ContactsByPhonePartition struct {
+	cache     outport.Cache
+	partition *outport.CachePartition
+}
+
+// RegisterContactsByPhone registers cache to get/delete contacts having a phone number, phone number is in E.164
+// format. Cached contacts are invalidated by phone numbers of contacts being saved or deleted.
+func RegisterContactsByPhone(cache outport.Cache) ContactsByPhonePartition {
+	prt := &outport.CachePartition{
+		Namespace:     nsAddrBookContactsByPhone,
+		Ttl:           30 * time.Second,
+		LocalMaxItems: 1000,
+		RefreshAfter:  20 * time.Second,
+	}
+	cache.Register(prt)
+	return ContactsByPhonePartition{cache: cache, partition: prt}
+}
+
+// GetOrBuild returns contacts of the tenant having the phone number from cache, if they are not cached yet then
+// they are loaded by builder
+func (pr ContactsByPhonePartition) GetOrBuild(
+	ctx context.Context,
+	tenant string,
+	phoneE164 string,
+	builder func(ctx context.Context) (c *[]*model.Contact, doNotSave bool, err error),
+) ([]*model.Contact, error) {
+	key := BuildCacheKey(nsAddrBookContactsByPhone, tenant, phoneE164)
+	contacts, err := GetOrBuild[[]*model.Contact](ctx, pr.cache, pr.partition, key, builder)
+	if err != nil || contacts == nil {
+		return nil, err
+	}
+	return *contacts, nil
+}
+
+// Del deletes contacts of the tenant having the phone number
+func (pr ContactsByPhonePartition) Del(ctx context.Context, tenant string, phoneE164 string) {
+	key := BuildCacheKey(nsAddrBookContactsByPhone, tenant, phoneE164)
+	Del(ctx, pr.cache, key)
 }
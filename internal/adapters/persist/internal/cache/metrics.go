@@ -0,0 +1,19 @@
+package cache
+
+import (
+	"context"
+	"expvar"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+)
+
+// cacheFailures counts failed cache operations by "<namespace>.<operation>" keys, it is published as
+// "cache_failures" variable by expvar handler
+var cacheFailures = expvar.NewMap("cache_failures")
+
+// reportFailure logs failed cache operation and counts it. Cache failures never fail the request,
+// failed Get is treated as a cache miss, so the value is loaded from the database instead.
+func reportFailure(ctx context.Context, operation string, key outport.CacheKey, err error) {
+	cacheFailures.Add(key.Namespace+"."+operation, 1)
+	app.Logger(ctx).Warnf("Cache %s failed for cacheKey=%s: %v", operation, key, err)
+}
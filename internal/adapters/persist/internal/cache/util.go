@@ -5,46 +5,164 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"golang.org/x/sync/singleflight"
+	"hash/fnv"
 	"io"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// buildTimeout limits time of building cache item. Build is shared by concurrent callers and it may run in background,
+// so it is not canceled with the request that started it.
+const buildTimeout = 10 * time.Second
+
+var (
+	// buildGroup coalesces concurrent builds of the same cache key into a single builder call
+	buildGroup singleflight.Group
+	// refreshing contains keys of items being refreshed in background
+	refreshing sync.Map
+	// invalidations count deletions of cache keys, every counter is shared by keys with the same hash. Value built
+	// while its key was deleted may be built from data loaded before the change, so it is not saved then.
+	invalidations [1024]atomic.Uint64
+)
+
+// cachedItem wraps every cached value to keep the time when value was built
+type cachedItem[T any] struct {
+	Value   *T
+	BuiltAt time.Time
+}
+
 func Get[T any](ctx context.Context, cache outport.Cache, key outport.CacheKey) *T {
-	value := new(T)
-	if cache.Get(ctx, key, &value) {
-		return value
+	if item := get[T](ctx, cache, key); item != nil {
+		return item.Value
 	}
 	return nil
 }
 
+func get[T any](ctx context.Context, cache outport.Cache, key outport.CacheKey) *cachedItem[T] {
+	item := new(cachedItem[T])
+	found, err := cache.Get(ctx, key, item)
+	if err != nil {
+		reportFailure(ctx, "get", key, err)
+		return nil
+	}
+	if !found || item.Value == nil {
+		return nil
+	}
+	return item
+}
+
 func Set[T any](ctx context.Context, cache outport.Cache, key outport.CacheKey, value *T) {
-	cache.Set(ctx, key, value)
+	if err := cache.Set(ctx, key, &cachedItem[T]{Value: value, BuiltAt: time.Now()}); err != nil {
+		reportFailure(ctx, "set", key, err)
+	}
+}
+
+func Del(ctx context.Context, cache outport.Cache, key outport.CacheKey) {
+	invalidationsOf(key).Add(1)
+	if err := cache.Del(ctx, key); err != nil {
+		reportFailure(ctx, "del", key, err)
+	}
 }
 
 // GetOrBuild fetches value from cache first and returns it if cache has it already
-// Otherwise builder callback function will be invoked
+// Otherwise builder callback function will be invoked. Concurrent misses of the same key share a single
+// builder call, which keeps running if the caller which started it gives up. If partition has RefreshAfter set,
+// then value older than RefreshAfter is returned immediately and rebuilt in background. Value which was built
+// while its key was deleted is not saved, because it may be loaded before the change that deleted the key.
 func GetOrBuild[T any](
-	ctx context.Context, cache outport.Cache, key outport.CacheKey, builder func() (value *T, doNotSave bool, err error),
+	ctx context.Context,
+	cache outport.Cache,
+	partition *outport.CachePartition,
+	key outport.CacheKey,
+	builder func(ctx context.Context) (value *T, doNotSave bool, err error),
 ) (*T, error) {
-	value := new(T)
-	if cache.Get(ctx, key, value) {
-		return value, nil
+	if item := get[T](ctx, cache, key); item != nil {
+		if partition.RefreshAfter > 0 && time.Since(item.BuiltAt) > partition.RefreshAfter {
+			refreshInBackground(ctx, cache, key, builder)
+		}
+		return item.Value, nil
+	}
+	built := buildGroup.DoChan(key.EncodedKey, func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), buildTimeout)
+		defer cancel()
+		return build(ctx, cache, key, builder)
+	})
+	select {
+	case result := <-built:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*T), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	newValue, doNotSave, err := builder()
+}
+
+func build[T any](
+	ctx context.Context,
+	cache outport.Cache,
+	key outport.CacheKey,
+	builder func(ctx context.Context) (value *T, doNotSave bool, err error),
+) (*T, error) {
+	invalidated := invalidationsOf(key)
+	generation := invalidated.Load()
+	newValue, doNotSave, err := builder(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if !doNotSave {
-		cache.Set(ctx, key, newValue)
+	if doNotSave {
+		return newValue, nil
 	}
+	if invalidated.Load() != generation {
+		app.Logger(ctx).Debugf("Cache item by cacheKey=%s was deleted while it was built, built value is not saved", key)
+		return newValue, nil
+	}
+	Set(ctx, cache, key, newValue)
 	return newValue, nil
 }
 
-// BuildCacheKey creates a key that contains two parts - namespace and key itself.
-// If namespace+key is shorter than 100 characters then it will be stored in a basic format such as "namespace:key",
-// otherwise the key part will be encoded in SHA1. This is due to the fact that usually long keys are
-// reducing cache performance (e.g. this is the case with Redis).
-func BuildCacheKey(namespace string, key string) outport.CacheKey {
+func invalidationsOf(key outport.CacheKey) *atomic.Uint64 {
+	h := fnv.New32a()
+	_, _ = io.WriteString(h, key.EncodedKey)
+	return &invalidations[h.Sum32()%uint32(len(invalidations))]
+}
+
+// refreshInBackground rebuilds cache item unless it is being refreshed already. Refresh outlives the request,
+// so it runs with a context that keeps request values (such as logger) but is not canceled with the request.
+func refreshInBackground[T any](
+	ctx context.Context,
+	cache outport.Cache,
+	key outport.CacheKey,
+	builder func(ctx context.Context) (value *T, doNotSave bool, err error),
+) {
+	if _, loaded := refreshing.LoadOrStore(key.EncodedKey, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer refreshing.Delete(key.EncodedKey)
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), buildTimeout)
+		defer cancel()
+		_, err, _ := buildGroup.Do(key.EncodedKey, func() (any, error) {
+			return build(ctx, cache, key, builder)
+		})
+		if err != nil {
+			app.Logger(ctx).Warnf("Background refresh of cache item by cacheKey=%s failed: %v", key, err)
+		}
+	}()
+}
+
+// BuildCacheKey creates a key that contains two parts - namespace and key itself, where the key is scoped
+// to the tenant, so that tenants never share cached values. Tenant is escaped, so it cannot contain ":" separator.
+// If namespace+key is shorter than 100 characters then it will be stored in a basic format such as
+// "namespace:tenant:key", otherwise the key part will be encoded in SHA1. This is due to the fact that usually
+// long keys are reducing cache performance (e.g. this is the case with Redis).
+func BuildCacheKey(namespace string, tenant string, key string) outport.CacheKey {
+	key = url.QueryEscape(tenant) + ":" + key
 	var newKey string
 	if len(namespace)+len(key) < 100 {
 		newKey = key
@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	cacheadapter "github.com/skvenkat/golang-chi-rest-api/internal/adapters/cache"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
+	"testing"
+	"time"
+)
+
+type testValue struct {
+	Name string
+}
+
+func TestGetOrBuildWithUnavailableRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	c := cacheadapter.NewRedisCache(app.CacheConfig{Address: server.Addr()})
+	t.Cleanup(c.Close)
+	partition := &outport.CachePartition{Namespace: "degraded", Ttl: time.Minute}
+	c.Register(partition)
+	ctx := app.BackgroundContextWithDefaultLogger()
+	key := BuildCacheKey(partition.Namespace, "tenant", "1")
+	server.Close()
+
+	builds := 0
+	builder := func(ctx context.Context) (*testValue, bool, error) {
+		builds++
+		return &testValue{Name: "John"}, false, nil
+	}
+	for i := 0; i < 2; i++ {
+		value, err := GetOrBuild(ctx, c, partition, key, builder)
+		if err != nil || value == nil || value.Name != "John" {
+			t.Fatalf("GetOrBuild returned %+v, error %v while Redis is down", value, err)
+		}
+	}
+	if builds != 2 {
+		t.Errorf("value was built %d times, want every call to build it", builds)
+	}
+	if failures := cacheFailures.Get("degraded.get"); failures == nil || failures.String() != "2" {
+		t.Errorf("failed gets are counted as %v, want 2", failures)
+	}
+	if failures := cacheFailures.Get("degraded.set"); failures == nil || failures.String() != "2" {
+		t.Errorf("failed sets are counted as %v, want 2", failures)
+	}
+}
+
+func TestGetOrBuildSharedBuildOutlivesCanceledCaller(t *testing.T) {
+	c := cacheadapter.NewInMemCache()
+	partition := &outport.CachePartition{Namespace: "shared", Ttl: time.Minute, LocalMaxItems: 10}
+	c.Register(partition)
+	key := BuildCacheKey(partition.Namespace, "tenant", "1")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	builds := 0
+	builder := func(ctx context.Context) (*testValue, bool, error) {
+		builds++
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return nil, true, err
+		}
+		return &testValue{Name: "John"}, false, nil
+	}
+
+	firstCtx, cancelFirst := context.WithCancel(app.BackgroundContextWithDefaultLogger())
+	firstErr := make(chan error)
+	go func() {
+		_, err := GetOrBuild(firstCtx, c, partition, key, builder)
+		firstErr <- err
+	}()
+	<-started
+	second := make(chan *testValue)
+	go func() {
+		value, err := GetOrBuild(app.BackgroundContextWithDefaultLogger(), c, partition, key, builder)
+		if err != nil {
+			t.Errorf("waiting caller failed: %v", err)
+		}
+		second <- value
+	}()
+
+	// the caller which started the build gives up, while the build goes on for another caller
+	cancelFirst()
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("canceled caller returned error %v", err)
+	}
+	time.Sleep(10 * time.Millisecond) // let the second caller join the build
+	close(release)
+	if value := <-second; value == nil || value.Name != "John" {
+		t.Errorf("waiting caller returned %+v", value)
+	}
+	if builds != 1 {
+		t.Errorf("value was built %d times, want 1", builds)
+	}
+	if cached := Get[testValue](app.BackgroundContextWithDefaultLogger(), c, key); cached == nil || cached.Name != "John" {
+		t.Errorf("built value is not cached: %+v", cached)
+	}
+}
+
+func TestBuiltValueIsNotSavedIfKeyIsDeletedWhileBuilding(t *testing.T) {
+	tests := map[string]struct {
+		refreshAfter time.Duration
+		cached       *testValue // value cached before the build, nil if build is made on cache miss
+	}{
+		"miss":    {},
+		"refresh": {refreshAfter: time.Millisecond, cached: &testValue{Name: "John"}},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			// every Set replaces value in Redis, so value saved by the build would be seen
+			c := cacheadapter.NewRedisCache(app.CacheConfig{Address: miniredis.RunT(t).Addr()})
+			t.Cleanup(c.Close)
+			partition := &outport.CachePartition{Namespace: "updated-" + name, Ttl: time.Minute, LocalMaxItems: 10, RefreshAfter: tt.refreshAfter}
+			c.Register(partition)
+			ctx := app.BackgroundContextWithDefaultLogger()
+			key := BuildCacheKey(partition.Namespace, "tenant", "1")
+			if tt.cached != nil {
+				Set(ctx, c, key, tt.cached)
+				time.Sleep(2 * tt.refreshAfter)
+			}
+
+			started, release, built := make(chan struct{}), make(chan struct{}), make(chan struct{})
+			builder := func(ctx context.Context) (*testValue, bool, error) {
+				defer close(built)
+				close(started)
+				<-release
+				return &testValue{Name: "John"}, false, nil // value loaded before the update
+			}
+			go func() { _, _ = GetOrBuild(ctx, c, partition, key, builder) }()
+			<-started
+			// value is updated the same way as ContactByIdPartition.Set does it
+			Del(ctx, c, key)
+			Set(ctx, c, key, &testValue{Name: "Johnny"})
+			close(release)
+			<-built
+			// let the build finish saving its value (if it does)
+			for i := 0; i < 100; i++ {
+				if _, refreshing := refreshing.Load(key.EncodedKey); !refreshing {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+			time.Sleep(10 * time.Millisecond)
+
+			if cached := Get[testValue](ctx, c, key); cached == nil || cached.Name != "Johnny" {
+				t.Errorf("value built before the update replaced updated one: %+v", cached)
+			}
+		})
+	}
+}
@@ -12,8 +12,9 @@ func ContactEntityToModel(e *repo.ContactWithPhonesEntity) *model.Contact {
 	phones := make([]*model.ContactPhone, len(e.Phones))
 	for i, ph := range e.Phones {
 		phones[i] = &model.ContactPhone{
-			PhoneType:   phoneTypeEntityToModel(ph.PhoneType),
-			PhoneNumber: ph.PhoneNumber,
+			PhoneType:       phoneTypeEntityToModel(ph.PhoneType),
+			PhoneNumber:     ph.PhoneNumber,
+			PhoneNumberE164: ph.PhoneE164,
 		}
 	}
 	return &model.Contact{
@@ -21,6 +22,30 @@ func ContactEntityToModel(e *repo.ContactWithPhonesEntity) *model.Contact {
 		FirstName: e.FirstName,
 		LastName:  e.LastName,
 		Phones:    phones,
+		Emails: lo.Map(e.Emails, func(item *repo.EmailEntity, _ int) *model.ContactEmail {
+			return &model.ContactEmail{Label: item.Label, Address: item.Address}
+		}),
+		Addresses: lo.Map(e.Addresses, func(item *repo.AddressEntity, _ int) *model.ContactAddress {
+			return &model.ContactAddress{
+				Label:      item.Label,
+				Street:     item.Street,
+				City:       item.City,
+				Region:     item.Region,
+				PostalCode: item.PostalCode,
+				Country:    item.Country,
+			}
+		}),
+		URLs: lo.Map(e.URLs, func(item *repo.URLEntity, _ int) *model.ContactURL {
+			return &model.ContactURL{Label: item.Label, URL: item.URL}
+		}),
+		CustomFields: lo.SliceToMap(e.CustomFieldValues, func(item *repo.CustomFieldValueEntity) (string, any) {
+			return item.Name, model.DecodeCustomFieldValue(model.CustomFieldType(item.Type), item.Value)
+		}),
+		Groups: lo.Map(e.Groups, func(item *repo.ContactGroupEntity, _ int) *model.GroupRef {
+			return &model.GroupRef{ID: RepoIdToModelId(item.ID), Name: item.Name}
+		}),
+		DeletedAt: e.DeletedAt,
+		Version:   e.Version,
 	}
 }
 
@@ -32,8 +57,31 @@ func ContactToSaveModelToEntity(m *model.ContactToSave) *repo.ContactWithPhonesE
 			return &repo.PhoneEntity{
 				PhoneType:   phoneTypeModelToEntity(item.PhoneType),
 				PhoneNumber: item.PhoneNumber,
+				PhoneE164:   item.PhoneNumberE164,
+			}
+		}),
+		Emails: lo.Map(m.Emails, func(item *model.ContactEmail, _ int) *repo.EmailEntity {
+			return &repo.EmailEntity{Label: item.Label, Address: item.Address}
+		}),
+		Addresses: lo.Map(m.Addresses, func(item *model.ContactAddress, _ int) *repo.AddressEntity {
+			return &repo.AddressEntity{
+				Label:      item.Label,
+				Street:     item.Street,
+				City:       item.City,
+				Region:     item.Region,
+				PostalCode: item.PostalCode,
+				Country:    item.Country,
 			}
 		}),
+		URLs: lo.Map(m.URLs, func(item *model.ContactURL, _ int) *repo.URLEntity {
+			return &repo.URLEntity{Label: item.Label, URL: item.URL}
+		}),
+		CustomFieldValues: lo.Map(m.CustomFieldValues, func(item *model.CustomFieldValue, _ int) *repo.CustomFieldValueEntity {
+			// field ids are taken from field definitions, so they are always valid
+			fieldID, _ := ModelIdToRepoId(item.FieldID)
+			return &repo.CustomFieldValueEntity{FieldID: fieldID, Value: item.Value}
+		}),
+		Version: m.Version,
 	}
 }
 
@@ -62,3 +110,16 @@ func phoneTypeModelToEntity(phoneType model.ContactPhoneType) string {
 		panic(fmt.Sprintf("unexpected model phone type: %s", phoneType))
 	}
 }
+
+func ContactRevisionEntityToModel(ID string, e *repo.ContactRevisionEntity) *model.ContactRevision {
+	contact := ContactEntityToModel(e.Contact)
+	contact.ID = ID
+	return &model.ContactRevision{
+		Revision:   e.Revision,
+		Action:     model.ContactRevisionAction(e.Action),
+		Actor:      e.Actor,
+		ChangedAt:  e.ChangedAt,
+		Contact:    contact,
+		RevertedTo: lo.FromPtr(e.RevertedTo),
+	}
+}
@@ -0,0 +1,102 @@
+package mapper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// cursorPayload is serialized into opaque pagination cursor. Sort order is stored as well, so that cursor
+// issued for one sort order cannot be used with another one.
+type cursorPayload struct {
+	SortBy    string `json:"s"`
+	Desc      bool   `json:"d,omitempty"`
+	Primary   string `json:"p"`
+	Secondary string `json:"q"`
+	ID        int64  `json:"i"`
+}
+
+func ContactListQueryToParams(q *model.ContactListQuery) (repo.ContactListParams, error) {
+	params := repo.ContactListParams{
+		Order: repo.ContactListOrder{
+			SortBy: sortFieldModelToEntity(q.SortBy),
+			Desc:   q.SortDesc,
+		},
+		Filter: repo.ContactListFilter{
+			LastNamePrefix: q.LastNamePrefix,
+		},
+		Limit: q.Limit,
+	}
+	if q.PhoneType != nil {
+		params.Filter.PhoneType = phoneTypeModelToEntity(*q.PhoneType)
+	}
+	if q.CustomField != nil {
+		fieldID, err := ModelIdToRepoId(q.CustomField.FieldID)
+		if err != nil {
+			return params, err
+		}
+		params.Filter.CustomFieldID = fieldID
+		params.Filter.CustomFieldValue = q.CustomField.Value
+	}
+	if q.GroupID != "" {
+		groupID, err := ModelIdToRepoId(q.GroupID)
+		if err != nil {
+			return params, model.NewValidationError("group", "invalid group id")
+		}
+		params.Filter.GroupID = groupID
+	}
+	if q.Cursor != "" {
+		cursor, err := decodeContactListCursor(params.Order, q.Cursor)
+		if err != nil {
+			return params, err
+		}
+		params.After = cursor
+	}
+	return params, nil
+}
+
+func EncodeContactListCursor(order repo.ContactListOrder, c *repo.ContactListCursor) string {
+	data, err := json.Marshal(cursorPayload{
+		SortBy:    order.SortBy,
+		Desc:      order.Desc,
+		Primary:   c.Primary,
+		Secondary: c.Secondary,
+		ID:        c.ID,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("error marshalling contact list cursor: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeContactListCursor(order repo.ContactListOrder, cursor string) (*repo.ContactListCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err = json.Unmarshal(data, &payload); err != nil {
+		return nil, model.ErrInvalidCursor
+	}
+	if payload.SortBy != order.SortBy || payload.Desc != order.Desc || payload.ID <= 0 {
+		return nil, model.ErrInvalidCursor
+	}
+	return &repo.ContactListCursor{
+		Primary:   payload.Primary,
+		Secondary: payload.Secondary,
+		ID:        payload.ID,
+	}, nil
+}
+
+func sortFieldModelToEntity(sortBy model.ContactSortField) string {
+	switch sortBy {
+	case model.ContactSortByLastName:
+		return repo.ContactListSortByLastName
+	case model.ContactSortByFirstName:
+		return repo.ContactListSortByFirstName
+	default:
+		panic(fmt.Sprintf("unexpected model contact sort field: %s", sortBy))
+	}
+}
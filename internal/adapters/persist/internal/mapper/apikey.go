@@ -0,0 +1,18 @@
+package mapper
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+func APIKeyEntityToModel(e *repo.APIKeyEntity) *model.APIKey {
+	return &model.APIKey{
+		ID:        RepoIdToModelId(e.ID),
+		Name:      e.Name,
+		Tenant:    e.TenantID,
+		Role:      model.Role(e.Role),
+		Prefix:    e.Prefix,
+		CreatedAt: e.CreatedAt,
+		RevokedAt: e.RevokedAt,
+	}
+}
@@ -0,0 +1,29 @@
+package mapper
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+func CustomFieldEntityToModel(e *repo.CustomFieldEntity) *model.CustomFieldDefinition {
+	enumValues := []string(e.EnumValues)
+	if enumValues == nil {
+		enumValues = []string{}
+	}
+	return &model.CustomFieldDefinition{
+		ID:         RepoIdToModelId(e.ID),
+		Name:       e.Name,
+		Type:       model.CustomFieldType(e.Type),
+		Required:   e.Required,
+		EnumValues: enumValues,
+	}
+}
+
+func CustomFieldToSaveModelToEntity(m *model.CustomFieldDefinitionToSave) *repo.CustomFieldEntity {
+	return &repo.CustomFieldEntity{
+		Name:       m.Name,
+		Type:       string(m.Type),
+		Required:   m.Required,
+		EnumValues: m.EnumValues,
+	}
+}
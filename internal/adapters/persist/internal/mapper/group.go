@@ -0,0 +1,14 @@
+package mapper
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+func GroupEntityToModel(e *repo.GroupEntity) *model.Group {
+	return &model.Group{
+		ID:          RepoIdToModelId(e.ID),
+		Name:        e.Name,
+		MemberCount: e.MemberCount,
+	}
+}
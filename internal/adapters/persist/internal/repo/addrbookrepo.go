@@ -2,46 +2,198 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/samber/lo"
 	"go.uber.org/zap"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type AddrBookRepo struct {
 	db                               *sqlx.DB
-	selectAllContactsWithPhonesStmt  *sqlx.NamedStmt
+	dialect                          *dialect
+	selectContactsPageStmts          map[ContactListOrder]*sqlx.NamedStmt
+	countContactsStmt                *sqlx.NamedStmt
 	insertContactStmt                *sqlx.NamedStmt
 	insertPhoneStmt                  *sqlx.NamedStmt
 	selectContactsWithPhonesByIdStmt *sqlx.NamedStmt
 	deletePhonesByContactIdStmt      *sqlx.NamedStmt
 	updateContactByIdStmt            *sqlx.NamedStmt
+	trashContactByIdStmt             *sqlx.NamedStmt
+	restoreContactByIdStmt           *sqlx.NamedStmt
+	selectContactVersionStmt         *sqlx.NamedStmt
+	selectTrashedContactsStmt        *sqlx.NamedStmt
+	selectContactIdsDeletedStmt      *sqlx.NamedStmt
 	deleteContactByIdStmt            *sqlx.NamedStmt
+	indexContactSearchStmt           *sqlx.NamedStmt
+	deleteContactSearchStmt          *sqlx.NamedStmt
+	searchContactsWithPhonesStmt     *sqlx.NamedStmt
+	selectContactsByPhoneStmt        *sqlx.NamedStmt
+	insertEmailStmt                  *sqlx.NamedStmt
+	insertAddressStmt                *sqlx.NamedStmt
+	insertURLStmt                    *sqlx.NamedStmt
+	insertCustomFieldValueStmt       *sqlx.NamedStmt
+	deleteContactDetailsStmts        []*sqlx.NamedStmt
+	deleteGroupMembershipsStmt       *sqlx.NamedStmt
+	selectContactSnapshotStmt        *sqlx.NamedStmt
+	insertContactRevisionStmt        *sqlx.NamedStmt
+	selectContactRevisionsStmt       *sqlx.NamedStmt
+	selectContactRevisionAsOfStmt    *sqlx.NamedStmt
+	selectContactRevisionStmt        *sqlx.NamedStmt
+	countContactRevisionsStmt        *sqlx.NamedStmt
+	countContactsOfTenantByIdStmt    *sqlx.NamedStmt
+	deleteContactRevisionsStmt       *sqlx.NamedStmt
 }
 
 func NewAddrBookRepo(db *sqlx.DB) *AddrBookRepo {
+	d := dialectOf(db)
 	return &AddrBookRepo{
 		db:                               db,
-		selectAllContactsWithPhonesStmt:  MustPrepareNamed(db, selectAllContactsWithPhonesSql),
-		insertContactStmt:                MustPrepareNamed(db, insertContactSql),
+		dialect:                          d,
+		selectContactsPageStmts:          mustPrepareSelectContactsPageStmts(db),
+		countContactsStmt:                MustPrepareNamed(db, countContactsSql),
+		insertContactStmt:                MustPrepareNamed(db, d.withReturningId(insertContactSql)),
 		insertPhoneStmt:                  MustPrepareNamed(db, insertPhoneSql),
 		selectContactsWithPhonesByIdStmt: MustPrepareNamed(db, selectContactsWithPhonesByIdSql),
 		deletePhonesByContactIdStmt:      MustPrepareNamed(db, deletePhonesByContactIdSql),
 		updateContactByIdStmt:            MustPrepareNamed(db, updateContactByIdSql),
+		trashContactByIdStmt:             MustPrepareNamed(db, trashContactByIdSql),
+		restoreContactByIdStmt:           MustPrepareNamed(db, restoreContactByIdSql),
+		selectContactVersionStmt:         MustPrepareNamed(db, selectContactVersionSql),
+		selectTrashedContactsStmt:        MustPrepareNamed(db, selectTrashedContactsSql),
+		selectContactIdsDeletedStmt:      MustPrepareNamed(db, selectContactIdsDeletedBeforeSql),
 		deleteContactByIdStmt:            MustPrepareNamed(db, deleteContactByIdSql),
+		indexContactSearchStmt:           MustPrepareNamed(db, d.indexContactSearchSql),
+		deleteContactSearchStmt:          MustPrepareNamed(db, d.deleteContactSearchSql),
+		searchContactsWithPhonesStmt:     MustPrepareNamed(db, d.searchContactsWithPhonesSql),
+		selectContactsByPhoneStmt:        MustPrepareNamed(db, selectContactsByPhoneSql),
+		insertEmailStmt:                  MustPrepareNamed(db, insertEmailSql),
+		insertAddressStmt:                MustPrepareNamed(db, insertAddressSql),
+		insertURLStmt:                    MustPrepareNamed(db, insertURLSql),
+		insertCustomFieldValueStmt:       MustPrepareNamed(db, insertCustomFieldValueSql),
+		deleteGroupMembershipsStmt:       MustPrepareNamed(db, deleteGroupMembershipsByContactIdSql),
+		selectContactSnapshotStmt:        MustPrepareNamed(db, selectContactSnapshotSql),
+		insertContactRevisionStmt:        MustPrepareNamed(db, insertContactRevisionSql),
+		selectContactRevisionsStmt:       MustPrepareNamed(db, selectContactRevisionsSql),
+		selectContactRevisionAsOfStmt:    MustPrepareNamed(db, selectContactRevisionAsOfSql),
+		selectContactRevisionStmt:        MustPrepareNamed(db, selectContactRevisionByNumberSql),
+		countContactRevisionsStmt:        MustPrepareNamed(db, countContactRevisionsSql),
+		countContactsOfTenantByIdStmt:    MustPrepareNamed(db, countContactsOfTenantByIdSql),
+		deleteContactRevisionsStmt:       MustPrepareNamed(db, deleteContactRevisionsByContactIdSql),
+		deleteContactDetailsStmts: lo.Map(deleteContactDetailsSqls, func(query string, _ int) *sqlx.NamedStmt {
+			return MustPrepareNamed(db, query)
+		}),
 	}
 }
 
-// ContactWithPhonesEntity is a result of JOIN
-type ContactWithPhonesEntity struct {
+func mustPrepareSelectContactsPageStmts(db *sqlx.DB) map[ContactListOrder]*sqlx.NamedStmt {
+	stmts := make(map[ContactListOrder]*sqlx.NamedStmt)
+	for sortBy, columns := range contactListSortColumns {
+		for _, desc := range []bool{false, true} {
+			order := ContactListOrder{SortBy: sortBy, Desc: desc}
+			stmts[order] = MustPrepareNamed(db, buildSelectContactsPageSql(columns[0], columns[1], desc))
+		}
+	}
+	return stmts
+}
+
+const (
+	ContactListSortByLastName  = "last_name"
+	ContactListSortByFirstName = "first_name"
+)
+
+// contactListSortColumns contains primary and secondary sort columns for every supported sort field
+var contactListSortColumns = map[string][2]string{
+	ContactListSortByLastName:  {"last_name", "first_name"},
+	ContactListSortByFirstName: {"first_name", "last_name"},
+}
+
+type ContactListOrder struct {
+	SortBy string
+	Desc   bool
+}
+
+// CursorOf returns keyset values of the contact for this order, so that the next page can start right after it
+func (o ContactListOrder) CursorOf(e *ContactWithPhonesEntity) *ContactListCursor {
+	if o.SortBy == ContactListSortByFirstName {
+		return &ContactListCursor{Primary: e.FirstName, Secondary: e.LastName, ID: e.ID}
+	}
+	return &ContactListCursor{Primary: e.LastName, Secondary: e.FirstName, ID: e.ID}
+}
+
+// ContactListFilter selects contacts of the tenant, empty filter fields (except for TenantID) are ignored
+type ContactListFilter struct {
+	TenantID       string
+	LastNamePrefix string
+	PhoneType      string
+	// CustomFieldID selects contacts having the custom field equal to CustomFieldValue, zero disables the filter
+	CustomFieldID    int64
+	CustomFieldValue string
+	GroupID          int64 // selects members of the group, zero disables the filter
+}
+
+// ContactListCursor holds keyset values of the last contact from the previous page
+type ContactListCursor struct {
+	Primary   string
+	Secondary string
 	ID        int64
-	FirstName string
-	LastName  string
-	Phones    []*PhoneEntity
 }
 
+type ContactListParams struct {
+	Order  ContactListOrder
+	Filter ContactListFilter
+	After  *ContactListCursor // nil for the first page
+	Limit  int
+}
+
+func (f ContactListFilter) toArgs() map[string]any {
+	var lastNamePattern string
+	if f.LastNamePrefix != "" {
+		lastNamePattern = likeEscaper.Replace(f.LastNamePrefix) + "%"
+	}
+	return map[string]any{
+		"tenantId":         f.TenantID,
+		"lastNamePattern":  lastNamePattern,
+		"phoneType":        f.PhoneType,
+		"customFieldId":    f.CustomFieldID,
+		"customFieldValue": f.CustomFieldValue,
+		"groupId":          f.GroupID,
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ContactWithPhonesEntity is a result of JOIN. It is also saved as JSON snapshot of contact revision, json tags
+// define format of the snapshot, so they must stay backward compatible.
+type ContactWithPhonesEntity struct {
+	ID        int64            `json:"-"`
+	FirstName string           `json:"first_name"`
+	LastName  string           `json:"last_name"`
+	Phones    []*PhoneEntity   `json:"phones"`
+	Emails    []*EmailEntity   `json:"emails"`
+	Addresses []*AddressEntity `json:"addresses"`
+	URLs      []*URLEntity     `json:"urls"`
+	// CustomFieldValues are selected with name and type of their fields. Snapshot keeps the name and type but not
+	// field id, so that revert matches values to fields by name and drops values of fields deleted since then.
+	CustomFieldValues []*CustomFieldValueEntity `json:"custom_fields"`
+	// Groups are selected only, memberships are changed by GroupRepo
+	Groups    []*ContactGroupEntity `json:"-"`
+	DeletedAt *time.Time            `json:"-"` // nil unless contact is in trash
+	// Version is selected with a single contact only. Contact is saved only if it still has this version,
+	// zero saves contact of any version.
+	Version int `json:"-"`
+}
+
+// ErrVersionMismatch is returned when contact is saved on condition that it has expected version, but it has another
+var ErrVersionMismatch = errors.New("contact version mismatch")
+
 type PhoneEntity struct {
-	PhoneType   string `db:"type"`
-	PhoneNumber string `db:"phone_number"`
+	PhoneType   string `db:"type" json:"type"`
+	PhoneNumber string `db:"phone_number" json:"phone_number"`
+	PhoneE164   string `db:"phone_e164" json:"phone_e164"` // empty for phones saved before numbers were normalized
 }
 
 type contactWithPhoneRow struct {
@@ -50,6 +202,11 @@ type contactWithPhoneRow struct {
 	LastName    string  `db:"last_name"`
 	PhoneType   *string `db:"phone_type"`
 	PhoneNumber *string `db:"phone_number"`
+	PhoneE164   *string `db:"phone_e164"`
+	// DeletedAt is selected by requests returning contacts in trash only
+	DeletedAt *time.Time `db:"deleted_at"`
+	// Version is selected by requests returning a single contact only
+	Version int `db:"version"`
 }
 
 func (p *contactWithPhoneRow) buildPhoneEntity() *PhoneEntity {
@@ -59,6 +216,7 @@ func (p *contactWithPhoneRow) buildPhoneEntity() *PhoneEntity {
 	return &PhoneEntity{
 		PhoneType:   *p.PhoneType,
 		PhoneNumber: *p.PhoneNumber,
+		PhoneE164:   lo.FromPtr(p.PhoneE164),
 	}
 }
 
@@ -80,129 +238,242 @@ func (c *contactWithPhoneRow) toContactEntity() *ContactWithPhonesEntity {
 		ID:        c.ID,
 		FirstName: c.FirstName,
 		LastName:  c.LastName,
+		DeletedAt: c.DeletedAt,
+		Version:   c.Version,
 	}
 }
 
-func (r *AddrBookRepo) AddContact(ctx context.Context, c *ContactWithPhonesEntity) (*ContactWithPhonesEntity, error) {
-	tx := r.db.MustBeginTx(ctx, nil)
-	defer tx.Rollback()
+// AddContact inserts contact of the tenant and records its first revision made by actor
+func (r *AddrBookRepo) AddContact(
+	ctx context.Context,
+	tenantId string,
+	actor string,
+	c *ContactWithPhonesEntity,
+) (*ContactWithPhonesEntity, error) {
+	t := r.BeginTx(ctx, tenantId, actor)
+	defer t.Rollback()
 
-	var err error
-	newc := *c
-	newc.ID, err = ExecNamedStmtReturningLastInsertId(ctx, tx.NamedStmtContext(ctx, r.insertContactStmt), map[string]any{
-		"firstName": c.FirstName,
-		"lastName":  c.LastName,
-	})
-	if err != nil {
-		err = fmt.Errorf("error inserting contact into database: %w", err)
-		zap.S().Errorln(err)
-		return nil, err
+	newc, err := t.AddContact(ctx, c)
+	if err == nil {
+		err = t.Commit()
 	}
-	// insert phones for contact
-	insertPhoneStmt := tx.NamedStmtContext(ctx, r.insertPhoneStmt)
-	for _, ph := range c.Phones {
-		if err = r.insertPhone(ctx, insertPhoneStmt, newc.ID, ph); err != nil {
-			return nil, err
-		}
-	}
-	if err = tx.Commit(); err != nil {
-		err = fmt.Errorf("error committing transaction: %w", err)
-		zap.S().Errorln(err)
+	if err != nil {
 		return nil, err
 	}
-	return &newc, nil
+	return newc, nil
 }
 
 func (r *AddrBookRepo) insertPhone(ctx context.Context, insertPhoneStmt *sqlx.NamedStmt, contactId int64, ph *PhoneEntity) error {
 	_, err := insertPhoneStmt.ExecContext(ctx, map[string]any{
 		"type":        ph.PhoneType,
 		"phoneNumber": ph.PhoneNumber,
+		"phoneE164":   nullIfEmpty(ph.PhoneE164),
 		"contactId":   contactId,
 	})
 	if err != nil {
-		err = fmt.Errorf("error inserting contact phone into database: %w", err)
+		err = dbErrorf("error inserting contact phone into database: %w", err)
 		zap.S().Errorln(err)
 	}
 	return err
 }
 
-func (r *AddrBookRepo) UpdateContact(ctx context.Context, c *ContactWithPhonesEntity) (found bool, err error) {
-	tx := r.db.MustBeginTx(ctx, nil)
-	defer tx.Rollback()
+// reindexContact replaces full-text search document of the contact, it must be called within the same
+// transaction that modifies contact or its phones
+func (r *AddrBookRepo) reindexContact(ctx context.Context, tx *sqlx.Tx, contactId int64) error {
+	args := map[string]any{
+		"contactId": contactId,
+	}
+	_, err := tx.NamedStmtContext(ctx, r.deleteContactSearchStmt).ExecContext(ctx, args)
+	if err == nil {
+		_, err = tx.NamedStmtContext(ctx, r.indexContactSearchStmt).ExecContext(ctx, args)
+	}
+	if err != nil {
+		err = dbErrorf("error indexing contact id=%d for search: %w", contactId, err)
+		zap.S().Errorln(err)
+	}
+	return err
+}
 
-	result, err := tx.NamedStmtContext(ctx, r.updateContactByIdStmt).ExecContext(ctx, map[string]any{
-		"contactId": c.ID,
-		"firstName": c.FirstName,
-		"lastName":  c.LastName,
+// UpdateContact replaces contact of the tenant along with all its details and records revision made by actor.
+// ErrVersionMismatch is returned if c.Version is not zero and the contact has another version.
+func (r *AddrBookRepo) UpdateContact(
+	ctx context.Context,
+	tenantId string,
+	actor string,
+	c *ContactWithPhonesEntity,
+) (found bool, err error) {
+	t := r.BeginTx(ctx, tenantId, actor)
+	defer t.Rollback()
+	return t.commitIfFound(t.UpdateContact(ctx, c))
+}
+
+// RevertContact replaces contact of the tenant with its state taken from the revision and records new revision
+// made by actor, which refers to the reverted one
+func (r *AddrBookRepo) RevertContact(
+	ctx context.Context,
+	tenantId string,
+	actor string,
+	c *ContactWithPhonesEntity,
+	revision int,
+) (found bool, err error) {
+	t := r.BeginTx(ctx, tenantId, actor)
+	defer t.Rollback()
+	return t.commitIfFound(t.replaceContact(ctx, c, RevisionActionReverted, revision))
+}
+
+func (r *AddrBookRepo) SelectContactByID(ctx context.Context, tenantId string, ID int64) (*ContactWithPhonesEntity, error) {
+	return r.selectContactByID(ctx, r.db, r.selectContactsWithPhonesByIdStmt, tenantId, ID)
+}
+
+// selectContactByID selects contact with given statement, contact details are selected with q, which is either
+// database or transaction of the statement
+func (r *AddrBookRepo) selectContactByID(
+	ctx context.Context,
+	q sqlx.ExtContext,
+	stmt *sqlx.NamedStmt,
+	tenantId string,
+	ID int64,
+) (*ContactWithPhonesEntity, error) {
+	var rows []*contactWithPhoneRow
+	err := stmt.SelectContext(ctx, &rows, map[string]any{
+		"id":       ID,
+		"tenantId": tenantId,
 	})
 	if err != nil {
-		err = fmt.Errorf("error updating contact id=%d in database: %w", c.ID, err)
+		err = dbErrorf("error selecting contact by id=%d in database: %w", ID, err)
 		zap.S().Errorln(err)
-		return false, err
+		return nil, err
 	}
-	if MustGetRowsAffected(result) == 0 {
-		zap.S().Warnln("no contact record found by id:", c.ID)
-		return false, nil
+	if len(rows) == 0 {
+		zap.S().Warnf("contact id=%d not found", ID)
+		return nil, nil
 	}
+	entities := mergeContactWithPhoneRows(rows)
+	if err = r.selectContactDetails(ctx, q, entities); err != nil {
+		return nil, err
+	}
+	return entities[0], nil
+}
 
-	_, err = tx.NamedStmtContext(ctx, r.deletePhonesByContactIdStmt).ExecContext(ctx, map[string]any{
-		"contactId": c.ID,
+// SelectContactsByPhone returns contacts of the tenant having a phone with given E.164 number
+func (r *AddrBookRepo) SelectContactsByPhone(
+	ctx context.Context,
+	tenantId string,
+	phoneE164 string,
+) ([]*ContactWithPhonesEntity, error) {
+	var rows []*contactWithPhoneRow
+	err := r.selectContactsByPhoneStmt.SelectContext(ctx, &rows, map[string]any{
+		"tenantId":  tenantId,
+		"phoneE164": phoneE164,
 	})
 	if err != nil {
-		err = fmt.Errorf("error updating contact phone in database: %w", err)
+		err = dbErrorf("error selecting contacts by phone in database: %w", err)
 		zap.S().Errorln(err)
-		return false, err
+		return nil, err
 	}
+	return r.mergeContactRows(ctx, rows)
+}
 
-	// insert phones for contact
-	insertPhoneStmt := tx.NamedStmtContext(ctx, r.insertPhoneStmt)
-	for _, ph := range c.Phones {
-		if err = r.insertPhone(ctx, insertPhoneStmt, c.ID, ph); err != nil {
-			return false, err
-		}
+// SelectContactsPage returns up to params.Limit contacts ordered by params.Order and matching params.Filter
+func (r *AddrBookRepo) SelectContactsPage(ctx context.Context, params ContactListParams) ([]*ContactWithPhonesEntity, error) {
+	stmt, ok := r.selectContactsPageStmts[params.Order]
+	if !ok {
+		return nil, fmt.Errorf("unsupported contacts sort order: %+v", params.Order)
 	}
-
-	if err = tx.Commit(); err != nil {
-		err = fmt.Errorf("error committing transaction: %w", err)
+	args := params.Filter.toArgs()
+	args["limit"] = params.Limit
+	if params.After != nil {
+		args["afterPrimary"] = params.After.Primary
+		args["afterSecondary"] = params.After.Secondary
+		args["afterId"] = params.After.ID
+	} else {
+		args["afterPrimary"] = ""
+		args["afterSecondary"] = ""
+		args["afterId"] = 0
+	}
+	var rows []*contactWithPhoneRow
+	err := stmt.SelectContext(ctx, &rows, args)
+	if err != nil {
+		err = dbErrorf("error selecting contacts page in database: %w", err)
 		zap.S().Errorln(err)
+		return nil, err
 	}
-	return true, err
+	return r.mergeContactRows(ctx, rows)
 }
 
-func (r *AddrBookRepo) SelectContactByID(ctx context.Context, ID int64) (*ContactWithPhonesEntity, error) {
+// SearchContacts returns up to limit contacts matching full-text search text ranked by relevance.
+// Every word of the text is matched as a prefix of contact first name, last name or a fragment of contact phone number.
+func (r *AddrBookRepo) SearchContacts(
+	ctx context.Context,
+	tenantId string,
+	text string,
+	limit int,
+) ([]*ContactWithPhonesEntity, error) {
+	terms := searchTerms(text)
+	if len(terms) == 0 {
+		return []*ContactWithPhonesEntity{}, nil
+	}
+	query := r.dialect.buildSearchMatchQuery(terms)
 	var rows []*contactWithPhoneRow
-	err := r.selectContactsWithPhonesByIdStmt.SelectContext(ctx, &rows, map[string]any{
-		"id": ID,
+	err := r.searchContactsWithPhonesStmt.SelectContext(ctx, &rows, map[string]any{
+		"tenantId": tenantId,
+		"query":    query,
+		"limit":    limit,
 	})
 	if err != nil {
-		zap.S().Errorln("Error selecting contact by id=%d in database:", ID, err)
+		err = dbErrorf("error searching contacts in database: %w", err)
+		zap.S().Errorln(err)
 		return nil, err
 	}
-	if len(rows) == 0 {
-		zap.S().Warnf("contact id=%d not found", ID)
-		return nil, nil
-	}
-	row := rows[0]
-	entity := row.toContactEntity()
-	entity.Phones = make([]*PhoneEntity, len(rows))
-	for i, row := range rows {
-		entity.Phones[i] = row.buildPhoneEntity()
+	return r.mergeContactRows(ctx, rows)
+}
+
+// searchTerms splits user input into words for full-text search. Punctuation is dropped (so it cannot break
+// full-text query syntax) and phone-like words are reduced to digits, e.g. "jo 503-55" becomes ["jo", "50355"].
+func searchTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.Fields(text) {
+		term := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				return r
+			}
+			return -1
+		}, word)
+		if term != "" {
+			terms = append(terms, term)
+		}
 	}
-	return entity, nil
+	return terms
 }
 
-func (r *AddrBookRepo) SelectAllContacts(ctx context.Context) ([]*ContactWithPhonesEntity, error) {
-	var rows []*contactWithPhoneRow
-	err := r.selectAllContactsWithPhonesStmt.SelectContext(ctx, &rows, map[string]any{})
+// CountContacts returns total number of contacts matching the filter
+func (r *AddrBookRepo) CountContacts(ctx context.Context, filter ContactListFilter) (int, error) {
+	var count int
+	err := r.countContactsStmt.GetContext(ctx, &count, filter.toArgs())
 	if err != nil {
-		zap.S().Errorln("Error selecting all contacts in database:", err)
+		err = dbErrorf("error counting contacts in database: %w", err)
+		zap.S().Errorln(err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// mergeContactRows merges rows of contacts joined with phones and loads the rest of contact details
+func (r *AddrBookRepo) mergeContactRows(ctx context.Context, rows []*contactWithPhoneRow) ([]*ContactWithPhonesEntity, error) {
+	entities := mergeContactWithPhoneRows(rows)
+	if err := r.selectContactDetails(ctx, r.db, entities); err != nil {
 		return nil, err
 	}
+	return entities, nil
+}
+
+// mergeContactWithPhoneRows merges rows of contacts joined with phones, contact order is preserved
+func mergeContactWithPhoneRows(rows []*contactWithPhoneRow) []*ContactWithPhonesEntity {
 	// The response will be something like:
 	// <id> <first_name> <last_name> <"phone.type"> <"phone.phone_number">
 	//  2    Toly         Pochkin     home           503-999-9999
-	//  3    Julia        Pod         home           333-111-1111
 	//  2    Toly         Pochkin     mobile         503-555-7777
+	//  3    Julia        Pod         home           333-111-1111
 	//  3    Julia        Pod         mobile         333-555-2222
 	// Our goal is to get rid of duplicate contacts while merging phones from duplicate contacts into a single distinct contact
 	entities := MergeJoinedRows[*contactWithPhoneRow, *ContactWithPhonesEntity](
@@ -218,30 +489,107 @@ func (r *AddrBookRepo) SelectAllContacts(ctx context.Context) ([]*ContactWithPho
 			return existingEntity
 		},
 	)
-	return entities, nil
+	return entities
+}
+
+// DeleteContact moves contact of the tenant to trash and records revision made by actor, contact is kept along with
+// its details until it is restored or purged. ErrVersionMismatch is returned if version is not zero and the contact
+// has another version.
+func (r *AddrBookRepo) DeleteContact(
+	ctx context.Context,
+	tenantId string,
+	actor string,
+	id int64,
+	version int,
+) (found bool, err error) {
+	t := r.BeginTx(ctx, tenantId, actor)
+	defer t.Rollback()
+	return t.commitIfFound(t.DeleteContact(ctx, id, version))
 }
 
-func (r *AddrBookRepo) DeleteContact(ctx context.Context, id int64) (found bool, err error) {
-	tx := r.db.MustBeginTx(ctx, nil)
-	defer tx.Rollback()
+// checkVersion finds out why contact was not changed on condition of its version: found is false if there is
+// no such contact, otherwise ErrVersionMismatch is returned
+func (r *AddrBookRepo) checkVersion(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	tenantId string,
+	id int64,
+	version int,
+) (found bool, err error) {
+	var versions []int
+	if version != 0 {
+		err = tx.NamedStmtContext(ctx, r.selectContactVersionStmt).SelectContext(ctx, &versions, map[string]any{
+			"id":       id,
+			"tenantId": tenantId,
+		})
+		if err != nil {
+			err = dbErrorf("error selecting version of contact id=%d: %w", id, err)
+			zap.S().Errorln(err)
+			return false, err
+		}
+	}
+	if len(versions) == 0 {
+		zap.S().Warnln("no contact record found by id:", id)
+		return false, nil
+	}
+	return true, fmt.Errorf("%w: contact id=%d has version=%d, expected version=%d", ErrVersionMismatch, id, versions[0], version)
+}
 
-	_, err = tx.NamedStmtContext(ctx, r.deletePhonesByContactIdStmt).ExecContext(ctx, map[string]any{
-		"contactId": id,
+// RestoreContact moves contact of the tenant back from trash and records revision made by actor
+func (r *AddrBookRepo) RestoreContact(ctx context.Context, tenantId string, actor string, id int64) (found bool, err error) {
+	t := r.BeginTx(ctx, tenantId, actor)
+	defer t.Rollback()
+	return t.commitIfFound(t.RestoreContact(ctx, id))
+}
+
+// SelectTrashedContacts returns up to limit contacts of the tenant in trash, the most recently deleted go first
+func (r *AddrBookRepo) SelectTrashedContacts(
+	ctx context.Context,
+	tenantId string,
+	limit int,
+) ([]*ContactWithPhonesEntity, error) {
+	var rows []*contactWithPhoneRow
+	err := r.selectTrashedContactsStmt.SelectContext(ctx, &rows, map[string]any{
+		"tenantId": tenantId,
+		"limit":    limit,
 	})
 	if err != nil {
-		err = fmt.Errorf("error deleting phone contacts by contact id=%d: %w", id, err)
+		err = dbErrorf("error selecting contacts in trash in database: %w", err)
 		zap.S().Errorln(err)
-		return false, err
+		return nil, err
 	}
+	return r.mergeContactRows(ctx, rows)
+}
 
-	result, err := tx.NamedStmtContext(ctx, r.deleteContactByIdStmt).ExecContext(ctx, map[string]any{
-		"id": id,
+// PurgeContacts permanently deletes up to limit contacts of all tenants which were moved to trash before deletedBefore,
+// every contact is deleted in its own transaction. Ids of deleted contacts are returned.
+func (r *AddrBookRepo) PurgeContacts(ctx context.Context, deletedBefore time.Time, limit int) ([]int64, error) {
+	var ids []int64
+	err := r.selectContactIdsDeletedStmt.SelectContext(ctx, &ids, map[string]any{
+		"deletedBefore": deletedBefore.UTC(),
+		"limit":         limit,
 	})
-	found = MustGetRowsAffected(result) > 0
-
-	if err = tx.Commit(); err != nil {
-		err = fmt.Errorf("error committing transaction: %w", err)
+	if err != nil {
+		err = dbErrorf("error selecting contacts to purge: %w", err)
 		zap.S().Errorln(err)
+		return nil, err
+	}
+	purged := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if err = r.purgeContact(ctx, id); err != nil {
+			return purged, err
+		}
+		purged = append(purged, id)
+	}
+	return purged, nil
+}
+
+// purgeContact deletes contact in its own transaction, purge is not made on behalf of any tenant or actor
+func (r *AddrBookRepo) purgeContact(ctx context.Context, id int64) error {
+	t := r.BeginTx(ctx, "", "")
+	defer t.Rollback()
+	if err := t.purgeContact(ctx, id); err != nil {
+		return err
 	}
-	return
+	return t.Commit()
 }
@@ -0,0 +1,222 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"github.com/samber/lo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const (
+	testTenant = "tenant-a"
+	testActor  = "tester"
+)
+
+func addTestContact(t *testing.T, r *AddrBookRepo, tenantId string, firstName string, lastName string, phones ...string) *ContactWithPhonesEntity {
+	t.Helper()
+	c := &ContactWithPhonesEntity{FirstName: firstName, LastName: lastName}
+	for _, number := range phones {
+		c.Phones = append(c.Phones, &PhoneEntity{PhoneType: "home", PhoneNumber: number})
+	}
+	newc, err := r.AddContact(context.Background(), tenantId, testActor, c)
+	if err != nil {
+		t.Fatalf("error adding contact %s %s: %v", firstName, lastName, err)
+	}
+	return newc
+}
+
+func TestInsertReturnsID(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		first := addTestContact(t, r, testTenant, "John", "Doe", "503-555-7777")
+		second := addTestContact(t, r, testTenant, "Jane", "Smith")
+		if first.ID <= 0 || second.ID <= first.ID {
+			t.Fatalf("contact ids are not increasing: %d, %d", first.ID, second.ID)
+		}
+		saved, err := r.SelectContactByID(ctx, testTenant, first.ID)
+		if err != nil || saved == nil {
+			t.Fatalf("contact id=%d is not selected: %v", first.ID, err)
+		}
+		if saved.FirstName != "John" || len(saved.Phones) != 1 || saved.Phones[0].PhoneNumber != "503-555-7777" || saved.Version != 1 {
+			t.Errorf("unexpected contact: %+v", saved)
+		}
+
+		group, err := NewGroupRepo(db).AddGroup(ctx, testTenant, "Friends")
+		if err != nil || group.ID <= 0 {
+			t.Errorf("group id is not returned: %+v, %v", group, err)
+		}
+		field, err := NewCustomFieldRepo(db).AddCustomField(ctx, testTenant, &CustomFieldEntity{Name: "tier", Type: "text"})
+		if err != nil || field.ID <= 0 {
+			t.Errorf("custom field id is not returned: %+v, %v", field, err)
+		}
+		key, err := NewAPIKeyRepo(db).AddAPIKey(ctx, "test", testTenant, "editor", "abc", "hash")
+		if err != nil || key.ID <= 0 {
+			t.Errorf("API key id is not returned: %+v, %v", key, err)
+		}
+	})
+}
+
+func TestSearchContacts(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		john := addTestContact(t, r, testTenant, "John", "Doe", "503-555-7777")
+		jane := addTestContact(t, r, testTenant, "Jane", "Johnson", "(415) 555-0101")
+		joe := addTestContact(t, r, testTenant, "Joe", "Black")
+		addTestContact(t, r, "tenant-b", "John", "Other", "503-555-7777")
+
+		tests := []struct {
+			text string
+			want []int64
+		}{
+			{"john", []int64{john.ID, jane.ID}},
+			{"jo", []int64{john.ID, jane.ID, joe.ID}},
+			{"jo do", []int64{john.ID}},
+			{"5557", []int64{john.ID}},
+			{"555", []int64{john.ID, jane.ID}},
+			{"415-555", []int64{jane.ID}},
+			{"o'bla", nil},
+			{"bla!", []int64{joe.ID}},
+			{"smith", nil},
+			{"&|!:*", nil},
+		}
+		for _, tt := range tests {
+			found, err := r.SearchContacts(ctx, testTenant, tt.text, 10)
+			if err != nil {
+				t.Errorf("search %q failed: %v", tt.text, err)
+				continue
+			}
+			if got := sortedIds(found); !reflect.DeepEqual(got, sortedInt64s(tt.want)) {
+				t.Errorf("search %q found %v, want %v", tt.text, got, tt.want)
+			}
+		}
+
+		// the best match goes first, search document follows contact changes
+		found, err := r.SearchContacts(ctx, testTenant, "johnson", 10)
+		if err != nil || len(found) != 1 || found[0].ID != jane.ID || found[0].Phones[0].PhoneNumber != "(415) 555-0101" {
+			t.Errorf("search by last name returned %+v, %v", found, err)
+		}
+		jane.LastName = "Walker"
+		if _, err = r.UpdateContact(ctx, testTenant, testActor, jane); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = r.DeleteContact(ctx, testTenant, testActor, john.ID, 0); err != nil {
+			t.Fatal(err)
+		}
+		for text, want := range map[string][]int64{"johnson": nil, "walk": {jane.ID}, "john": nil, "5557": nil} {
+			found, err = r.SearchContacts(ctx, testTenant, text, 10)
+			if got := sortedIds(found); err != nil || !reflect.DeepEqual(got, sortedInt64s(want)) {
+				t.Errorf("search %q after changes found %v, want %v (error %v)", text, got, want, err)
+			}
+		}
+
+		found, err = r.SearchContacts(ctx, testTenant, "jo", 1)
+		if err != nil || len(found) != 1 {
+			t.Errorf("search is not limited: %d contacts found, error %v", len(found), err)
+		}
+	})
+}
+
+func TestSelectContactsPage(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		for _, name := range [][2]string{{"Ann", "Lee"}, {"Bob", "Lee"}, {"Cid", "Adams"}, {"Dan", "Zane"}} {
+			addTestContact(t, r, testTenant, name[0], name[1], "503-555-0001", "503-555-0002")
+		}
+		addTestContact(t, r, "tenant-b", "Eve", "Brown")
+
+		params := ContactListParams{
+			Order:  ContactListOrder{SortBy: ContactListSortByLastName},
+			Filter: ContactListFilter{TenantID: testTenant},
+			Limit:  3,
+		}
+		page, err := r.SelectContactsPage(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(page); !reflect.DeepEqual(got, []string{"Cid Adams", "Ann Lee", "Bob Lee"}) {
+			t.Errorf("first page is %v", got)
+		}
+		if len(page[0].Phones) != 2 {
+			t.Errorf("contact has %d phones, want 2", len(page[0].Phones))
+		}
+		params.After = params.Order.CursorOf(page[len(page)-1])
+		if page, err = r.SelectContactsPage(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+		if got := names(page); !reflect.DeepEqual(got, []string{"Dan Zane"}) {
+			t.Errorf("second page is %v", got)
+		}
+
+		params = ContactListParams{
+			Order:  ContactListOrder{SortBy: ContactListSortByFirstName, Desc: true},
+			Filter: ContactListFilter{TenantID: testTenant, LastNamePrefix: "le"},
+			Limit:  10,
+		}
+		if page, err = r.SelectContactsPage(ctx, params); err != nil {
+			t.Fatal(err)
+		}
+		if got := names(page); !reflect.DeepEqual(got, []string{"Bob Lee", "Ann Lee"}) {
+			t.Errorf("filtered page is %v", got)
+		}
+		count, err := r.CountContacts(ctx, params.Filter)
+		if err != nil || count != 2 {
+			t.Errorf("count is %d, want 2 (error %v)", count, err)
+		}
+	})
+}
+
+func sortedIds(contacts []*ContactWithPhonesEntity) []int64 {
+	return sortedInt64s(lo.Map(contacts, func(c *ContactWithPhonesEntity, _ int) int64 { return c.ID }))
+}
+
+func sortedInt64s(ids []int64) []int64 {
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = append([]int64(nil), ids...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func names(contacts []*ContactWithPhonesEntity) []string {
+	return lo.Map(contacts, func(c *ContactWithPhonesEntity, _ int) string { return c.FirstName + " " + c.LastName })
+}
+
+func TestBusySQLiteIsUnavailable(t *testing.T) {
+	db := openSQLite(t)
+	r := NewAddrBookRepo(db)
+	addTestContact(t, r, testTenant, "John", "Doe")
+	var file struct {
+		Seq  int
+		Name string
+		File string
+	}
+	if err := db.Get(&file, "PRAGMA database_list"); err != nil {
+		t.Fatal(err)
+	}
+
+	// another service replica keeps write lock, this one gives up waiting for it almost immediately
+	tx := db.MustBegin()
+	defer tx.Rollback()
+	tx.MustExec("UPDATE contacts SET version = version + 1")
+	busyDb, err := sqlx.Connect("sqlite3", file.File+"?_busy_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busyDb.Close()
+
+	_, err = NewAddrBookRepo(busyDb).AddContact(context.Background(), testTenant, testActor, &ContactWithPhonesEntity{
+		FirstName: "Jane",
+		LastName:  "Doe",
+	})
+	if !errors.Is(err, model.ErrUnavailable) {
+		t.Errorf("contact is added to locked database with error %v, want %v", err, model.ErrUnavailable)
+	}
+}
@@ -0,0 +1,242 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+// AddrBookTx changes contacts of the tenant within a single transaction, so that a number of changes is applied
+// atomically. Every change is recorded in contact revisions as made by actor.
+type AddrBookTx struct {
+	r        *AddrBookRepo
+	tx       *sqlx.Tx
+	tenantId string
+	actor    string
+}
+
+// BeginTx starts transaction in address book of the tenant, it must be either committed or rolled back
+func (r *AddrBookRepo) BeginTx(ctx context.Context, tenantId string, actor string) *AddrBookTx {
+	return &AddrBookTx{
+		r:        r,
+		tx:       r.db.MustBeginTx(ctx, nil),
+		tenantId: tenantId,
+		actor:    actor,
+	}
+}
+
+func (t *AddrBookTx) Commit() error {
+	if err := t.tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return err
+	}
+	return nil
+}
+
+// Rollback discards changes unless transaction is already committed, so it can be deferred
+func (t *AddrBookTx) Rollback() {
+	_ = t.tx.Rollback()
+}
+
+// commitIfFound commits transaction if changed contact was found and no error occurred
+func (t *AddrBookTx) commitIfFound(found bool, err error) (bool, error) {
+	if err != nil || !found {
+		return found, err
+	}
+	return true, t.Commit()
+}
+
+// AddContact inserts contact and records its first revision
+func (t *AddrBookTx) AddContact(ctx context.Context, c *ContactWithPhonesEntity) (*ContactWithPhonesEntity, error) {
+	var err error
+	newc := *c
+	newc.ID, err = t.r.dialect.execInsertReturningId(ctx, t.tx.NamedStmtContext(ctx, t.r.insertContactStmt), map[string]any{
+		"tenantId":  t.tenantId,
+		"firstName": c.FirstName,
+		"lastName":  c.LastName,
+	})
+	if err != nil {
+		err = dbErrorf("error inserting contact into database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	// insert phones for contact
+	insertPhoneStmt := t.tx.NamedStmtContext(ctx, t.r.insertPhoneStmt)
+	for _, ph := range c.Phones {
+		if err = t.r.insertPhone(ctx, insertPhoneStmt, newc.ID, ph); err != nil {
+			return nil, err
+		}
+	}
+	if err = t.r.insertContactDetails(ctx, t.tx, newc.ID, c); err != nil {
+		return nil, err
+	}
+	if err = t.r.reindexContact(ctx, t.tx, newc.ID); err != nil {
+		return nil, err
+	}
+	if err = t.r.insertRevision(ctx, t.tx, newc.ID, RevisionActionCreated, t.actor, time.Now().UTC(), 0); err != nil {
+		return nil, err
+	}
+	return &newc, nil
+}
+
+// UpdateContact replaces contact along with all its details and records its revision.
+// ErrVersionMismatch is returned if c.Version is not zero and the contact has another version.
+func (t *AddrBookTx) UpdateContact(ctx context.Context, c *ContactWithPhonesEntity) (found bool, err error) {
+	return t.replaceContact(ctx, c, RevisionActionUpdated, 0)
+}
+
+func (t *AddrBookTx) replaceContact(
+	ctx context.Context,
+	c *ContactWithPhonesEntity,
+	action string,
+	revertedTo int,
+) (found bool, err error) {
+	result, err := t.tx.NamedStmtContext(ctx, t.r.updateContactByIdStmt).ExecContext(ctx, map[string]any{
+		"contactId": c.ID,
+		"tenantId":  t.tenantId,
+		"firstName": c.FirstName,
+		"lastName":  c.LastName,
+		"version":   c.Version,
+	})
+	if err != nil {
+		err = dbErrorf("error updating contact id=%d in database: %w", c.ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if MustGetRowsAffected(result) == 0 {
+		return t.r.checkVersion(ctx, t.tx, t.tenantId, c.ID, c.Version)
+	}
+
+	_, err = t.tx.NamedStmtContext(ctx, t.r.deletePhonesByContactIdStmt).ExecContext(ctx, map[string]any{
+		"contactId": c.ID,
+	})
+	if err != nil {
+		err = dbErrorf("error updating contact phone in database: %w", err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+
+	// insert phones for contact
+	insertPhoneStmt := t.tx.NamedStmtContext(ctx, t.r.insertPhoneStmt)
+	for _, ph := range c.Phones {
+		if err = t.r.insertPhone(ctx, insertPhoneStmt, c.ID, ph); err != nil {
+			return false, err
+		}
+	}
+	if err = t.r.deleteContactDetails(ctx, t.tx, c.ID); err != nil {
+		return false, err
+	}
+	if err = t.r.insertContactDetails(ctx, t.tx, c.ID, c); err != nil {
+		return false, err
+	}
+	if err = t.r.reindexContact(ctx, t.tx, c.ID); err != nil {
+		return false, err
+	}
+	if err = t.r.insertRevision(ctx, t.tx, c.ID, action, t.actor, time.Now().UTC(), revertedTo); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteContact moves contact to trash and records its revision, contact is kept along with its details until it is
+// restored or purged. ErrVersionMismatch is returned if version is not zero and the contact has another version.
+func (t *AddrBookTx) DeleteContact(ctx context.Context, id int64, version int) (found bool, err error) {
+	now := time.Now().UTC()
+	result, err := t.tx.NamedStmtContext(ctx, t.r.trashContactByIdStmt).ExecContext(ctx, map[string]any{
+		"id":        id,
+		"tenantId":  t.tenantId,
+		"deletedAt": now,
+		"version":   version,
+	})
+	if err != nil {
+		err = dbErrorf("error moving contact id=%d to trash: %w", id, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if MustGetRowsAffected(result) == 0 {
+		return t.r.checkVersion(ctx, t.tx, t.tenantId, id, version)
+	}
+	if err = t.r.insertRevision(ctx, t.tx, id, RevisionActionDeleted, t.actor, now, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RestoreContact moves contact back from trash and records its revision
+func (t *AddrBookTx) RestoreContact(ctx context.Context, id int64) (found bool, err error) {
+	result, err := t.tx.NamedStmtContext(ctx, t.r.restoreContactByIdStmt).ExecContext(ctx, map[string]any{
+		"id":       id,
+		"tenantId": t.tenantId,
+	})
+	if err != nil {
+		err = dbErrorf("error restoring contact id=%d from trash: %w", id, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if MustGetRowsAffected(result) == 0 {
+		zap.S().Warnln("no contact record found in trash by id:", id)
+		return false, nil
+	}
+	if err = t.r.insertRevision(ctx, t.tx, id, RevisionActionRestored, t.actor, time.Now().UTC(), 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// purgeContact deletes contact along with its phones, details, group memberships, search index and revisions
+func (t *AddrBookTx) purgeContact(ctx context.Context, id int64) error {
+	_, err := t.tx.NamedStmtContext(ctx, t.r.deletePhonesByContactIdStmt).ExecContext(ctx, map[string]any{
+		"contactId": id,
+	})
+	if err != nil {
+		err = dbErrorf("error deleting phone contacts by contact id=%d: %w", id, err)
+		zap.S().Errorln(err)
+		return err
+	}
+	if err = t.r.deleteContactDetails(ctx, t.tx, id); err != nil {
+		return err
+	}
+	_, err = t.tx.NamedStmtContext(ctx, t.r.deleteGroupMembershipsStmt).ExecContext(ctx, map[string]any{
+		"contactId": id,
+	})
+	if err != nil {
+		err = dbErrorf("error deleting group memberships by contact id=%d: %w", id, err)
+		zap.S().Errorln(err)
+		return err
+	}
+
+	_, err = t.tx.NamedStmtContext(ctx, t.r.deleteContactSearchStmt).ExecContext(ctx, map[string]any{
+		"contactId": id,
+	})
+	if err != nil {
+		err = dbErrorf("error deleting search index of contact id=%d: %w", id, err)
+		zap.S().Errorln(err)
+		return err
+	}
+	_, err = t.tx.NamedStmtContext(ctx, t.r.deleteContactRevisionsStmt).ExecContext(ctx, map[string]any{
+		"contactId": id,
+	})
+	if err != nil {
+		err = dbErrorf("error deleting revisions of contact id=%d: %w", id, err)
+		zap.S().Errorln(err)
+		return err
+	}
+
+	_, err = t.tx.NamedStmtContext(ctx, t.r.deleteContactByIdStmt).ExecContext(ctx, map[string]any{
+		"id": id,
+	})
+	if err != nil {
+		err = dbErrorf("error deleting contact id=%d: %w", id, err)
+		zap.S().Errorln(err)
+		return err
+	}
+	return nil
+}
+
+// SelectContactByID returns contact which is not in trash as it is seen within the transaction
+func (t *AddrBookTx) SelectContactByID(ctx context.Context, ID int64) (*ContactWithPhonesEntity, error) {
+	stmt := t.tx.NamedStmtContext(ctx, t.r.selectContactsWithPhonesByIdStmt)
+	return t.r.selectContactByID(ctx, t.tx, stmt, t.tenantId, ID)
+}
@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+type APIKeyRepo struct {
+	db                           *sqlx.DB
+	dialect                      *dialect
+	insertAPIKeyStmt             *sqlx.NamedStmt
+	selectAllAPIKeysStmt         *sqlx.NamedStmt
+	selectActiveAPIKeyByHashStmt *sqlx.NamedStmt
+	revokeAPIKeyByIdStmt         *sqlx.NamedStmt
+}
+
+func NewAPIKeyRepo(db *sqlx.DB) *APIKeyRepo {
+	d := dialectOf(db)
+	return &APIKeyRepo{
+		db:                           db,
+		dialect:                      d,
+		insertAPIKeyStmt:             MustPrepareNamed(db, d.withReturningId(insertAPIKeySql)),
+		selectAllAPIKeysStmt:         MustPrepareNamed(db, selectAllAPIKeysSql),
+		selectActiveAPIKeyByHashStmt: MustPrepareNamed(db, selectActiveAPIKeyByHashSql),
+		revokeAPIKeyByIdStmt:         MustPrepareNamed(db, revokeAPIKeyByIdSql),
+	}
+}
+
+type APIKeyEntity struct {
+	ID        int64      `db:"id"`
+	Name      string     `db:"name"`
+	TenantID  string     `db:"tenant_id"`
+	Role      string     `db:"role"`
+	Prefix    string     `db:"prefix"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+func (r *APIKeyRepo) AddAPIKey(
+	ctx context.Context,
+	name string,
+	tenantId string,
+	role string,
+	prefix string,
+	keyHash string,
+) (*APIKeyEntity, error) {
+	entity := &APIKeyEntity{
+		Name:      name,
+		TenantID:  tenantId,
+		Role:      role,
+		Prefix:    prefix,
+		CreatedAt: time.Now().UTC(),
+	}
+	var err error
+	entity.ID, err = r.dialect.execInsertReturningId(ctx, r.insertAPIKeyStmt, map[string]any{
+		"name":      name,
+		"tenantId":  tenantId,
+		"role":      role,
+		"prefix":    prefix,
+		"keyHash":   keyHash,
+		"createdAt": entity.CreatedAt,
+	})
+	if err != nil {
+		err = dbErrorf("error inserting API key into database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *APIKeyRepo) SelectAllAPIKeys(ctx context.Context) ([]*APIKeyEntity, error) {
+	var rows []*APIKeyEntity
+	if err := r.selectAllAPIKeysStmt.SelectContext(ctx, &rows, map[string]any{}); err != nil {
+		err = dbErrorf("error selecting all API keys in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *APIKeyRepo) SelectActiveAPIKeyByHash(ctx context.Context, keyHash string) (*APIKeyEntity, error) {
+	entity := &APIKeyEntity{}
+	err := r.selectActiveAPIKeyByHashStmt.GetContext(ctx, entity, map[string]any{
+		"keyHash": keyHash,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		err = dbErrorf("error selecting API key by hash in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return entity, nil
+}
+
+func (r *APIKeyRepo) RevokeAPIKey(ctx context.Context, ID int64) (found bool, err error) {
+	result, err := r.revokeAPIKeyByIdStmt.ExecContext(ctx, map[string]any{
+		"id":        ID,
+		"revokedAt": time.Now().UTC(),
+	})
+	if err != nil {
+		err = dbErrorf("error revoking API key id=%d in database: %w", ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return MustGetRowsAffected(result) > 0, nil
+}
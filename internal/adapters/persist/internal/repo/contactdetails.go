@@ -0,0 +1,198 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+type EmailEntity struct {
+	ContactID int64  `db:"contact_id" json:"-"`
+	Label     string `db:"label" json:"label"`
+	Address   string `db:"address" json:"address"`
+}
+
+type AddressEntity struct {
+	ContactID  int64  `db:"contact_id" json:"-"`
+	Label      string `db:"label" json:"label"`
+	Street     string `db:"street" json:"street"`
+	City       string `db:"city" json:"city"`
+	Region     string `db:"region" json:"region"`
+	PostalCode string `db:"postal_code" json:"postal_code"`
+	Country    string `db:"country" json:"country"`
+}
+
+type URLEntity struct {
+	ContactID int64  `db:"contact_id" json:"-"`
+	Label     string `db:"label" json:"label"`
+	URL       string `db:"url" json:"url"`
+}
+
+type ContactGroupEntity struct {
+	ContactID int64  `db:"contact_id"`
+	ID        int64  `db:"id"`
+	Name      string `db:"name"`
+}
+
+type CustomFieldValueEntity struct {
+	ContactID int64  `db:"contact_id" json:"-"`
+	FieldID   int64  `db:"field_id" json:"-"`
+	Name      string `db:"name" json:"name"`
+	Type      string `db:"type" json:"type"`
+	Value     string `db:"value" json:"value"`
+}
+
+// selectContactDetails loads emails, addresses, URLs, custom field values and groups of the contacts. They are not joined with contacts and
+// phones because every extra one-to-many join multiplies the number of rows, instead every kind of details is
+// selected by a single request for all contacts and merged into contacts by contact id. Details are selected by q,
+// which is either database or transaction.
+func (r *AddrBookRepo) selectContactDetails(ctx context.Context, q sqlx.ExtContext, entities []*ContactWithPhonesEntity) error {
+	if len(entities) == 0 {
+		return nil
+	}
+	byId := make(map[int64]*ContactWithPhonesEntity, len(entities))
+	for _, e := range entities {
+		e.Emails, e.Addresses, e.URLs = []*EmailEntity{}, []*AddressEntity{}, []*URLEntity{}
+		e.CustomFieldValues, e.Groups = []*CustomFieldValueEntity{}, []*ContactGroupEntity{}
+		byId[e.ID] = e
+	}
+	ids := lo.Keys(byId)
+
+	emails, err := selectByContactIds[EmailEntity](ctx, q, selectEmailsByContactIdsSql, ids)
+	if err != nil {
+		return err
+	}
+	for _, email := range emails {
+		c := byId[email.ContactID]
+		c.Emails = append(c.Emails, email)
+	}
+	addresses, err := selectByContactIds[AddressEntity](ctx, q, selectAddressesByContactIdsSql, ids)
+	if err != nil {
+		return err
+	}
+	for _, address := range addresses {
+		c := byId[address.ContactID]
+		c.Addresses = append(c.Addresses, address)
+	}
+	urls, err := selectByContactIds[URLEntity](ctx, q, selectURLsByContactIdsSql, ids)
+	if err != nil {
+		return err
+	}
+	for _, url := range urls {
+		c := byId[url.ContactID]
+		c.URLs = append(c.URLs, url)
+	}
+	values, err := selectByContactIds[CustomFieldValueEntity](ctx, q, selectCustomFieldValuesByContactIdsSql, ids)
+	if err != nil {
+		return err
+	}
+	for _, value := range values {
+		c := byId[value.ContactID]
+		c.CustomFieldValues = append(c.CustomFieldValues, value)
+	}
+	groups, err := selectByContactIds[ContactGroupEntity](ctx, q, selectGroupsByContactIdsSql, ids)
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		c := byId[group.ContactID]
+		c.Groups = append(c.Groups, group)
+	}
+	return nil
+}
+
+// SelectPhoneE164sByContactIds returns distinct E.164 phone numbers of the contacts
+func (r *AddrBookRepo) SelectPhoneE164sByContactIds(ctx context.Context, ids []int64) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	rows, err := selectByContactIds[string](ctx, r.db, selectPhoneE164sByContactIdsSql, ids)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map(rows, func(item *string, _ int) string { return *item }), nil
+}
+
+func selectByContactIds[T any](ctx context.Context, q sqlx.ExtContext, query string, ids []int64) ([]*T, error) {
+	query, args, err := sqlx.In(query, ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []*T
+	if err = sqlx.SelectContext(ctx, q, &rows, q.Rebind(query), args...); err != nil {
+		err = dbErrorf("error selecting contact details in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+// insertContactDetails inserts emails, addresses, URLs and custom field values of the contact within transaction
+func (r *AddrBookRepo) insertContactDetails(ctx context.Context, tx *sqlx.Tx, contactId int64, c *ContactWithPhonesEntity) error {
+	var err error
+	insertEmailStmt := tx.NamedStmtContext(ctx, r.insertEmailStmt)
+	for _, email := range c.Emails {
+		if err == nil {
+			_, err = insertEmailStmt.ExecContext(ctx, map[string]any{
+				"label":     email.Label,
+				"address":   email.Address,
+				"contactId": contactId,
+			})
+		}
+	}
+	insertAddressStmt := tx.NamedStmtContext(ctx, r.insertAddressStmt)
+	for _, address := range c.Addresses {
+		if err == nil {
+			_, err = insertAddressStmt.ExecContext(ctx, map[string]any{
+				"label":      address.Label,
+				"street":     address.Street,
+				"city":       address.City,
+				"region":     address.Region,
+				"postalCode": address.PostalCode,
+				"country":    address.Country,
+				"contactId":  contactId,
+			})
+		}
+	}
+	insertURLStmt := tx.NamedStmtContext(ctx, r.insertURLStmt)
+	for _, url := range c.URLs {
+		if err == nil {
+			_, err = insertURLStmt.ExecContext(ctx, map[string]any{
+				"label":     url.Label,
+				"url":       url.URL,
+				"contactId": contactId,
+			})
+		}
+	}
+	insertCustomFieldValueStmt := tx.NamedStmtContext(ctx, r.insertCustomFieldValueStmt)
+	for _, value := range c.CustomFieldValues {
+		if err == nil {
+			_, err = insertCustomFieldValueStmt.ExecContext(ctx, map[string]any{
+				"fieldId":   value.FieldID,
+				"value":     value.Value,
+				"contactId": contactId,
+			})
+		}
+	}
+	if err != nil {
+		err = dbErrorf("error inserting contact details into database: %w", err)
+		zap.S().Errorln(err)
+	}
+	return err
+}
+
+// deleteContactDetails deletes emails, addresses, URLs and custom field values of the contact within transaction
+func (r *AddrBookRepo) deleteContactDetails(ctx context.Context, tx *sqlx.Tx, contactId int64) error {
+	for _, stmt := range r.deleteContactDetailsStmts {
+		_, err := tx.NamedStmtContext(ctx, stmt).ExecContext(ctx, map[string]any{
+			"contactId": contactId,
+		})
+		if err != nil {
+			err = dbErrorf("error deleting contact details by contact id=%d: %w", contactId, err)
+			zap.S().Errorln(err)
+			return err
+		}
+	}
+	return nil
+}
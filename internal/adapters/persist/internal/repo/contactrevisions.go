@@ -0,0 +1,252 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Actions recorded in contact revisions
+const (
+	RevisionActionCreated  = "created"
+	RevisionActionUpdated  = "updated"
+	RevisionActionDeleted  = "deleted"
+	RevisionActionRestored = "restored"
+	RevisionActionReverted = "reverted"
+)
+
+type ContactRevisionEntity struct {
+	Revision  int       `db:"revision"`
+	Action    string    `db:"action"`
+	Actor     string    `db:"actor"`
+	ChangedAt time.Time `db:"changed_at"`
+	Snapshot  string    `db:"snapshot"`
+	// RevertedTo is a number of the revision the contact was reverted to, nil for other actions
+	RevertedTo *int `db:"reverted_to"`
+	// Contact is decoded from Snapshot, it has no id and groups
+	Contact *ContactWithPhonesEntity `db:"-"`
+}
+
+// insertRevision records revision of the contact which was just changed, it must be called within the same
+// transaction that changes the contact, so that the snapshot matches the change. revertedTo is a number of
+// the reverted revision for RevisionActionReverted and 0 for other actions.
+func (r *AddrBookRepo) insertRevision(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	contactId int64,
+	action string,
+	actor string,
+	changedAt time.Time,
+	revertedTo int,
+) error {
+	var rows []*contactWithPhoneRow
+	err := tx.NamedStmtContext(ctx, r.selectContactSnapshotStmt).SelectContext(ctx, &rows, map[string]any{
+		"contactId": contactId,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting snapshot of contact id=%d: %w", contactId, err)
+		zap.S().Errorln(err)
+		return err
+	}
+	entities := mergeContactWithPhoneRows(rows)
+	if len(entities) == 0 {
+		return fmt.Errorf("error selecting snapshot of contact id=%d: contact not found", contactId)
+	}
+	if err = r.selectContactDetails(ctx, tx, entities); err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(entities[0])
+	if err != nil {
+		return fmt.Errorf("error encoding snapshot of contact id=%d: %w", contactId, err)
+	}
+	_, err = tx.NamedStmtContext(ctx, r.insertContactRevisionStmt).ExecContext(ctx, map[string]any{
+		"contactId":  contactId,
+		"action":     action,
+		"actor":      actor,
+		"changedAt":  changedAt,
+		"snapshot":   string(snapshot),
+		"revertedTo": nullIfZero(revertedTo),
+	})
+	if err != nil {
+		err = dbErrorf("error inserting revision of contact id=%d: %w", contactId, err)
+		zap.S().Errorln(err)
+	}
+	return err
+}
+
+// contactWithoutRevisionsEntity is a contact saved before revisions were recorded
+type contactWithoutRevisionsEntity struct {
+	ID        int64      `db:"id"`
+	DeletedAt *time.Time `db:"deleted_at"`
+}
+
+// InsertMissingRevisions records the first revision of every contact saved before revisions were recorded, so that
+// its history and its state as of the current time are known. Contact in trash gets deleted revision made when it
+// was moved to trash, other contacts get created revision made now. Every contact is recorded in a transaction of its
+// own, contact recorded by another service instance in the meantime is skipped. It returns number of recorded
+// revisions.
+func (r *AddrBookRepo) InsertMissingRevisions(ctx context.Context, actor string) (recorded int, err error) {
+	var contacts []*contactWithoutRevisionsEntity
+	if err = r.db.SelectContext(ctx, &contacts, selectContactsWithoutRevisionsSql); err != nil {
+		err = dbErrorf("error selecting contacts without revisions: %w", err)
+		zap.S().Errorln(err)
+		return 0, err
+	}
+	for _, c := range contacts {
+		action, changedAt := RevisionActionCreated, time.Now().UTC()
+		if c.DeletedAt != nil {
+			action, changedAt = RevisionActionDeleted, *c.DeletedAt
+		}
+		inserted, err := r.insertFirstRevision(ctx, c.ID, action, actor, changedAt)
+		if err != nil {
+			return recorded, err
+		}
+		if inserted {
+			recorded++
+		}
+	}
+	return recorded, nil
+}
+
+// insertFirstRevision records revision of the contact unless it has revisions already
+func (r *AddrBookRepo) insertFirstRevision(
+	ctx context.Context,
+	contactId int64,
+	action string,
+	actor string,
+	changedAt time.Time,
+) (inserted bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	var count int
+	err = tx.NamedStmtContext(ctx, r.countContactRevisionsStmt).GetContext(ctx, &count, map[string]any{
+		"contactId": contactId,
+	})
+	if err != nil {
+		err = dbErrorf("error counting revisions of contact id=%d: %w", contactId, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if err = r.insertRevision(ctx, tx, contactId, action, actor, changedAt, 0); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// SelectContactRevisions returns up to limit revisions of the contact of the tenant, the most recent go first.
+// Contact in trash has revisions too, found is false if there is no such contact.
+func (r *AddrBookRepo) SelectContactRevisions(
+	ctx context.Context,
+	tenantId string,
+	contactId int64,
+	limit int,
+) (revisions []*ContactRevisionEntity, found bool, err error) {
+	var count int
+	err = r.countContactsOfTenantByIdStmt.GetContext(ctx, &count, map[string]any{
+		"id":       contactId,
+		"tenantId": tenantId,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting contact id=%d in database: %w", contactId, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	if count == 0 {
+		return nil, false, nil
+	}
+	err = r.selectContactRevisionsStmt.SelectContext(ctx, &revisions, map[string]any{
+		"contactId": contactId,
+		"tenantId":  tenantId,
+		"limit":     limit,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting revisions of contact id=%d in database: %w", contactId, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	for _, rev := range revisions {
+		if err = rev.decodeSnapshot(contactId); err != nil {
+			return nil, false, err
+		}
+	}
+	return revisions, true, nil
+}
+
+// SelectContactRevisionAsOf returns the last revision of the contact of the tenant made at or before asOf,
+// nil is returned if there is no such revision
+func (r *AddrBookRepo) SelectContactRevisionAsOf(
+	ctx context.Context,
+	tenantId string,
+	contactId int64,
+	asOf time.Time,
+) (*ContactRevisionEntity, error) {
+	var revisions []*ContactRevisionEntity
+	err := r.selectContactRevisionAsOfStmt.SelectContext(ctx, &revisions, map[string]any{
+		"contactId": contactId,
+		"tenantId":  tenantId,
+		"asOf":      asOf.UTC(),
+	})
+	if err != nil {
+		err = dbErrorf("error selecting revision of contact id=%d in database: %w", contactId, err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	if err = revisions[0].decodeSnapshot(contactId); err != nil {
+		return nil, err
+	}
+	return revisions[0], nil
+}
+
+// SelectContactRevision returns revision of the contact of the tenant by its number, nil is returned if there is
+// no such revision
+func (r *AddrBookRepo) SelectContactRevision(
+	ctx context.Context,
+	tenantId string,
+	contactId int64,
+	revision int,
+) (*ContactRevisionEntity, error) {
+	var revisions []*ContactRevisionEntity
+	err := r.selectContactRevisionStmt.SelectContext(ctx, &revisions, map[string]any{
+		"contactId": contactId,
+		"tenantId":  tenantId,
+		"revision":  revision,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting revision=%d of contact id=%d in database: %w", revision, contactId, err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	if len(revisions) == 0 {
+		return nil, nil
+	}
+	if err = revisions[0].decodeSnapshot(contactId); err != nil {
+		return nil, err
+	}
+	return revisions[0], nil
+}
+
+func (e *ContactRevisionEntity) decodeSnapshot(contactId int64) error {
+	e.Contact = &ContactWithPhonesEntity{}
+	if err := json.Unmarshal([]byte(e.Snapshot), e.Contact); err != nil {
+		err = dbErrorf("error decoding snapshot of contact id=%d revision=%d: %w", contactId, e.Revision, err)
+		zap.S().Errorln(err)
+		return err
+	}
+	e.Contact.ID = contactId
+	return nil
+}
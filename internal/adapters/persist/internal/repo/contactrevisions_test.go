@@ -0,0 +1,192 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jmoiron/sqlx"
+	"testing"
+	"time"
+)
+
+// addTestContactWithDetails adds contact with every kind of details and values of the custom fields
+func addTestContactWithDetails(t *testing.T, r *AddrBookRepo, fields ...*CustomFieldEntity) *ContactWithPhonesEntity {
+	t.Helper()
+	c := &ContactWithPhonesEntity{
+		FirstName: "John",
+		LastName:  "Doe",
+		Phones:    []*PhoneEntity{{PhoneType: "mobile", PhoneNumber: "503-555-7777", PhoneE164: "+15035557777"}},
+		Emails:    []*EmailEntity{{Label: "work", Address: "john@example.com"}},
+		Addresses: []*AddressEntity{{Label: "home", Street: "1 Main St", City: "Portland", Region: "OR", PostalCode: "97201", Country: "US"}},
+		URLs:      []*URLEntity{{Label: "blog", URL: "https://john.example.com"}},
+	}
+	for i, f := range fields {
+		c.CustomFieldValues = append(c.CustomFieldValues, &CustomFieldValueEntity{FieldID: f.ID, Value: []string{"gold", "42"}[i%2]})
+	}
+	newc, err := r.AddContact(context.Background(), testTenant, testActor, c)
+	if err != nil {
+		t.Fatalf("error adding contact: %v", err)
+	}
+	return newc
+}
+
+func addTestCustomField(t *testing.T, db *sqlx.DB, name string, fieldType string) *CustomFieldEntity {
+	t.Helper()
+	f, err := NewCustomFieldRepo(db).AddCustomField(context.Background(), testTenant, &CustomFieldEntity{Name: name, Type: fieldType})
+	if err != nil {
+		t.Fatalf("error adding custom field %s: %v", name, err)
+	}
+	return f
+}
+
+func TestContactSnapshotRoundTrip(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		tier := addTestCustomField(t, db, "tier", "string")
+		score := addTestCustomField(t, db, "score", "number")
+		created := addTestContactWithDetails(t, r, tier, score)
+		saved, err := r.SelectContactByID(ctx, testTenant, created.ID)
+		if err != nil || saved == nil {
+			t.Fatalf("contact id=%d is not selected: %v", created.ID, err)
+		}
+
+		rev, err := r.SelectContactRevision(ctx, testTenant, created.ID, 1)
+		if err != nil || rev == nil {
+			t.Fatalf("revision 1 of contact id=%d is not selected: %v", created.ID, err)
+		}
+		if rev.Action != RevisionActionCreated || rev.Actor != testActor || rev.Contact.ID != created.ID {
+			t.Errorf("unexpected revision: %+v", rev)
+		}
+		want, _ := json.Marshal(saved)
+		got, _ := json.Marshal(rev.Contact)
+		if string(got) != string(want) {
+			t.Errorf("snapshot does not match saved contact\n got: %s\nwant: %s", got, want)
+		}
+		// custom field values are saved with name and type of their fields, but without field id
+		for _, v := range rev.Contact.CustomFieldValues {
+			if v.FieldID != 0 || v.Name == "" || v.Type == "" {
+				t.Errorf("unexpected custom field value in snapshot: %+v", v)
+			}
+		}
+	})
+}
+
+func TestRevertContactAfterCustomFieldIsDeleted(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		fields := NewCustomFieldRepo(db)
+		tier := addTestCustomField(t, db, "tier", "string")
+		score := addTestCustomField(t, db, "score", "number")
+		created := addTestContactWithDetails(t, r, tier, score)
+
+		changed := &ContactWithPhonesEntity{
+			ID:                created.ID,
+			FirstName:         "Johnny",
+			LastName:          "Doe",
+			CustomFieldValues: []*CustomFieldValueEntity{{FieldID: tier.ID, Value: "silver"}},
+		}
+		if found, err := r.UpdateContact(ctx, testTenant, testActor, changed); err != nil || !found {
+			t.Fatalf("contact id=%d is not updated: %v", created.ID, err)
+		}
+		if _, found, err := fields.DeleteCustomField(ctx, testTenant, score.ID); err != nil || !found {
+			t.Fatalf("custom field id=%d is not deleted: %v", score.ID, err)
+		}
+
+		rev, err := r.SelectContactRevision(ctx, testTenant, created.ID, 1)
+		if err != nil || rev == nil {
+			t.Fatalf("revision 1 of contact id=%d is not selected: %v", created.ID, err)
+		}
+		if len(rev.Contact.CustomFieldValues) != 2 {
+			t.Fatalf("snapshot lost values of deleted custom field: %+v", rev.Contact.CustomFieldValues)
+		}
+		// values of deleted fields are dropped and the rest are matched to fields by name, as use case does
+		defined, err := fields.SelectCustomFields(ctx, testTenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reverted := rev.Contact
+		var values []*CustomFieldValueEntity
+		for _, v := range reverted.CustomFieldValues {
+			for _, f := range defined {
+				if f.Name == v.Name {
+					values = append(values, &CustomFieldValueEntity{FieldID: f.ID, Value: v.Value})
+				}
+			}
+		}
+		reverted.CustomFieldValues = values
+		if found, err := r.RevertContact(ctx, testTenant, testActor, reverted, rev.Revision); err != nil || !found {
+			t.Fatalf("contact id=%d is not reverted: %v", created.ID, err)
+		}
+
+		saved, err := r.SelectContactByID(ctx, testTenant, created.ID)
+		if err != nil || saved == nil {
+			t.Fatalf("contact id=%d is not selected: %v", created.ID, err)
+		}
+		if saved.FirstName != "John" || len(saved.Emails) != 1 || len(saved.Addresses) != 1 || len(saved.URLs) != 1 {
+			t.Errorf("contact is not reverted: %+v", saved)
+		}
+		if len(saved.CustomFieldValues) != 1 || saved.CustomFieldValues[0].Name != "tier" || saved.CustomFieldValues[0].Value != "gold" {
+			t.Errorf("unexpected custom field values of reverted contact: %+v", saved.CustomFieldValues)
+		}
+		revisions, _, err := r.SelectContactRevisions(ctx, testTenant, created.ID, 10)
+		if err != nil || len(revisions) != 3 {
+			t.Fatalf("unexpected revisions %+v, error %v", revisions, err)
+		}
+		if last := revisions[0]; last.Action != RevisionActionReverted || last.RevertedTo == nil || *last.RevertedTo != 1 {
+			t.Errorf("unexpected last revision: %+v", last)
+		}
+	})
+}
+
+func TestInsertMissingRevisions(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		john := addTestContact(t, r, testTenant, "John", "Doe", "503-555-7777")
+		jane := addTestContact(t, r, testTenant, "Jane", "Doe")
+		recent := addTestContact(t, r, testTenant, "Ann", "Lee")
+		if found, err := r.DeleteContact(ctx, testTenant, testActor, jane.ID, 0); err != nil || !found {
+			t.Fatalf("contact is not deleted: %v", err)
+		}
+		// contacts saved before revisions were recorded have none
+		for _, id := range []int64{john.ID, jane.ID} {
+			if _, err := db.NamedExec(deleteContactRevisionsByContactIdSql, map[string]any{"contactId": id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if recorded, err := r.InsertMissingRevisions(ctx, "system"); err != nil || recorded != 2 {
+			t.Fatalf("%d revisions are recorded, error %v, want 2", recorded, err)
+		}
+		if recorded, err := r.InsertMissingRevisions(ctx, "system"); err != nil || recorded != 0 {
+			t.Errorf("%d revisions are recorded again, error %v", recorded, err)
+		}
+		revisionsOf := func(id int64) []*ContactRevisionEntity {
+			t.Helper()
+			revs, found, err := r.SelectContactRevisions(ctx, testTenant, id, 10)
+			if err != nil || !found {
+				t.Fatalf("revisions of contact id=%d are not selected: %v", id, err)
+			}
+			return revs
+		}
+		if revs := revisionsOf(john.ID); len(revs) != 1 || revs[0].Revision != 1 || revs[0].Action != RevisionActionCreated ||
+			revs[0].Actor != "system" || revs[0].Contact.FirstName != "John" || len(revs[0].Contact.Phones) != 1 {
+			t.Errorf("unexpected revisions of contact without revisions: %+v", revs)
+		}
+		if rev, err := r.SelectContactRevisionAsOf(ctx, testTenant, john.ID, time.Now().UTC().Add(time.Second)); err != nil || rev == nil {
+			t.Errorf("contact without revisions is not found as of now: %v", err)
+		}
+		trashed, err := r.SelectTrashedContacts(ctx, testTenant, 10)
+		if err != nil || len(trashed) != 1 {
+			t.Fatalf("trash is not selected: %v", err)
+		}
+		if revs := revisionsOf(jane.ID); len(revs) != 1 || revs[0].Action != RevisionActionDeleted ||
+			!revs[0].ChangedAt.Equal(*trashed[0].DeletedAt) {
+			t.Errorf("unexpected revisions of contact in trash without revisions: %+v", revs)
+		}
+		if revs := revisionsOf(recent.ID); len(revs) != 1 || revs[0].Actor != testActor {
+			t.Errorf("revisions of contact with revisions are changed: %+v", revs)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package repo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+type CustomFieldRepo struct {
+	db                                 *sqlx.DB
+	dialect                            *dialect
+	selectCustomFieldsStmt             *sqlx.NamedStmt
+	insertCustomFieldStmt              *sqlx.NamedStmt
+	selectContactIdsByFieldStmt        *sqlx.NamedStmt
+	bumpContactVersionsByFieldStmt     *sqlx.NamedStmt
+	deleteCustomFieldValuesByFieldStmt *sqlx.NamedStmt
+	deleteCustomFieldByIdStmt          *sqlx.NamedStmt
+}
+
+func NewCustomFieldRepo(db *sqlx.DB) *CustomFieldRepo {
+	d := dialectOf(db)
+	return &CustomFieldRepo{
+		db:                                 db,
+		dialect:                            d,
+		selectCustomFieldsStmt:             MustPrepareNamed(db, selectCustomFieldsSql),
+		insertCustomFieldStmt:              MustPrepareNamed(db, d.withReturningId(insertCustomFieldSql)),
+		selectContactIdsByFieldStmt:        MustPrepareNamed(db, selectContactIdsByCustomFieldIdSql),
+		bumpContactVersionsByFieldStmt:     MustPrepareNamed(db, bumpContactVersionsByCustomFieldIdSql),
+		deleteCustomFieldValuesByFieldStmt: MustPrepareNamed(db, deleteCustomFieldValuesByFieldIdSql),
+		deleteCustomFieldByIdStmt:          MustPrepareNamed(db, deleteCustomFieldByIdSql),
+	}
+}
+
+type CustomFieldEntity struct {
+	ID         int64    `db:"id"`
+	Name       string   `db:"name"`
+	Type       string   `db:"type"`
+	Required   bool     `db:"required"`
+	EnumValues jsonList `db:"enum_values"`
+}
+
+// jsonList is a list of strings stored as JSON array in a text column
+type jsonList []string
+
+func (l *jsonList) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported JSON list column type %T", src)
+	}
+	return json.Unmarshal(data, (*[]string)(l))
+}
+
+func (l jsonList) text() string {
+	if l == nil {
+		l = jsonList{}
+	}
+	data, _ := json.Marshal([]string(l))
+	return string(data)
+}
+
+// SelectCustomFields returns custom field definitions of the tenant in order of their creation
+func (r *CustomFieldRepo) SelectCustomFields(ctx context.Context, tenantId string) ([]*CustomFieldEntity, error) {
+	var rows []*CustomFieldEntity
+	err := r.selectCustomFieldsStmt.SelectContext(ctx, &rows, map[string]any{
+		"tenantId": tenantId,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting custom fields in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *CustomFieldRepo) AddCustomField(ctx context.Context, tenantId string, f *CustomFieldEntity) (*CustomFieldEntity, error) {
+	newf := *f
+	var err error
+	newf.ID, err = r.dialect.execInsertReturningId(ctx, r.insertCustomFieldStmt, map[string]any{
+		"tenantId":   tenantId,
+		"name":       f.Name,
+		"type":       f.Type,
+		"required":   f.Required,
+		"enumValues": f.EnumValues.text(),
+	})
+	if err != nil {
+		err = dbErrorf("error inserting custom field into database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return &newf, nil
+}
+
+// DeleteCustomField deletes custom field of the tenant along with its values, contacts which had a value of the field
+// get new version and their ids are returned, so that cached copies of them could be forgotten
+func (r *CustomFieldRepo) DeleteCustomField(
+	ctx context.Context,
+	tenantId string,
+	ID int64,
+) (contactIds []int64, found bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	args := map[string]any{
+		"id":       ID,
+		"tenantId": tenantId,
+	}
+	err = tx.NamedStmtContext(ctx, r.selectContactIdsByFieldStmt).SelectContext(ctx, &contactIds, args)
+	if err != nil {
+		err = dbErrorf("error selecting contacts having value of custom field id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	_, err = tx.NamedStmtContext(ctx, r.bumpContactVersionsByFieldStmt).ExecContext(ctx, args)
+	if err != nil {
+		err = dbErrorf("error changing versions of contacts having value of custom field id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	_, err = tx.NamedStmtContext(ctx, r.deleteCustomFieldValuesByFieldStmt).ExecContext(ctx, args)
+	if err != nil {
+		err = dbErrorf("error deleting values of custom field id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	result, err := tx.NamedStmtContext(ctx, r.deleteCustomFieldByIdStmt).ExecContext(ctx, args)
+	if err != nil {
+		err = dbErrorf("error deleting custom field id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	if MustGetRowsAffected(result) == 0 {
+		return nil, false, nil
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	return contactIds, true, nil
+}
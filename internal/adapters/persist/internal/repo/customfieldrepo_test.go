@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"reflect"
+	"testing"
+)
+
+func TestDeleteCustomFieldChangesContactVersions(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		fields := NewCustomFieldRepo(db)
+		tier := addTestCustomField(t, db, "tier", "string")
+		john := addTestContactWithDetails(t, r, tier)
+		jane := addTestContact(t, r, testTenant, "Jane", "Doe")
+		versionOf := func(ID int64) int {
+			t.Helper()
+			c, err := r.SelectContactByID(ctx, testTenant, ID)
+			if err != nil || c == nil {
+				t.Fatalf("contact id=%d is not selected: %v", ID, err)
+			}
+			return c.Version
+		}
+
+		contactIds, found, err := fields.DeleteCustomField(ctx, "tenant-b", tier.ID)
+		if err != nil || found || len(contactIds) > 0 {
+			t.Fatalf("custom field is deleted by another tenant: contacts=%v, found=%v, error %v", contactIds, found, err)
+		}
+		if version := versionOf(john.ID); version != 1 {
+			t.Errorf("version of contact is changed by another tenant to %d, want 1", version)
+		}
+
+		contactIds, found, err = fields.DeleteCustomField(ctx, testTenant, tier.ID)
+		if err != nil || !found {
+			t.Fatalf("custom field is not deleted: %v", err)
+		}
+		if want := []int64{john.ID}; !reflect.DeepEqual(contactIds, want) {
+			t.Errorf("contacts having value of deleted field are %v, want %v", contactIds, want)
+		}
+		if version := versionOf(john.ID); version != 2 {
+			t.Errorf("version of contact having value of deleted field is %d, want 2", version)
+		}
+		if version := versionOf(jane.ID); version != 1 {
+			t.Errorf("version of contact without value of deleted field is %d, want 1", version)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// dialect contains SQL requests and helpers that differ between supported databases
+type dialect struct {
+	// returningIdClause is appended to insert requests, so that execInsertReturningId can get ID of inserted row
+	returningIdClause           string
+	indexContactSearchSql       string
+	deleteContactSearchSql      string
+	searchContactsWithPhonesSql string
+	// buildSearchMatchQuery converts search terms into full-text query expression, every term is matched as a prefix
+	buildSearchMatchQuery func(terms []string) string
+	// execInsertReturningId executes insert statement and returns ID of inserted row
+	execInsertReturningId func(ctx context.Context, stmt *sqlx.NamedStmt, arg any) (int64, error)
+}
+
+// dialectOf returns dialect by database driver name
+func dialectOf(db *sqlx.DB) *dialect {
+	switch db.DriverName() {
+	case "sqlite3":
+		return sqliteDialect
+	case "pgx":
+		return postgresDialect
+	default:
+		panic(fmt.Sprintf("unsupported database driver: %s", db.DriverName()))
+	}
+}
+
+// withReturningId makes insert request return ID of inserted row
+func (d *dialect) withReturningId(insertSql string) string {
+	return insertSql + d.returningIdClause
+}
@@ -0,0 +1,271 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+type GroupRepo struct {
+	db                              *sqlx.DB
+	dialect                         *dialect
+	selectGroupsStmt                *sqlx.NamedStmt
+	insertGroupStmt                 *sqlx.NamedStmt
+	updateGroupByIdStmt             *sqlx.NamedStmt
+	countGroupsByIdStmt             *sqlx.NamedStmt
+	bumpMemberVersionsStmt          *sqlx.NamedStmt
+	deleteGroupMembersByGroupIdStmt *sqlx.NamedStmt
+	deleteGroupByIdStmt             *sqlx.NamedStmt
+	selectGroupMemberIdsStmt        *sqlx.NamedStmt
+	insertGroupMemberStmt           *sqlx.NamedStmt
+	deleteGroupMemberStmt           *sqlx.NamedStmt
+	bumpContactVersionStmt          *sqlx.NamedStmt
+}
+
+func NewGroupRepo(db *sqlx.DB) *GroupRepo {
+	d := dialectOf(db)
+	return &GroupRepo{
+		db:                              db,
+		dialect:                         d,
+		selectGroupsStmt:                MustPrepareNamed(db, selectGroupsSql),
+		insertGroupStmt:                 MustPrepareNamed(db, d.withReturningId(insertGroupSql)),
+		updateGroupByIdStmt:             MustPrepareNamed(db, updateGroupByIdSql),
+		countGroupsByIdStmt:             MustPrepareNamed(db, countGroupsByIdSql),
+		bumpMemberVersionsStmt:          MustPrepareNamed(db, bumpContactVersionsByGroupIdSql),
+		deleteGroupMembersByGroupIdStmt: MustPrepareNamed(db, deleteGroupMembersByGroupIdSql),
+		deleteGroupByIdStmt:             MustPrepareNamed(db, deleteGroupByIdSql),
+		selectGroupMemberIdsStmt:        MustPrepareNamed(db, selectGroupMemberIdsSql),
+		insertGroupMemberStmt:           MustPrepareNamed(db, insertGroupMemberSql),
+		deleteGroupMemberStmt:           MustPrepareNamed(db, deleteGroupMemberSql),
+		bumpContactVersionStmt:          MustPrepareNamed(db, bumpContactVersionSql),
+	}
+}
+
+type GroupEntity struct {
+	ID          int64  `db:"id"`
+	Name        string `db:"name"`
+	MemberCount int    `db:"member_count"`
+}
+
+// SelectGroups returns groups of the tenant ordered by name
+func (r *GroupRepo) SelectGroups(ctx context.Context, tenantId string) ([]*GroupEntity, error) {
+	return r.selectGroups(ctx, tenantId, 0)
+}
+
+func (r *GroupRepo) SelectGroupByID(ctx context.Context, tenantId string, ID int64) (*GroupEntity, error) {
+	groups, err := r.selectGroups(ctx, tenantId, ID)
+	if err != nil || len(groups) == 0 {
+		return nil, err
+	}
+	return groups[0], nil
+}
+
+// selectGroups returns all groups of the tenant if ID is zero, or the group with ID otherwise
+func (r *GroupRepo) selectGroups(ctx context.Context, tenantId string, ID int64) ([]*GroupEntity, error) {
+	var rows []*GroupEntity
+	err := r.selectGroupsStmt.SelectContext(ctx, &rows, map[string]any{
+		"tenantId": tenantId,
+		"id":       ID,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting groups in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (r *GroupRepo) AddGroup(ctx context.Context, tenantId string, name string) (*GroupEntity, error) {
+	ID, err := r.dialect.execInsertReturningId(ctx, r.insertGroupStmt, map[string]any{
+		"tenantId": tenantId,
+		"name":     name,
+	})
+	if err != nil {
+		err = dbErrorf("error inserting group into database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return &GroupEntity{ID: ID, Name: name}, nil
+}
+
+// UpdateGroup renames group of the tenant, versions of its members are changed as they refer to the group by name
+func (r *GroupRepo) UpdateGroup(ctx context.Context, tenantId string, ID int64, name string) (found bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	args := map[string]any{
+		"id":       ID,
+		"tenantId": tenantId,
+		"name":     name,
+	}
+	result, err := tx.NamedStmtContext(ctx, r.updateGroupByIdStmt).ExecContext(ctx, args)
+	if err != nil {
+		err = dbErrorf("error updating group id=%d in database: %w", ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if MustGetRowsAffected(result) == 0 {
+		return false, nil
+	}
+	if _, err = tx.NamedStmtContext(ctx, r.bumpMemberVersionsStmt).ExecContext(ctx, args); err != nil {
+		err = dbErrorf("error changing versions of group id=%d members: %w", ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteGroup deletes group of the tenant along with memberships of its contacts, versions of the contacts are changed
+func (r *GroupRepo) DeleteGroup(ctx context.Context, tenantId string, ID int64) (found bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	if found, err = r.groupExists(ctx, tx, tenantId, ID); err != nil || !found {
+		return false, err
+	}
+	args := map[string]any{
+		"id":       ID,
+		"tenantId": tenantId,
+	}
+	_, err = tx.NamedStmtContext(ctx, r.bumpMemberVersionsStmt).ExecContext(ctx, args)
+	if err == nil {
+		_, err = tx.NamedStmtContext(ctx, r.deleteGroupMembersByGroupIdStmt).ExecContext(ctx, args)
+	}
+	if err == nil {
+		_, err = tx.NamedStmtContext(ctx, r.deleteGroupByIdStmt).ExecContext(ctx, args)
+	}
+	if err != nil {
+		err = dbErrorf("error deleting group id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// SelectGroupMemberIds returns ids of the group member contacts, group must belong to the caller tenant
+func (r *GroupRepo) SelectGroupMemberIds(ctx context.Context, ID int64) ([]int64, error) {
+	var ids []int64
+	err := r.selectGroupMemberIdsStmt.SelectContext(ctx, &ids, map[string]any{
+		"id": ID,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting group members in database: %w", err)
+		zap.S().Errorln(err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+// AddGroupMembers adds contacts of the tenant to the group. If some contacts do not exist, then nothing
+// is added and ids of missing contacts are returned.
+func (r *GroupRepo) AddGroupMembers(
+	ctx context.Context,
+	tenantId string,
+	ID int64,
+	contactIds []int64,
+) (missing []int64, found bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	if found, err = r.groupExists(ctx, tx, tenantId, ID); err != nil || !found {
+		return nil, false, err
+	}
+	query, args, err := sqlx.In(selectContactIdsOfTenantSql, tenantId, contactIds)
+	if err != nil {
+		return nil, false, err
+	}
+	var existing []int64
+	if err = tx.SelectContext(ctx, &existing, tx.Rebind(query), args...); err != nil {
+		err = dbErrorf("error selecting contacts to add to group id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	if missing, _ = lo.Difference(contactIds, existing); len(missing) > 0 {
+		return missing, true, nil
+	}
+	if err = r.execForMembers(ctx, tx, r.insertGroupMemberStmt, tenantId, ID, contactIds); err != nil {
+		return nil, false, err
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return nil, false, err
+	}
+	return nil, true, nil
+}
+
+// RemoveGroupMembers removes contacts from the group of the tenant
+func (r *GroupRepo) RemoveGroupMembers(ctx context.Context, tenantId string, ID int64, contactIds []int64) (found bool, err error) {
+	tx := r.db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	if found, err = r.groupExists(ctx, tx, tenantId, ID); err != nil || !found {
+		return false, err
+	}
+	if err = r.execForMembers(ctx, tx, r.deleteGroupMemberStmt, tenantId, ID, contactIds); err != nil {
+		return false, err
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return true, nil
+}
+
+// execForMembers executes membership statement for every contact within transaction and changes version of
+// the contact of the tenant if its membership has actually changed
+func (r *GroupRepo) execForMembers(
+	ctx context.Context,
+	tx *sqlx.Tx,
+	stmt *sqlx.NamedStmt,
+	tenantId string,
+	ID int64,
+	contactIds []int64,
+) error {
+	txStmt := tx.NamedStmtContext(ctx, stmt)
+	bumpStmt := tx.NamedStmtContext(ctx, r.bumpContactVersionStmt)
+	for _, contactId := range contactIds {
+		args := map[string]any{
+			"groupId":   ID,
+			"contactId": contactId,
+			"tenantId":  tenantId,
+		}
+		result, err := txStmt.ExecContext(ctx, args)
+		if err == nil && MustGetRowsAffected(result) > 0 {
+			_, err = bumpStmt.ExecContext(ctx, args)
+		}
+		if err != nil {
+			err = dbErrorf("error changing members of group id=%d: %w", ID, err)
+			zap.S().Errorln(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// groupExists checks that group belongs to the tenant, memberships are keyed by group id only
+func (r *GroupRepo) groupExists(ctx context.Context, tx *sqlx.Tx, tenantId string, ID int64) (bool, error) {
+	var count int
+	err := tx.NamedStmtContext(ctx, r.countGroupsByIdStmt).GetContext(ctx, &count, map[string]any{
+		"id":       ID,
+		"tenantId": tenantId,
+	})
+	if err != nil {
+		err = dbErrorf("error selecting group id=%d: %w", ID, err)
+		zap.S().Errorln(err)
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,154 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"testing"
+)
+
+func TestGroupMembersChangeContactVersion(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		groups := NewGroupRepo(db)
+		john := addTestContact(t, r, testTenant, "John", "Doe")
+		other := addTestContact(t, r, "tenant-b", "Jane", "Other")
+		group, err := groups.AddGroup(ctx, testTenant, "Friends")
+		if err != nil {
+			t.Fatal(err)
+		}
+		versionOf := func(tenantId string, ID int64) int {
+			t.Helper()
+			c, err := r.SelectContactByID(ctx, tenantId, ID)
+			if err != nil || c == nil {
+				t.Fatalf("contact id=%d is not selected: %v", ID, err)
+			}
+			return c.Version
+		}
+
+		for i := 0; i < 2; i++ {
+			if missing, found, err := groups.AddGroupMembers(ctx, testTenant, group.ID, []int64{john.ID}); err != nil || !found || len(missing) > 0 {
+				t.Fatalf("contact is not added to group: missing=%v, found=%v, error %v", missing, found, err)
+			}
+		}
+		if version := versionOf(testTenant, john.ID); version != 2 {
+			t.Errorf("version of contact added to group twice is %d, want 2", version)
+		}
+
+		// contact of another tenant is never a member, so it must not be changed
+		if found, err := groups.RemoveGroupMembers(ctx, testTenant, group.ID, []int64{john.ID, other.ID}); err != nil || !found {
+			t.Fatalf("members are not removed from group: %v", err)
+		}
+		if version := versionOf(testTenant, john.ID); version != 3 {
+			t.Errorf("version of contact removed from group is %d, want 3", version)
+		}
+		if version := versionOf("tenant-b", other.ID); version != 1 {
+			t.Errorf("version of contact of another tenant is %d, want 1", version)
+		}
+		if found, err := groups.RemoveGroupMembers(ctx, testTenant, group.ID, []int64{john.ID}); err != nil || !found {
+			t.Fatalf("members are not removed from group: %v", err)
+		}
+		if version := versionOf(testTenant, john.ID); version != 3 {
+			t.Errorf("version of contact which is not a member is changed to %d, want 3", version)
+		}
+	})
+}
+
+func TestRenamedGroupChangesVersionsOfActiveMembers(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		groups := NewGroupRepo(db)
+		john := addTestContact(t, r, testTenant, "John", "Doe")
+		jane := addTestContact(t, r, testTenant, "Jane", "Doe")
+		group, err := groups.AddGroup(ctx, testTenant, "Friends")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, found, err := groups.AddGroupMembers(ctx, testTenant, group.ID, []int64{john.ID, jane.ID}); err != nil || !found {
+			t.Fatalf("contacts are not added to group: %v", err)
+		}
+		// version 3 of the trashed member must not be changed by renaming of its group
+		if found, err := r.DeleteContact(ctx, testTenant, testActor, jane.ID, 0); err != nil || !found {
+			t.Fatalf("contact is not deleted: %v", err)
+		}
+
+		if found, err := groups.UpdateGroup(ctx, "tenant-b", group.ID, "Enemies"); err != nil || found {
+			t.Fatalf("group is renamed by another tenant: found=%v, error %v", found, err)
+		}
+		if found, err := groups.UpdateGroup(ctx, testTenant, group.ID, "Best friends"); err != nil || !found {
+			t.Fatalf("group is not renamed: %v", err)
+		}
+		if c, err := r.SelectContactByID(ctx, testTenant, john.ID); err != nil || c == nil || c.Version != 3 {
+			t.Errorf("member of renamed group is selected as %+v, error %v, want version 3", c, err)
+		}
+		if found, err := r.RestoreContact(ctx, testTenant, testActor, jane.ID); err != nil || !found {
+			t.Fatalf("contact is not restored: %v", err)
+		}
+		if c, err := r.SelectContactByID(ctx, testTenant, jane.ID); err != nil || c == nil || c.Version != 4 {
+			t.Errorf("restored member of renamed group is selected as %+v, error %v, want version 4", c, err)
+		}
+	})
+}
+
+func TestGroupMembershipChangeBumpsAffectedContactsOnly(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		groups := NewGroupRepo(db)
+		contacts := map[string]*ContactWithPhonesEntity{}
+		for _, name := range []string{"John", "Jane", "Bob", "Ann"} {
+			contacts[name] = addTestContact(t, r, testTenant, name, "Doe")
+		}
+		friends, err := groups.AddGroup(ctx, testTenant, "Friends")
+		if err != nil {
+			t.Fatal(err)
+		}
+		family, err := groups.AddGroup(ctx, testTenant, "Family")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addMembers := func(groupId int64, names ...string) {
+			t.Helper()
+			ids := make([]int64, len(names))
+			for i, name := range names {
+				ids[i] = contacts[name].ID
+			}
+			if missing, found, err := groups.AddGroupMembers(ctx, testTenant, groupId, ids); err != nil || !found || len(missing) > 0 {
+				t.Fatalf("contacts are not added to group: missing=%v, found=%v, error %v", missing, found, err)
+			}
+		}
+		addMembers(friends.ID, "John", "Jane")
+		addMembers(family.ID, "Ann")
+		versions := map[string]int{"John": 2, "Jane": 2, "Bob": 1, "Ann": 2}
+		checkVersions := func(change string) {
+			t.Helper()
+			for name, want := range versions {
+				c, err := r.SelectContactByID(ctx, testTenant, contacts[name].ID)
+				if err != nil || c == nil {
+					t.Fatalf("contact %s is not selected: %v", name, err)
+				}
+				if c.Version != want {
+					t.Errorf("version of %s after %s is %d, want %d", name, change, c.Version, want)
+				}
+			}
+		}
+		checkVersions("adding members")
+
+		addMembers(friends.ID, "John", "Bob")
+		versions["Bob"]++
+		checkVersions("adding new member along with existing one")
+
+		if found, err := groups.RemoveGroupMembers(ctx, testTenant, friends.ID, []int64{contacts["Jane"].ID, contacts["Ann"].ID}); err != nil || !found {
+			t.Fatalf("members are not removed from group: %v", err)
+		}
+		versions["Jane"]++
+		checkVersions("removing member along with non-member")
+
+		if found, err := groups.DeleteGroup(ctx, testTenant, family.ID); err != nil || !found {
+			t.Fatalf("group is not deleted: %v", err)
+		}
+		versions["Ann"]++
+		checkVersions("deleting another group")
+	})
+}
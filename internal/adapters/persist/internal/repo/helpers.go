@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/jmoiron/sqlx"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
 )
 
 // MustPrepareNamed creates prepared statement or panics if it cannot be created.
@@ -42,6 +43,32 @@ func MergeJoinedRows[TJoined any, TMerged any, TId comparable](
 	return entities
 }
 
+// nullIfEmpty returns nil for empty string, so that it is stored as NULL
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullIfZero returns nil for zero number, so that it is stored as NULL
+func nullIfZero(n int) any {
+	if n == 0 {
+		return nil
+	}
+	return n
+}
+
+// dbErrorf formats error of database request. Requests failed because database is busy are reported as
+// model.ErrUnavailable, so that they may be retried later.
+func dbErrorf(format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	if isSQLiteBusy(err) {
+		return fmt.Errorf("%w: %w", model.ErrUnavailable, err)
+	}
+	return err
+}
+
 func MustGetRowsAffected(result sql.Result) int64 {
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
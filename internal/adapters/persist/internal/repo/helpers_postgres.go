@@ -0,0 +1,13 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+)
+
+// QueryNamedStmtReturningId runs statement that ends with "RETURNING id" clause and returns the id
+func QueryNamedStmtReturningId(ctx context.Context, stmt *sqlx.NamedStmt, arg any) (int64, error) {
+	var id int64
+	err := stmt.QueryRowxContext(ctx, arg).Scan(&id)
+	return id, err
+}
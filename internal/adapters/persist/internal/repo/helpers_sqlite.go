@@ -2,7 +2,9 @@ package repo
 
 import (
 	"context"
+	"errors"
 	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
 )
 
 func ExecNamedStmtReturningLastInsertId(ctx context.Context, stmt *sqlx.NamedStmt, arg any) (int64, error) {
@@ -12,3 +14,10 @@ func ExecNamedStmtReturningLastInsertId(ctx context.Context, stmt *sqlx.NamedStm
 	}
 	return 0, err
 }
+
+// isSQLiteBusy reports whether request failed because SQLite database is locked by another connection for longer
+// than busy timeout
+func isSQLiteBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && (sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked)
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+)
+
+// PhoneToNormalizeEntity is a phone saved before numbers were normalized, it has no E.164 number
+type PhoneToNormalizeEntity struct {
+	ID          int64  `db:"id"`
+	ContactID   int64  `db:"contact_id"`
+	PhoneNumber string `db:"phone_number"`
+}
+
+// NormalizePhones fills E.164 numbers of phones saved before numbers were normalized and changes versions of their
+// contacts, so that cached representations are not served as current ones. normalize returns empty string for
+// a number which cannot be normalized, such phone keeps no E.164 number. It returns number of normalized phones
+// and number of phones which could not be normalized.
+func NormalizePhones(
+	ctx context.Context,
+	db *sqlx.DB,
+	normalize func(number string) string,
+) (normalized int, failed int, err error) {
+	tx := db.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+
+	var phones []*PhoneToNormalizeEntity
+	if err = tx.SelectContext(ctx, &phones, selectPhonesToNormalizeSql); err != nil {
+		err = dbErrorf("error selecting phones to normalize: %w", err)
+		zap.S().Errorln(err)
+		return 0, 0, err
+	}
+	changed := make(map[int64]bool)
+	for _, ph := range phones {
+		e164 := normalize(ph.PhoneNumber)
+		if e164 == "" {
+			failed++
+			continue
+		}
+		result, err := tx.NamedExecContext(ctx, updatePhoneE164Sql, map[string]any{
+			"id":        ph.ID,
+			"phoneE164": e164,
+		})
+		if err != nil {
+			err = dbErrorf("error normalizing phone id=%d: %w", ph.ID, err)
+			zap.S().Errorln(err)
+			return 0, 0, err
+		}
+		if MustGetRowsAffected(result) > 0 {
+			normalized++
+			changed[ph.ContactID] = true
+		}
+	}
+	for _, contactId := range lo.Keys(changed) {
+		_, err = tx.NamedExecContext(ctx, bumpNormalizedContactVersionSql, map[string]any{"contactId": contactId})
+		if err != nil {
+			err = dbErrorf("error changing version of contact id=%d: %w", contactId, err)
+			zap.S().Errorln(err)
+			return 0, 0, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		err = dbErrorf("error committing transaction: %w", err)
+		zap.S().Errorln(err)
+		return 0, 0, err
+	}
+	return normalized, failed, nil
+}
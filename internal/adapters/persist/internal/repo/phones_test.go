@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"strings"
+	"testing"
+)
+
+func TestNormalizePhones(t *testing.T) {
+	runWithDatabases(t, func(t *testing.T, db *sqlx.DB) {
+		ctx := context.Background()
+		r := NewAddrBookRepo(db)
+		// phones saved before numbers were normalized have no E.164 number
+		john := addTestContact(t, r, testTenant, "John", "Doe", "503-555-7777", "unknown")
+		jane := addTestContact(t, r, testTenant, "Jane", "Doe")
+		normalize := func(number string) string {
+			if number == "unknown" {
+				return ""
+			}
+			return "+1" + strings.ReplaceAll(number, "-", "")
+		}
+
+		normalized, failed, err := NormalizePhones(ctx, db, normalize)
+		if err != nil || normalized != 1 || failed != 1 {
+			t.Fatalf("NormalizePhones returned normalized=%d failed=%d, error %v", normalized, failed, err)
+		}
+		found, err := r.SelectContactsByPhone(ctx, testTenant, "+15035557777")
+		if err != nil || len(found) != 1 || found[0].ID != john.ID {
+			t.Fatalf("contact is not found by normalized phone: %+v, %v", found, err)
+		}
+		for _, c := range []struct {
+			ID      int64
+			version int
+		}{{john.ID, 2}, {jane.ID, 1}} {
+			saved, err := r.SelectContactByID(ctx, testTenant, c.ID)
+			if err != nil || saved == nil {
+				t.Fatalf("contact id=%d is not selected: %v", c.ID, err)
+			}
+			if saved.Version != c.version {
+				t.Errorf("contact id=%d has version %d, want %d", c.ID, saved.Version, c.version)
+			}
+		}
+
+		// normalized phones are not checked again
+		normalized, failed, err = NormalizePhones(ctx, db, normalize)
+		if err != nil || normalized != 0 || failed != 1 {
+			t.Errorf("second NormalizePhones returned normalized=%d failed=%d, error %v", normalized, failed, err)
+		}
+	})
+}
@@ -1,37 +1,231 @@
 package repo
 
-// selectAllContactsWithPhonesSql contains SQL request that returns all rows where single row is result of merging
-// contact row with phone row
-const selectAllContactsWithPhonesSql =
+import "fmt"
+
+// contactListFilterSql contains WHERE conditions shared by contacts page and contacts count requests,
+// an empty parameter value disables corresponding filter (except for tenant which is always required).
+// Contacts moved to trash are never listed.
+const contactListFilterSql =
 /*language=sql*/ `
-SELECT
-    c.id AS id, c.first_name AS first_name, c.last_name as last_name,
-    p.type AS phone_type, p.phone_number AS phone_number
-FROM contacts c 
-LEFT JOIN phones p on c.id = p.contact_id
-ORDER BY c.last_name, c.first_name
+    c.tenant_id = :tenantId
+    AND c.deleted_at IS NULL
+    AND (:lastNamePattern = '' OR lower(c.last_name) LIKE lower(:lastNamePattern) ESCAPE '\')
+    AND (:phoneType = '' OR EXISTS (SELECT 1 FROM phones fp WHERE fp.contact_id = c.id AND fp.type = :phoneType))
+    AND (:customFieldId = 0 OR EXISTS (SELECT 1 FROM custom_field_values fv
+         WHERE fv.contact_id = c.id AND fv.field_id = :customFieldId AND fv.value = :customFieldValue))
+    AND (:groupId = 0 OR EXISTS (SELECT 1 FROM contact_group_members gm
+         WHERE gm.contact_id = c.id AND gm.group_id = :groupId))
 `
 
+// buildSelectContactsPageSql builds SQL request that returns a single page of contacts merged with their phones.
+// Pagination is keyset based: page starts right after the contact identified by (afterPrimary, afterSecondary,
+// afterId) values, where primary and secondary are sort columns and contact id makes ordering stable.
+// Zero afterId means that the first page is requested.
+func buildSelectContactsPageSql(primary string, secondary string, desc bool) string {
+	cmp, dir := ">", "ASC"
+	if desc {
+		cmp, dir = "<", "DESC"
+	}
+	return fmt.Sprintf(
+		/*language=sql*/ `
+WITH page AS (
+    SELECT c.id, c.first_name, c.last_name
+    FROM contacts c
+    WHERE %[1]s
+      AND (:afterId = 0 OR (c.%[2]s, c.%[3]s, c.id) %[4]s (:afterPrimary, :afterSecondary, :afterId))
+    ORDER BY c.%[2]s %[5]s, c.%[3]s %[5]s, c.id %[5]s
+    LIMIT :limit
+)
+SELECT
+    page.id AS id, page.first_name AS first_name, page.last_name AS last_name,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM page
+LEFT JOIN phones p ON page.id = p.contact_id
+ORDER BY page.%[2]s %[5]s, page.%[3]s %[5]s, page.id %[5]s, p.id
+`, contactListFilterSql, primary, secondary, cmp, dir)
+}
+
+const countContactsSql =
+/*language=sql*/ `
+SELECT COUNT(*) FROM contacts c WHERE ` + contactListFilterSql
+
 const insertContactSql =
 /*language=sql*/ `
-INSERT INTO contacts(first_name, last_name)
-VALUES (:firstName, :lastName)
+INSERT INTO contacts(tenant_id, first_name, last_name)
+VALUES (:tenantId, :firstName, :lastName)
 `
 
 const insertPhoneSql =
 /*language=sql*/ `
-INSERT INTO phones(type, phone_number, contact_id)
-VALUES (:type, :phoneNumber, :contactId)
+INSERT INTO phones(type, phone_number, phone_e164, contact_id)
+VALUES (:type, :phoneNumber, :phoneE164, :contactId)
 `
 
 const selectContactsWithPhonesByIdSql =
 /*language=sql*/ `
 SELECT
-    c.id AS id, c.first_name AS first_name, c.last_name as last_name,
-    p.type AS phone_type, p.phone_number AS phone_number
+    c.id AS id, c.first_name AS first_name, c.last_name as last_name, c.version AS version,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
 FROM contacts c 
 LEFT JOIN phones p on c.id = p.contact_id
-WHERE c.id = :id
+WHERE c.id = :id AND c.tenant_id = :tenantId AND c.deleted_at IS NULL
+ORDER BY p.id
+`
+
+// selectContactsByPhoneSql returns contacts of the tenant having a phone with the number merged with all their phones,
+// the number is looked up by phones_e164_idx index
+const selectContactsByPhoneSql =
+/*language=sql*/ `
+WITH found AS (
+    SELECT DISTINCT c.id, c.first_name, c.last_name
+    FROM phones fp
+    JOIN contacts c ON c.id = fp.contact_id
+    WHERE fp.phone_e164 = :phoneE164 AND c.tenant_id = :tenantId AND c.deleted_at IS NULL
+)
+SELECT
+    found.id AS id, found.first_name AS first_name, found.last_name AS last_name,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM found
+LEFT JOIN phones p ON found.id = p.contact_id
+ORDER BY found.last_name, found.first_name, found.id, p.id
+`
+
+const insertEmailSql =
+/*language=sql*/ `
+INSERT INTO emails(label, address, contact_id)
+VALUES (:label, :address, :contactId)
+`
+
+const insertAddressSql =
+/*language=sql*/ `
+INSERT INTO addresses(label, street, city, region, postal_code, country, contact_id)
+VALUES (:label, :street, :city, :region, :postalCode, :country, :contactId)
+`
+
+const insertURLSql =
+/*language=sql*/ `
+INSERT INTO urls(label, url, contact_id)
+VALUES (:label, :url, :contactId)
+`
+
+const insertCustomFieldValueSql =
+/*language=sql*/ `
+INSERT INTO custom_field_values(field_id, value, contact_id)
+VALUES (:fieldId, :value, :contactId)
+`
+
+// select*ByContactIdsSql requests are expanded by sqlx.In, so they use "?" bind variable instead of named ones
+const selectEmailsByContactIdsSql =
+/*language=sql*/ `
+SELECT contact_id, label, address FROM emails WHERE contact_id IN (?) ORDER BY id
+`
+
+const selectAddressesByContactIdsSql =
+/*language=sql*/ `
+SELECT contact_id, label, street, city, region, postal_code, country FROM addresses WHERE contact_id IN (?) ORDER BY id
+`
+
+const selectURLsByContactIdsSql =
+/*language=sql*/ `
+SELECT contact_id, label, url FROM urls WHERE contact_id IN (?) ORDER BY id
+`
+
+const selectCustomFieldValuesByContactIdsSql =
+/*language=sql*/ `
+SELECT v.contact_id, f.name, f.type, v.value
+FROM custom_field_values v
+JOIN custom_fields f ON f.id = v.field_id
+WHERE v.contact_id IN (?)
+ORDER BY f.id
+`
+
+const selectGroupsByContactIdsSql =
+/*language=sql*/ `
+SELECT m.contact_id, g.id, g.name
+FROM contact_group_members m
+JOIN contact_groups g ON g.id = m.group_id
+WHERE m.contact_id IN (?)
+ORDER BY g.name, g.id
+`
+
+const selectPhonesToNormalizeSql =
+/*language=sql*/ `
+SELECT id, contact_id, phone_number FROM phones WHERE phone_e164 IS NULL ORDER BY id
+`
+
+const updatePhoneE164Sql =
+/*language=sql*/ `
+UPDATE phones SET phone_e164 = :phoneE164 WHERE id = :id AND phone_e164 IS NULL
+`
+
+// bumpNormalizedContactVersionSql changes version of a contact whose phones were normalized, normalization is
+// not made on behalf of any tenant
+const bumpNormalizedContactVersionSql =
+/*language=sql*/ `
+UPDATE contacts SET version = version + 1 WHERE id = :contactId
+`
+
+const selectPhoneE164sByContactIdsSql =
+/*language=sql*/ `
+SELECT DISTINCT phone_e164 FROM phones WHERE contact_id IN (?) AND phone_e164 IS NOT NULL
+`
+
+// deleteContactDetailsSqls delete emails, addresses, URLs and custom field values of the contact
+var deleteContactDetailsSqls = []string{
+	/*language=sql*/ `DELETE FROM emails WHERE contact_id = :contactId`,
+	/*language=sql*/ `DELETE FROM addresses WHERE contact_id = :contactId`,
+	/*language=sql*/ `DELETE FROM urls WHERE contact_id = :contactId`,
+	/*language=sql*/ `DELETE FROM custom_field_values WHERE contact_id = :contactId`,
+}
+
+// trashContactByIdSql moves contact to trash, zero version moves contact of any version
+const trashContactByIdSql =
+/*language=sql*/ `
+UPDATE contacts SET deleted_at = :deletedAt, version = version + 1
+WHERE id = :id AND tenant_id = :tenantId AND deleted_at IS NULL AND (:version = 0 OR version = :version)
+`
+
+const restoreContactByIdSql =
+/*language=sql*/ `
+UPDATE contacts SET deleted_at = NULL, version = version + 1
+WHERE id = :id AND tenant_id = :tenantId AND deleted_at IS NOT NULL
+`
+
+// selectContactVersionSql returns version of the contact which is not in trash
+const selectContactVersionSql =
+/*language=sql*/ `
+SELECT version FROM contacts WHERE id = :id AND tenant_id = :tenantId AND deleted_at IS NULL
+`
+
+// selectTrashedContactsSql returns contacts of the tenant moved to trash merged with their phones,
+// the most recently deleted contacts go first
+const selectTrashedContactsSql =
+/*language=sql*/ `
+WITH trashed AS (
+    SELECT c.id, c.first_name, c.last_name, c.deleted_at
+    FROM contacts c
+    WHERE c.tenant_id = :tenantId AND c.deleted_at IS NOT NULL
+    ORDER BY c.deleted_at DESC, c.id DESC
+    LIMIT :limit
+)
+SELECT
+    trashed.id AS id, trashed.first_name AS first_name, trashed.last_name AS last_name,
+    trashed.deleted_at AS deleted_at,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM trashed
+LEFT JOIN phones p ON trashed.id = p.contact_id
+ORDER BY trashed.deleted_at DESC, trashed.id DESC, p.id
+`
+
+// selectContactIdsDeletedBeforeSql returns ids of contacts of all tenants which were moved to trash before given time
+const selectContactIdsDeletedBeforeSql =
+/*language=sql*/ `
+SELECT id FROM contacts WHERE deleted_at IS NOT NULL AND deleted_at < :deletedBefore ORDER BY id LIMIT :limit
+`
+
+const deleteGroupMembershipsByContactIdSql =
+/*language=sql*/ `
+DELETE FROM contact_group_members WHERE contact_id = :contactId
 `
 
 const deleteContactByIdSql =
@@ -44,10 +238,223 @@ const deletePhonesByContactIdSql =
 DELETE FROM phones WHERE contact_id = :contactId
 `
 
+// updateContactByIdSql updates contact on condition that it has expected version, zero version updates contact
+// of any version
 const updateContactByIdSql =
 /*language=sql*/ `
 UPDATE contacts
 SET first_name = :firstName,
-    last_name = :lastName
-WHERE id = :contactId
+    last_name = :lastName,
+    version = version + 1
+WHERE id = :contactId AND tenant_id = :tenantId AND deleted_at IS NULL AND (:version = 0 OR version = :version)
+`
+
+const insertAPIKeySql =
+/*language=sql*/ `
+INSERT INTO api_keys(name, tenant_id, role, prefix, key_hash, created_at)
+VALUES (:name, :tenantId, :role, :prefix, :keyHash, :createdAt)
+`
+
+const selectAllAPIKeysSql =
+/*language=sql*/ `
+SELECT id, name, tenant_id, role, prefix, created_at, revoked_at FROM api_keys ORDER BY id
+`
+
+const selectActiveAPIKeyByHashSql =
+/*language=sql*/ `
+SELECT id, name, tenant_id, role, prefix, created_at, revoked_at FROM api_keys WHERE key_hash = :keyHash AND revoked_at IS NULL
+`
+
+const revokeAPIKeyByIdSql =
+/*language=sql*/ `
+UPDATE api_keys SET revoked_at = :revokedAt WHERE id = :id AND revoked_at IS NULL
+`
+
+const selectCustomFieldsSql =
+/*language=sql*/ `
+SELECT id, name, type, required, enum_values FROM custom_fields WHERE tenant_id = :tenantId ORDER BY id
+`
+
+const insertCustomFieldSql =
+/*language=sql*/ `
+INSERT INTO custom_fields(tenant_id, name, type, required, enum_values)
+VALUES (:tenantId, :name, :type, :required, :enumValues)
+`
+
+// deleteCustomFieldValuesByFieldIdSql deletes values of the field only if the field belongs to the tenant
+const deleteCustomFieldValuesByFieldIdSql =
+/*language=sql*/ `
+DELETE FROM custom_field_values
+WHERE field_id = (SELECT id FROM custom_fields WHERE id = :id AND tenant_id = :tenantId)
+`
+
+// selectContactIdsByCustomFieldIdSql selects contacts having value of the field only if the field belongs to the tenant
+const selectContactIdsByCustomFieldIdSql =
+/*language=sql*/ `
+SELECT DISTINCT c.id FROM contacts c JOIN custom_field_values v ON v.contact_id = c.id
+WHERE v.field_id = :id AND c.tenant_id = :tenantId AND c.deleted_at IS NULL
+ORDER BY c.id
+`
+
+// bumpContactVersionsByCustomFieldIdSql changes versions of contacts having value of the field
+const bumpContactVersionsByCustomFieldIdSql =
+/*language=sql*/ `
+UPDATE contacts SET version = version + 1
+WHERE id IN (SELECT contact_id FROM custom_field_values WHERE field_id = :id)
+  AND tenant_id = :tenantId AND deleted_at IS NULL
+`
+
+const deleteCustomFieldByIdSql =
+/*language=sql*/ `
+DELETE FROM custom_fields WHERE id = :id AND tenant_id = :tenantId
+`
+
+const selectGroupsSql =
+/*language=sql*/ `
+SELECT g.id, g.name, (SELECT COUNT(*) FROM contact_group_members m JOIN contacts c ON c.id = m.contact_id
+                      WHERE m.group_id = g.id AND c.deleted_at IS NULL) AS member_count
+FROM contact_groups g
+WHERE g.tenant_id = :tenantId AND (:id = 0 OR g.id = :id)
+ORDER BY g.name, g.id
+`
+
+const insertGroupSql =
+/*language=sql*/ `
+INSERT INTO contact_groups(tenant_id, name) VALUES (:tenantId, :name)
+`
+
+const updateGroupByIdSql =
+/*language=sql*/ `
+UPDATE contact_groups SET name = :name WHERE id = :id AND tenant_id = :tenantId
+`
+
+const countGroupsByIdSql =
+/*language=sql*/ `
+SELECT COUNT(*) FROM contact_groups WHERE id = :id AND tenant_id = :tenantId
+`
+
+// bumpContactVersionsByGroupIdSql changes versions of group members, the group must belong to the caller tenant
+const bumpContactVersionsByGroupIdSql =
+/*language=sql*/ `
+UPDATE contacts SET version = version + 1
+WHERE id IN (SELECT contact_id FROM contact_group_members WHERE group_id = :id)
+  AND tenant_id = :tenantId AND deleted_at IS NULL
+`
+
+const deleteGroupMembersByGroupIdSql =
+/*language=sql*/ `
+DELETE FROM contact_group_members WHERE group_id = :id
+`
+
+const deleteGroupByIdSql =
+/*language=sql*/ `
+DELETE FROM contact_groups WHERE id = :id AND tenant_id = :tenantId
+`
+
+const selectGroupMemberIdsSql =
+/*language=sql*/ `
+SELECT contact_id FROM contact_group_members WHERE group_id = :id ORDER BY contact_id
+`
+
+const insertGroupMemberSql =
+/*language=sql*/ `
+INSERT INTO contact_group_members(group_id, contact_id) VALUES (:groupId, :contactId)
+ON CONFLICT DO NOTHING
+`
+
+const deleteGroupMemberSql =
+/*language=sql*/ `
+DELETE FROM contact_group_members WHERE group_id = :groupId AND contact_id = :contactId
+`
+
+// bumpContactVersionSql changes version of a contact of the caller tenant which is not in trash
+const bumpContactVersionSql =
+/*language=sql*/ `
+UPDATE contacts SET version = version + 1
+WHERE id = :contactId AND tenant_id = :tenantId AND deleted_at IS NULL
+`
+
+// selectContactIdsOfTenantSql is expanded by sqlx.In, so it uses "?" bind variables instead of named ones
+const selectContactIdsOfTenantSql =
+/*language=sql*/ `
+SELECT id FROM contacts WHERE tenant_id = ? AND id IN (?) AND deleted_at IS NULL
+`
+
+// selectContactSnapshotSql returns contact merged with its phones regardless of tenant and trash, it is used to take
+// snapshot of the contact which was just changed within the same transaction
+const selectContactSnapshotSql =
+/*language=sql*/ `
+SELECT
+    c.id AS id, c.first_name AS first_name, c.last_name as last_name,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM contacts c
+LEFT JOIN phones p on c.id = p.contact_id
+WHERE c.id = :contactId
+ORDER BY p.id
+`
+
+// insertContactRevisionSql numbers revisions of every contact sequentially starting with 1
+const insertContactRevisionSql =
+/*language=sql*/ `
+INSERT INTO contact_revisions(contact_id, revision, action, actor, changed_at, snapshot, reverted_to)
+VALUES (
+    :contactId,
+    (SELECT COALESCE(MAX(r.revision), 0) + 1 FROM contact_revisions r WHERE r.contact_id = :contactId),
+    :action, :actor, :changedAt, :snapshot, :revertedTo
+)
+`
+
+// selectContactRevisionsSql returns revisions of the contact of the tenant (including contact in trash),
+// the most recent revisions go first
+const selectContactRevisionsSql =
+/*language=sql*/ `
+SELECT r.revision, r.action, r.actor, r.changed_at, r.snapshot, r.reverted_to
+FROM contact_revisions r
+JOIN contacts c ON c.id = r.contact_id
+WHERE r.contact_id = :contactId AND c.tenant_id = :tenantId
+ORDER BY r.revision DESC
+LIMIT :limit
+`
+
+// selectContactRevisionAsOfSql returns the last revision of the contact of the tenant made at or before given time
+const selectContactRevisionAsOfSql =
+/*language=sql*/ `
+SELECT r.revision, r.action, r.actor, r.changed_at, r.snapshot, r.reverted_to
+FROM contact_revisions r
+JOIN contacts c ON c.id = r.contact_id
+WHERE r.contact_id = :contactId AND c.tenant_id = :tenantId AND r.changed_at <= :asOf
+ORDER BY r.revision DESC
+LIMIT 1
+`
+
+// selectContactsWithoutRevisionsSql returns contacts (including contacts in trash) saved before revisions were
+// recorded and not changed since then
+const selectContactsWithoutRevisionsSql =
+/*language=sql*/ `
+SELECT c.id, c.deleted_at FROM contacts c
+WHERE NOT EXISTS (SELECT 1 FROM contact_revisions r WHERE r.contact_id = c.id)
+ORDER BY c.id
+`
+
+const countContactRevisionsSql =
+/*language=sql*/ `
+SELECT COUNT(*) FROM contact_revisions WHERE contact_id = :contactId
+`
+
+const selectContactRevisionByNumberSql =
+/*language=sql*/ `
+SELECT r.revision, r.action, r.actor, r.changed_at, r.snapshot, r.reverted_to
+FROM contact_revisions r
+JOIN contacts c ON c.id = r.contact_id
+WHERE r.contact_id = :contactId AND c.tenant_id = :tenantId AND r.revision = :revision
+`
+
+const countContactsOfTenantByIdSql =
+/*language=sql*/ `
+SELECT COUNT(*) FROM contacts WHERE id = :id AND tenant_id = :tenantId
+`
+
+const deleteContactRevisionsByContactIdSql =
+/*language=sql*/ `
+DELETE FROM contact_revisions WHERE contact_id = :contactId
 `
@@ -0,0 +1,72 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+var postgresDialect = &dialect{
+	returningIdClause:           "RETURNING id\n",
+	indexContactSearchSql:       indexContactSearchPostgresSql,
+	deleteContactSearchSql:      deleteContactSearchPostgresSql,
+	searchContactsWithPhonesSql: searchContactsWithPhonesPostgresSql,
+	buildSearchMatchQuery: func(terms []string) string {
+		// e.g. jo:* & 50355:*
+		for i, term := range terms {
+			terms[i] = term + ":*"
+		}
+		return strings.Join(terms, " & ")
+	},
+	execInsertReturningId: func(ctx context.Context, stmt *sqlx.NamedStmt, arg any) (int64, error) {
+		return QueryNamedStmtReturningId(ctx, stmt, arg)
+	},
+}
+
+// indexContactSearchPostgresSql contains SQL request that builds full-text search document for the contact. Every
+// phone number is indexed as a list of its digit suffixes (down to 3 digits long), so that prefix search over them
+// matches any fragment of the number, e.g. "5557" matches "503-555-7777".
+const indexContactSearchPostgresSql =
+/*language=postgresql*/ `
+WITH RECURSIVE
+    phone_digits(contact_id, fragment) AS (
+        SELECT p.contact_id, regexp_replace(p.phone_number, '[^0-9]', '', 'g')
+        FROM phones p
+        WHERE p.contact_id = :contactId
+    ),
+    phone_fragments(contact_id, fragment) AS (
+        SELECT contact_id, fragment FROM phone_digits
+        UNION ALL
+        SELECT contact_id, substr(fragment, 2) FROM phone_fragments WHERE length(fragment) > 3
+    )
+INSERT INTO contacts_search(contact_id, document)
+SELECT c.id, to_tsvector('simple', c.first_name || ' ' || c.last_name || ' ' ||
+       COALESCE((SELECT string_agg(f.fragment, ' ') FROM phone_fragments f WHERE f.contact_id = c.id), ''))
+FROM contacts c
+WHERE c.id = :contactId
+`
+
+const deleteContactSearchPostgresSql =
+/*language=postgresql*/ `
+DELETE FROM contacts_search WHERE contact_id = :contactId
+`
+
+// searchContactsWithPhonesPostgresSql returns contacts of the tenant matching full-text query merged with their phones,
+// the best matches go first
+const searchContactsWithPhonesPostgresSql =
+/*language=postgresql*/ `
+SELECT
+    c.id AS id, c.first_name AS first_name, c.last_name AS last_name,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM (
+    SELECT s.contact_id, ts_rank(s.document, to_tsquery('simple', :query)) AS rank
+    FROM contacts_search s
+    JOIN contacts tc ON tc.id = s.contact_id
+    WHERE s.document @@ to_tsquery('simple', :query) AND tc.tenant_id = :tenantId AND tc.deleted_at IS NULL
+    ORDER BY rank DESC
+    LIMIT :limit
+) m
+JOIN contacts c ON c.id = m.contact_id
+LEFT JOIN phones p ON c.id = p.contact_id
+ORDER BY m.rank DESC, c.id, p.id
+`
@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+var sqliteDialect = &dialect{
+	returningIdClause:           "",
+	indexContactSearchSql:       indexContactSearchSqliteSql,
+	deleteContactSearchSql:      deleteContactSearchSqliteSql,
+	searchContactsWithPhonesSql: searchContactsWithPhonesSqliteSql,
+	buildSearchMatchQuery: func(terms []string) string {
+		// e.g. "jo"* AND "50355"*
+		for i, term := range terms {
+			terms[i] = `"` + term + `"*`
+		}
+		return strings.Join(terms, " AND ")
+	},
+	execInsertReturningId: func(ctx context.Context, stmt *sqlx.NamedStmt, arg any) (int64, error) {
+		return ExecNamedStmtReturningLastInsertId(ctx, stmt, arg)
+	},
+}
+
+// indexContactSearchSqliteSql contains SQL request that builds full-text search document for the contact. Every phone
+// number is indexed as a list of its digit suffixes (down to 3 digits long), so that prefix search over them
+// matches any fragment of the number, e.g. "5557" matches "503-555-7777".
+const indexContactSearchSqliteSql =
+/*language=sqlite*/ `
+WITH RECURSIVE
+    phone_digits(contact_id, fragment) AS (
+        SELECT p.contact_id,
+               replace(replace(replace(replace(replace(replace(
+                   p.phone_number, '-', ''), ' ', ''), '(', ''), ')', ''), '+', ''), '.', '')
+        FROM phones p
+        WHERE p.contact_id = :contactId
+    ),
+    phone_fragments(contact_id, fragment) AS (
+        SELECT contact_id, fragment FROM phone_digits
+        UNION ALL
+        SELECT contact_id, substr(fragment, 2) FROM phone_fragments WHERE length(fragment) > 3
+    )
+INSERT INTO contacts_fts(rowid, first_name, last_name, phones)
+SELECT c.id, c.first_name, c.last_name,
+       COALESCE((SELECT group_concat(f.fragment, ' ') FROM phone_fragments f WHERE f.contact_id = c.id), '')
+FROM contacts c
+WHERE c.id = :contactId
+`
+
+const deleteContactSearchSqliteSql =
+/*language=sqlite*/ `
+DELETE FROM contacts_fts WHERE rowid = :contactId
+`
+
+// searchContactsWithPhonesSqliteSql returns contacts of the tenant matching full-text query merged with their phones,
+// the best matches go first
+const searchContactsWithPhonesSqliteSql =
+/*language=sqlite*/ `
+SELECT
+    c.id AS id, c.first_name AS first_name, c.last_name AS last_name,
+    p.type AS phone_type, p.phone_number AS phone_number, p.phone_e164 AS phone_e164
+FROM (
+    SELECT contacts_fts.rowid AS contact_id, contacts_fts.rank AS rank
+    FROM contacts_fts
+    JOIN contacts tc ON tc.id = contacts_fts.rowid
+    WHERE contacts_fts MATCH :query AND tc.tenant_id = :tenantId AND tc.deleted_at IS NULL
+    ORDER BY contacts_fts.rank
+    LIMIT :limit
+) m
+JOIN contacts c ON c.id = m.contact_id
+LEFT JOIN phones p ON c.id = p.contact_id
+ORDER BY m.rank, c.id, p.id
+`
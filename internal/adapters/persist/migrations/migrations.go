@@ -0,0 +1,394 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql
+var embedded embed.FS
+
+// Dialects have their own set of migration scripts, every script must have a counterpart with the same
+// version in every dialect directory
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+var dialects = []string{DialectSQLite, DialectPostgres}
+
+// SourceDir is a source tree directory with migration scripts, new scripts are created in its dialect subdirectories
+const SourceDir = "internal/adapters/persist/migrations"
+
+// Scripts returns migration scripts of the dialect embedded into application binary
+func Scripts(dialect string) fs.FS {
+	sub, err := fs.Sub(embedded, dialect)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// Migration is a single schema change, loaded from a pair of files named as
+// <version>_<name>.up.sql and <version>_<name>.down.sql
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // checksum of Up script, it is recorded when migration is applied
+}
+
+// Status describes migration and whether it was applied to the database
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	Modified  bool // applied migration script was changed afterwards
+	Missing   bool // applied migration has no script
+}
+
+type appliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+const createMigrationsTableSql =
+/*language=sql*/ `
+CREATE TABLE IF NOT EXISTS schema_migrations(
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    checksum TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL
+)
+`
+
+var fileNameRegexp = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []*Migration
+}
+
+// NewMigrator loads migration scripts from the file system, migrations are ordered by version
+func NewMigrator(db *sqlx.DB, scripts fs.FS) (*Migrator, error) {
+	migrations, err := load(scripts)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func load(scripts fs.FS) ([]*Migration, error) {
+	files, err := fs.ReadDir(scripts, ".")
+	if err != nil {
+		return nil, fmt.Errorf("error reading migration scripts: %w", err)
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		match := fileNameRegexp.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(scripts, f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("error reading migration script %s: %w", f.Name(), err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+			m.Checksum = checksum(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func checksum(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+// Up applies all pending migrations, every migration runs in its own transaction.
+// It refuses to run if any of already applied migrations was modified. Migrations are applied under migration lock,
+// so that replicas starting together do not apply the same migration twice.
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(s *session) error {
+		applied, err := loadApplied(ctx, s.queryer())
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if a, ok := applied[mg.Version]; ok && a.Checksum != mg.Checksum {
+				return fmt.Errorf("applied migration %d_%s was modified (checksum mismatch)", mg.Version, mg.Name)
+			}
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			zap.S().Infof("applying migration %d_%s", mg.Version, mg.Name)
+			err = s.inTx(ctx, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.Up); err != nil {
+					return err
+				}
+				_, err := tx.NamedExecContext(ctx,
+					`INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES (:version, :name, :checksum, :appliedAt)`,
+					map[string]any{
+						"version":   mg.Version,
+						"name":      mg.Name,
+						"checksum":  mg.Checksum,
+						"appliedAt": time.Now().UTC(),
+					})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("error applying migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts up to steps last applied migrations, starting from the most recent one
+func (m *Migrator) Down(ctx context.Context, steps int) ([]*Migration, error) {
+	var done []*Migration
+	err := m.withLock(ctx, func(s *session) error {
+		applied, err := loadApplied(ctx, s.queryer())
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mg := m.migrations[i]
+			if _, ok := applied[mg.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(mg.Down) == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted, it has no down script", mg.Version, mg.Name)
+			}
+			zap.S().Infof("reverting migration %d_%s", mg.Version, mg.Name)
+			err = s.inTx(ctx, func(tx *sqlx.Tx) error {
+				if _, err := tx.ExecContext(ctx, mg.Down); err != nil {
+					return err
+				}
+				_, err := tx.NamedExecContext(ctx,
+					`DELETE FROM schema_migrations WHERE version = :version`,
+					map[string]any{"version": mg.Version})
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("error reverting migration %d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status returns all known migrations ordered by version, including applied ones which scripts are missing
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	applied, err := loadApplied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]*Status, 0, len(m.migrations))
+	for _, mg := range m.migrations {
+		st := &Status{Version: mg.Version, Name: mg.Name}
+		if a, ok := applied[mg.Version]; ok {
+			st.AppliedAt = &a.AppliedAt
+			st.Modified = a.Checksum != mg.Checksum
+			delete(applied, mg.Version)
+		}
+		statuses = append(statuses, st)
+	}
+	for _, a := range applied {
+		appliedAt := a.AppliedAt
+		statuses = append(statuses, &Status{Version: a.Version, Name: a.Name, AppliedAt: &appliedAt, Missing: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// queryer is implemented by database, connection and transaction
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+func loadApplied(ctx context.Context, q queryer) (map[int64]*appliedMigration, error) {
+	if _, err := q.ExecContext(ctx, createMigrationsTableSql); err != nil {
+		return nil, fmt.Errorf("error creating schema_migrations table: %w", err)
+	}
+	var rows []*appliedMigration
+	if err := q.SelectContext(ctx, &rows, `SELECT version, name, checksum, applied_at FROM schema_migrations`); err != nil {
+		return nil, fmt.Errorf("error loading applied migrations: %w", err)
+	}
+	applied := make(map[int64]*appliedMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// migrationLockKey identifies PostgreSQL advisory lock which is held while migrations are applied or reverted
+const migrationLockKey = 7236118443
+
+// createMigrationsLockTableSql creates SQLite table, its row is written to lock database while migrations are
+// applied or reverted
+const createMigrationsLockTableSql =
+/*language=sqlite*/ `
+CREATE TABLE IF NOT EXISTS schema_migrations_lock(
+    id INTEGER PRIMARY KEY,
+    locked_at TIMESTAMP NOT NULL
+)
+`
+
+// session is a database connection holding migration lock
+type session struct {
+	conn *sqlx.Conn
+	tx   *sqlx.Tx // SQLite transaction holding the lock, migrations run in its savepoints
+}
+
+// withLock runs fn on a connection holding migration lock, concurrent callers wait until the lock is released.
+// PostgreSQL connection holds session advisory lock. SQLite database is locked by a transaction writing the lock row,
+// it is committed after fn returns, so that migrations which were applied before a failed one are kept.
+func (m *Migrator) withLock(ctx context.Context, fn func(s *session) error) error {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("error connecting to database: %w", err)
+	}
+	defer conn.Close()
+	s := &session{conn: conn}
+
+	if m.db.DriverName() != "sqlite3" {
+		if _, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+			return fmt.Errorf("error acquiring migration lock: %w", err)
+		}
+		defer func() {
+			if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockKey); err != nil {
+				zap.S().Errorln("error releasing migration lock:", err)
+				// session keeps the lock until it is closed, so the connection must not return to the pool
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+		return fn(s)
+	}
+
+	if _, err = conn.ExecContext(ctx, createMigrationsLockTableSql); err != nil {
+		return fmt.Errorf("error creating schema_migrations_lock table: %w", err)
+	}
+	if s.tx, err = conn.BeginTxx(ctx, nil); err != nil {
+		return err
+	}
+	defer s.tx.Rollback()
+	// the first statement of transaction writes, so that it waits for the lock instead of failing on upgrade
+	// from read to write lock
+	_, err = s.tx.ExecContext(ctx, `INSERT OR REPLACE INTO schema_migrations_lock(id, locked_at) VALUES (1, ?)`,
+		time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("error acquiring migration lock: %w", err)
+	}
+	err = fn(s)
+	if commitErr := s.tx.Commit(); commitErr != nil && err == nil {
+		err = fmt.Errorf("error committing migrations: %w", commitErr)
+	}
+	return err
+}
+
+func (s *session) queryer() queryer {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.conn
+}
+
+// inTx runs fn in a transaction of its own, or in a savepoint of SQLite transaction holding the lock
+func (s *session) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	if s.tx != nil {
+		if _, err := s.tx.ExecContext(ctx, `SAVEPOINT migration`); err != nil {
+			return err
+		}
+		if err := fn(s.tx); err != nil {
+			if _, rbErr := s.tx.ExecContext(ctx, `ROLLBACK TO migration`); rbErr != nil {
+				zap.S().Errorln("error rolling back migration:", rbErr)
+			}
+			return err
+		}
+		_, err := s.tx.ExecContext(ctx, `RELEASE migration`)
+		return err
+	}
+	tx, err := s.conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create adds a pair of empty up/down scripts to every dialect subdirectory of the directory,
+// new migration gets the next version number
+func Create(dir string, name string) (files []string, err error) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("migration name must contain only letters, digits and underscores: %s", name)
+	}
+	var version int64 = 1
+	for _, dialect := range dialects {
+		existing, err := load(os.DirFS(filepath.Join(dir, dialect)))
+		if err != nil {
+			return nil, err
+		}
+		if len(existing) > 0 && existing[len(existing)-1].Version >= version {
+			version = existing[len(existing)-1].Version + 1
+		}
+	}
+	for _, dialect := range dialects {
+		base := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s", version, name))
+		for _, f := range []string{base + ".up.sql", base + ".down.sql"} {
+			if err = os.WriteFile(f, []byte("-- "+filepath.Base(f)+"\n"), 0o644); err != nil {
+				return files, fmt.Errorf("error creating migration script: %w", err)
+			}
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,210 @@
+package migrations
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// testScripts returns scripts of two migrations, the second one may be changed by test
+func testScripts() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_notes.up.sql":     {Data: []byte("CREATE TABLE notes(id INTEGER PRIMARY KEY, text TEXT NOT NULL);")},
+		"0001_create_notes.down.sql":   {Data: []byte("DROP TABLE notes;")},
+		"0002_add_notes_tags.up.sql":   {Data: []byte("ALTER TABLE notes ADD COLUMN tags TEXT;")},
+		"0002_add_notes_tags.down.sql": {Data: []byte("ALTER TABLE notes DROP COLUMN tags;")},
+		"README.md":                    {Data: []byte("files which are not scripts are ignored")},
+	}
+}
+
+// openTestDB opens SQLite database file, every call returns a separate connection pool as service replicas have
+func openTestDB(t *testing.T, filename string) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sqlx.DB, scripts fstest.MapFS) *Migrator {
+	t.Helper()
+	m, err := NewMigrator(db, scripts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func versionsOf(migrations []*Migration) []int64 {
+	versions := make([]int64, len(migrations))
+	for i, mg := range migrations {
+		versions[i] = mg.Version
+	}
+	return versions
+}
+
+// appliedVersionsOf returns versions of applied migrations reported by Status
+func appliedVersionsOf(t *testing.T, m *Migrator) []int64 {
+	t.Helper()
+	statuses, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := []int64{}
+	for _, st := range statuses {
+		if st.AppliedAt != nil {
+			applied = append(applied, st.Version)
+		}
+	}
+	return applied
+}
+
+func TestUpStatusDown(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	m := newTestMigrator(t, db, testScripts())
+	if applied := appliedVersionsOf(t, m); len(applied) != 0 {
+		t.Fatalf("migrations %v are applied to empty database", applied)
+	}
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := versionsOf(done), []int64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("applied migrations are %v, want %v", got, want)
+	}
+	if _, err = db.Exec(`INSERT INTO notes(text, tags) VALUES ('note', 'a,b')`); err != nil {
+		t.Errorf("schema is not migrated: %v", err)
+	}
+	if done, err = m.Up(ctx); err != nil || len(done) != 0 {
+		t.Errorf("migrations %v are applied again, error %v", versionsOf(done), err)
+	}
+	if got, want := appliedVersionsOf(t, m), []int64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status reports applied migrations %v, want %v", got, want)
+	}
+
+	if done, err = m.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := versionsOf(done), []int64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("reverted migrations are %v, want %v", got, want)
+	}
+	if got, want := appliedVersionsOf(t, m), []int64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status reports applied migrations %v, want %v", got, want)
+	}
+	if _, err = db.Exec(`INSERT INTO notes(text, tags) VALUES ('note', 'a,b')`); err == nil {
+		t.Errorf("column of reverted migration is kept")
+	}
+	if done, err = m.Down(ctx, 5); err != nil || !reflect.DeepEqual(versionsOf(done), []int64{1}) {
+		t.Errorf("reverted migrations are %v, error %v, want [1]", versionsOf(done), err)
+	}
+}
+
+func TestFailedMigrationKeepsPreviousOnes(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	scripts := testScripts()
+	scripts["0002_add_notes_tags.up.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE missing ADD COLUMN tags TEXT;")}
+
+	done, err := newTestMigrator(t, db, scripts).Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "2_add_notes_tags") {
+		t.Errorf("failed migration is reported as %v", err)
+	}
+	if got, want := versionsOf(done), []int64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("applied migrations are %v, want %v", got, want)
+	}
+	if got, want := appliedVersionsOf(t, newTestMigrator(t, db, testScripts())), []int64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("status reports applied migrations %v, want %v", got, want)
+	}
+}
+
+func TestModifiedAndMissingMigrations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	if _, err := newTestMigrator(t, db, testScripts()).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := testScripts()
+	tampered["0001_create_notes.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE notes(id INTEGER PRIMARY KEY);")}
+	delete(tampered, "0002_add_notes_tags.up.sql")
+	delete(tampered, "0002_add_notes_tags.down.sql")
+	tampered["0003_add_notes_index.up.sql"] = &fstest.MapFile{Data: []byte("CREATE INDEX notes_text ON notes(text);")}
+	m := newTestMigrator(t, db, tampered)
+
+	done, err := m.Up(ctx)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("modified migration is reported as %v", err)
+	}
+	if len(done) != 0 {
+		t.Errorf("migrations %v are applied after modified one", versionsOf(done))
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]Status, len(statuses))
+	for i, st := range statuses {
+		got[i] = *st
+		got[i].AppliedAt = nil
+	}
+	want := []Status{
+		{Version: 1, Name: "create_notes", Modified: true},
+		{Version: 2, Name: "add_notes_tags", Missing: true},
+		{Version: 3, Name: "add_notes_index"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses are %+v, want %+v", got, want)
+	}
+}
+
+func TestMigrationLock(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.db")
+	first := newTestMigrator(t, openTestDB(t, filename), testScripts())
+	second := newTestMigrator(t, openTestDB(t, filename), testScripts())
+
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	firstDone := make(chan error)
+	go func() {
+		firstDone <- first.withLock(context.Background(), func(s *session) error {
+			close(locked)
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	type result struct {
+		done []*Migration
+		err  error
+	}
+	secondDone := make(chan result)
+	go func() {
+		done, err := second.Up(context.Background())
+		secondDone <- result{done, err}
+	}()
+	select {
+	case r := <-secondDone:
+		t.Fatalf("migrations %v are applied while lock is held, error %v", versionsOf(r.done), r.err)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-firstDone; err != nil {
+		t.Fatal(err)
+	}
+	r := <-secondDone
+	if r.err != nil || !reflect.DeepEqual(versionsOf(r.done), []int64{1, 2}) {
+		t.Errorf("migrations %v are applied after lock is released, error %v", versionsOf(r.done), r.err)
+	}
+}
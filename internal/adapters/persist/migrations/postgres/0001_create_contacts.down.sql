@@ -0,0 +1,2 @@
+DROP TABLE phones;
+DROP TABLE contacts;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS contacts(
+    id BIGSERIAL PRIMARY KEY,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS phones(
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    phone_number TEXT NOT NULL,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS contacts_search(
+    contact_id BIGINT PRIMARY KEY REFERENCES contacts(id),
+    document TSVECTOR NOT NULL
+);
+CREATE INDEX IF NOT EXISTS contacts_search_document_idx ON contacts_search USING GIN (document);
+
+-- index contacts created before full-text search was introduced,
+-- keep phone fragments in sync with indexContactSearchSql in repo package
+WITH RECURSIVE
+    phone_digits(contact_id, fragment) AS (
+        SELECT p.contact_id, regexp_replace(p.phone_number, '[^0-9]', '', 'g')
+        FROM phones p
+        WHERE p.contact_id NOT IN (SELECT contact_id FROM contacts_search)
+    ),
+    phone_fragments(contact_id, fragment) AS (
+        SELECT contact_id, fragment FROM phone_digits
+        UNION ALL
+        SELECT contact_id, substr(fragment, 2) FROM phone_fragments WHERE length(fragment) > 3
+    )
+INSERT INTO contacts_search(contact_id, document)
+SELECT c.id, to_tsvector('simple', c.first_name || ' ' || c.last_name || ' ' ||
+       COALESCE((SELECT string_agg(f.fragment, ' ') FROM phone_fragments f WHERE f.contact_id = c.id), ''))
+FROM contacts c
+WHERE c.id NOT IN (SELECT contact_id FROM contacts_search);
@@ -0,0 +1,4 @@
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+DROP INDEX contacts_tenant_first_name_idx;
+DROP INDEX contacts_tenant_last_name_idx;
+ALTER TABLE contacts DROP COLUMN tenant_id;
@@ -0,0 +1 @@
+ALTER TABLE phones DROP COLUMN phone_e164;
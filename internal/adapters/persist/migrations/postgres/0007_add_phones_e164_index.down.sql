@@ -0,0 +1 @@
+DROP INDEX phones_e164_idx;
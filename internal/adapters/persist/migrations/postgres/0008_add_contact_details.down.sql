@@ -0,0 +1,3 @@
+DROP TABLE urls;
+DROP TABLE addresses;
+DROP TABLE emails;
@@ -0,0 +1,2 @@
+DROP TABLE contact_group_members;
+DROP TABLE contact_groups;
@@ -0,0 +1,13 @@
+-- named groups of contacts of a tenant, contact may be a member of many groups
+CREATE TABLE IF NOT EXISTS contact_groups(
+    id BIGSERIAL PRIMARY KEY,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    UNIQUE (tenant_id, name)
+);
+CREATE TABLE IF NOT EXISTS contact_group_members(
+    group_id BIGINT NOT NULL REFERENCES contact_groups(id),
+    contact_id BIGINT NOT NULL REFERENCES contacts(id),
+    PRIMARY KEY (group_id, contact_id)
+);
+CREATE INDEX contact_group_members_contact_id_idx ON contact_group_members(contact_id);
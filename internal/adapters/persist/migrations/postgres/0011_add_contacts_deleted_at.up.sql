@@ -0,0 +1,3 @@
+-- time when contact was moved to trash, it is NULL for live contacts; trashed contacts are purged after retention period
+ALTER TABLE contacts ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX contacts_deleted_at_idx ON contacts(deleted_at);
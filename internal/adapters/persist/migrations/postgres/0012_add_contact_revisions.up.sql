@@ -0,0 +1,11 @@
+-- audit trail of contact changes, every revision keeps JSON snapshot of the contact right after the change
+CREATE TABLE IF NOT EXISTS contact_revisions(
+    id BIGSERIAL PRIMARY KEY,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id),
+    revision INTEGER NOT NULL, -- 1, 2, 3... for every contact
+    action TEXT NOT NULL, -- created, updated, deleted or restored
+    actor TEXT NOT NULL, -- subject of the caller who made the change
+    changed_at TIMESTAMP NOT NULL,
+    snapshot TEXT NOT NULL,
+    UNIQUE (contact_id, revision)
+);
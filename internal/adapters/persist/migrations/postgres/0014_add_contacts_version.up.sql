@@ -0,0 +1,2 @@
+-- incremented on every change of the contact, it is exposed as ETag for optimistic concurrency control
+ALTER TABLE contacts ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS contacts(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    first_name TEXT NOT NULL,
+    last_name TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS phones(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    type TEXT NOT NULL,
+    phone_number TEXT NOT NULL,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id)
+);
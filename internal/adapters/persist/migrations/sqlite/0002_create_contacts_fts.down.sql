@@ -0,0 +1 @@
+DROP TABLE contacts_fts;
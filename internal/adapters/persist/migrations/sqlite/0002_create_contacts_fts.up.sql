@@ -0,0 +1,26 @@
+CREATE VIRTUAL TABLE IF NOT EXISTS contacts_fts USING fts5(
+    first_name,
+    last_name,
+    phones
+);
+
+-- index contacts created before full-text search was introduced,
+-- keep phone fragments in sync with indexContactSearchSql in repo package
+WITH RECURSIVE
+    phone_digits(contact_id, fragment) AS (
+        SELECT p.contact_id,
+               replace(replace(replace(replace(replace(replace(
+                   p.phone_number, '-', ''), ' ', ''), '(', ''), ')', ''), '+', ''), '.', '')
+        FROM phones p
+        WHERE p.contact_id NOT IN (SELECT rowid FROM contacts_fts)
+    ),
+    phone_fragments(contact_id, fragment) AS (
+        SELECT contact_id, fragment FROM phone_digits
+        UNION ALL
+        SELECT contact_id, substr(fragment, 2) FROM phone_fragments WHERE length(fragment) > 3
+    )
+INSERT INTO contacts_fts(rowid, first_name, last_name, phones)
+SELECT c.id, c.first_name, c.last_name,
+       COALESCE((SELECT group_concat(f.fragment, ' ') FROM phone_fragments f WHERE f.contact_id = c.id), '')
+FROM contacts c
+WHERE c.id NOT IN (SELECT rowid FROM contacts_fts);
@@ -0,0 +1,6 @@
+-- every contact belongs to address book of a tenant (user or organisation),
+-- contacts and API keys created before tenants were introduced belong to 'default' tenant
+ALTER TABLE contacts ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+CREATE INDEX contacts_tenant_last_name_idx ON contacts(tenant_id, last_name, first_name, id);
+CREATE INDEX contacts_tenant_first_name_idx ON contacts(tenant_id, first_name, last_name, id);
+ALTER TABLE api_keys ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
@@ -0,0 +1,2 @@
+-- keys issued before roles were introduced keep full access to contacts
+ALTER TABLE api_keys ADD COLUMN role TEXT NOT NULL DEFAULT 'editor';
@@ -0,0 +1,2 @@
+-- phone number in E.164 format, e.g. +15035557777, it is NULL for phones saved before numbers were normalized
+ALTER TABLE phones ADD COLUMN phone_e164 TEXT;
@@ -0,0 +1,2 @@
+-- reverse lookup of contacts by phone number
+CREATE INDEX phones_e164_idx ON phones(phone_e164);
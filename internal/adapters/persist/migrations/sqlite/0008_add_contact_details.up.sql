@@ -0,0 +1,26 @@
+-- email addresses, postal addresses and URLs of contacts, labels are optional free text (e.g. 'work')
+CREATE TABLE IF NOT EXISTS emails(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    label TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id)
+);
+CREATE INDEX emails_contact_id_idx ON emails(contact_id);
+CREATE TABLE IF NOT EXISTS addresses(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    label TEXT NOT NULL DEFAULT '',
+    street TEXT NOT NULL DEFAULT '',
+    city TEXT NOT NULL DEFAULT '',
+    region TEXT NOT NULL DEFAULT '',
+    postal_code TEXT NOT NULL DEFAULT '',
+    country TEXT NOT NULL DEFAULT '',
+    contact_id BIGINT NOT NULL REFERENCES contacts(id)
+);
+CREATE INDEX addresses_contact_id_idx ON addresses(contact_id);
+CREATE TABLE IF NOT EXISTS urls(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    label TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id)
+);
+CREATE INDEX urls_contact_id_idx ON urls(contact_id);
@@ -0,0 +1,19 @@
+-- custom contact fields defined by tenants, enum_values is a JSON array of allowed values of enum field
+CREATE TABLE IF NOT EXISTS custom_fields(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    tenant_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    enum_values TEXT NOT NULL DEFAULT '[]',
+    UNIQUE (tenant_id, name)
+);
+-- values of custom fields kept in canonical text form, e.g. '12.5', 'true' or '2024-01-31'
+CREATE TABLE IF NOT EXISTS custom_field_values(
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    field_id BIGINT NOT NULL REFERENCES custom_fields(id),
+    value TEXT NOT NULL,
+    contact_id BIGINT NOT NULL REFERENCES contacts(id),
+    UNIQUE (contact_id, field_id)
+);
+CREATE INDEX custom_field_values_field_value_idx ON custom_field_values(field_id, value);
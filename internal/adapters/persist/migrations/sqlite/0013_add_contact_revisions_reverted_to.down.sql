@@ -0,0 +1 @@
+ALTER TABLE contact_revisions DROP COLUMN reverted_to;
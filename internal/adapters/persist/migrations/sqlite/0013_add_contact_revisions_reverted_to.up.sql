@@ -0,0 +1,2 @@
+-- number of the revision the contact was reverted to, NULL for other actions
+ALTER TABLE contact_revisions ADD COLUMN reverted_to INTEGER;
@@ -0,0 +1,32 @@
+package persist
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"go.uber.org/zap"
+)
+
+// normalizePhones fills E.164 numbers of phones saved before numbers were normalized, so that their contacts are
+// found by phone number and checked for duplicate phones. Numbers without country code belong to the default region.
+// It is done whenever service starts, but only phones without E.164 number are checked.
+func normalizePhones(db *sqlx.DB, defaultRegion string) {
+	normalized, failed, err := repo.NormalizePhones(context.Background(), db, func(number string) string {
+		e164, err := model.NormalizePhoneNumber(number, defaultRegion)
+		if err != nil {
+			return ""
+		}
+		return e164
+	})
+	if err != nil {
+		zap.S().Fatalln("failed to normalize phone numbers:", err)
+	}
+	if normalized > 0 {
+		zap.S().Infof("%d phone number(s) saved before numbers were normalized are normalized now", normalized)
+	}
+	if failed > 0 {
+		zap.S().Warnf("%d phone number(s) cannot be normalized (phones.default_region=%q), "+
+			"they are not found by phone number until their contacts are updated", failed, defaultRegion)
+	}
+}
@@ -0,0 +1,24 @@
+package persist
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/internal/repo"
+	"go.uber.org/zap"
+)
+
+// missingRevisionsActor is recorded as the actor of revisions made for contacts saved before revisions were recorded
+const missingRevisionsActor = "system"
+
+// recordMissingRevisions records the first revision of contacts saved before revisions were recorded, so that
+// their history is not empty and they are found as of the current time. It is done whenever service starts,
+// but only contacts without revisions are recorded.
+func recordMissingRevisions(db *sqlx.DB) {
+	recorded, err := repo.NewAddrBookRepo(db).InsertMissingRevisions(context.Background(), missingRevisionsActor)
+	if err != nil {
+		zap.S().Fatalln("failed to record revisions of contacts:", err)
+	}
+	if recorded > 0 {
+		zap.S().Infof("%d contact(s) saved before revisions were recorded got their first revision", recorded)
+	}
+}
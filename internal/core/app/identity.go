@@ -0,0 +1,37 @@
+package app
+
+import "context"
+
+// DefaultTenant owns contacts created before address books became tenant scoped, as well as contacts
+// of callers authenticated by API keys issued without explicit tenant
+const DefaultTenant = "default"
+
+// Identity describes authenticated caller of the service
+type Identity struct {
+	Subject  string         // "sub" claim of JWT or "apikey:<id>" for API key callers
+	Tenant   string         // owner (user or organisation) of address book the caller works with
+	Roles    []string       // roles granted to the caller, see model.Role
+	APIKeyID string         // set only for callers authenticated by API key
+	Claims   map[string]any // all verified JWT claims, empty for API key callers
+}
+
+type identityContextKey struct{}
+
+func ContextWithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// CallerIdentity returns identity of authenticated caller or nil if context belongs to anonymous
+// request or to background job
+func CallerIdentity(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(identityContextKey{}).(*Identity)
+	return identity
+}
+
+// Tenant returns tenant of the caller, or empty string if context has no caller identity
+func Tenant(ctx context.Context) string {
+	if identity := CallerIdentity(ctx); identity != nil {
+		return identity.Tenant
+	}
+	return ""
+}
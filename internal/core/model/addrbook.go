@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type ContactPhoneType string
 
 const (
@@ -13,20 +15,131 @@ type Contact struct {
 	FirstName string
 	LastName  string
 	Phones    []*ContactPhone
+	Emails    []*ContactEmail
+	Addresses []*ContactAddress
+	URLs      []*ContactURL
+	// CustomFields are values of custom fields defined by tenant, values are string, float64 or bool
+	CustomFields map[string]any
+	Groups       []*GroupRef
+	DeletedAt    *time.Time // time when contact was moved to trash, nil for contacts which are not in trash
+	// Version is changed on every change of the contact, it is zero for contacts loaded from revisions and lists
+	Version int
 }
 
 type ContactPhone struct {
-	PhoneType   ContactPhoneType
-	PhoneNumber string
+	PhoneType       ContactPhoneType
+	PhoneNumber     string // as it was entered
+	PhoneNumberE164 string // empty for phones saved before numbers were normalized
 }
 
 type ContactToSave struct {
 	FirstName string
 	LastName  string
 	Phones    []*ContactPhoneToSave
+	Emails    []*ContactEmail
+	Addresses []*ContactAddress
+	URLs      []*ContactURL
+	// CustomFields are values of custom fields by field name as they were decoded from JSON
+	CustomFields map[string]any
+	// CustomFieldValues are validated CustomFields set by NormalizeCustomFields
+	CustomFieldValues []*CustomFieldValue
+	// Version is a version the contact must have to be saved, otherwise ErrPreconditionFailed is returned.
+	// Zero saves contact of any version, negative version never matches.
+	Version int
+}
+
+// ContactPatch changes the contact, it is applied by PatchAddrBookContact use case to the current state of the contact
+type ContactPatch interface {
+	// Apply returns patched contact to save, ValidationError or ErrConflict is returned if patch cannot be applied
+	Apply(contact *Contact) (*ContactToSave, error)
+}
+
+// ToSave returns contact to save with the same names, phones, details and custom field values as the contact has.
+// It is saved only if the contact still has the same version.
+func (c *Contact) ToSave() *ContactToSave {
+	save := &ContactToSave{
+		Version:      c.Version,
+		FirstName:    c.FirstName,
+		LastName:     c.LastName,
+		Phones:       make([]*ContactPhoneToSave, len(c.Phones)),
+		Emails:       make([]*ContactEmail, len(c.Emails)),
+		Addresses:    make([]*ContactAddress, len(c.Addresses)),
+		URLs:         make([]*ContactURL, len(c.URLs)),
+		CustomFields: make(map[string]any, len(c.CustomFields)),
+	}
+	for i, ph := range c.Phones {
+		save.Phones[i] = &ContactPhoneToSave{PhoneType: ph.PhoneType, PhoneNumber: ph.PhoneNumber}
+	}
+	for i, email := range c.Emails {
+		e := *email
+		save.Emails[i] = &e
+	}
+	for i, address := range c.Addresses {
+		a := *address
+		save.Addresses[i] = &a
+	}
+	for i, url := range c.URLs {
+		u := *url
+		save.URLs[i] = &u
+	}
+	for name, value := range c.CustomFields {
+		save.CustomFields[name] = value
+	}
+	return save
 }
 
 type ContactPhoneToSave struct {
-	PhoneType   ContactPhoneType
-	PhoneNumber string
+	PhoneType       ContactPhoneType
+	PhoneNumber     string // as it was entered
+	PhoneNumberE164 string // set by ContactToSave.NormalizePhones
+}
+
+// ContactEmail is an email address of the contact, label is optional free text such as "work" or "personal"
+type ContactEmail struct {
+	Label   string
+	Address string
+}
+
+// ContactAddress is a postal address of the contact, every part of it (but not all of them) may be empty
+type ContactAddress struct {
+	Label      string
+	Street     string
+	City       string
+	Region     string // state, province or county
+	PostalCode string
+	Country    string
+}
+
+// ContactURL is a web page of the contact, e.g. a homepage or a profile in a social network
+type ContactURL struct {
+	Label string
+	URL   string
+}
+
+type ContactSortField string
+
+const (
+	ContactSortByLastName  ContactSortField = "LAST_NAME"
+	ContactSortByFirstName ContactSortField = "FIRST_NAME"
+)
+
+// ErrInvalidCursor is returned when pagination cursor cannot be decoded or does not match the requested sort order
+var ErrInvalidCursor = NewValidationError("cursor", "invalid pagination cursor")
+
+// ContactListQuery describes a single page of contacts to be loaded
+type ContactListQuery struct {
+	Limit          int
+	Cursor         string // opaque cursor taken from ContactListPage.NextCursor, empty for the first page
+	SortBy         ContactSortField
+	SortDesc       bool
+	LastNamePrefix string
+	PhoneType      *ContactPhoneType
+	CustomField    *CustomFieldFilter
+	GroupID        string // selects members of the group
+}
+
+type ContactListPage struct {
+	Contacts   []*Contact
+	NextCursor string // empty when there are no more pages
+	TotalCount int    // total number of contacts matching query filters
 }
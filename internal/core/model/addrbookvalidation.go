@@ -0,0 +1,238 @@
+package model
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxContactNameLength = 100 // characters
+	MaxContactPhones     = 20
+	MaxPhoneNumberLength = 30 // characters including separators
+	MaxContactEmails     = 20
+	MaxEmailLength       = 254 // RFC 5321 limit
+	MaxContactAddresses  = 10
+	MaxAddressPartLength = 200 // characters of every address part (street, city etc.)
+	MaxContactURLs       = 20
+	MaxURLLength         = 2048
+	MaxLabelLength       = 50 // characters of email, address and URL labels
+)
+
+// Validate checks contact against address book rules and returns *ValidationError listing all violations,
+// or nil if contact is valid. Field paths are the same as field names of REST API, e.g. "phones[1].phone_number".
+// Phone numbers without country code are validated as numbers of the default region, custom fields are validated
+// against custom field definitions of the tenant.
+func (c *ContactToSave) Validate(defaultPhoneRegion string, customFields []*CustomFieldDefinition) error {
+	v := &ValidationError{}
+	validateContactName(v, "first_name", c.FirstName)
+	validateContactName(v, "last_name", c.LastName)
+	if len(c.Phones) > MaxContactPhones {
+		v.Add("phones", fmt.Sprintf("must contain at most %d phones", MaxContactPhones))
+	}
+	seen := make(map[string]int) // E.164 phone number -> index of the first phone with this number
+	for i, phone := range c.Phones {
+		path := fmt.Sprintf("phones[%d]", i)
+		if phone == nil {
+			v.Add(path, "must not be null")
+			continue
+		}
+		if !phone.PhoneType.IsValid() {
+			v.Add(path+".phone_type", fmt.Sprintf("unsupported phone type %q", string(phone.PhoneType)))
+		}
+		if !validatePhoneNumber(v, path+".phone_number", phone.PhoneNumber) {
+			continue
+		}
+		e164, err := NormalizePhoneNumber(phone.PhoneNumber, defaultPhoneRegion)
+		if err != nil {
+			v.Add(path+".phone_number", err.Error())
+		} else if first, ok := seen[e164]; ok {
+			v.Add(path+".phone_number", fmt.Sprintf("duplicates phones[%d].phone_number", first))
+		} else {
+			seen[e164] = i
+		}
+	}
+	validateContactEmails(v, c.Emails)
+	validateContactAddresses(v, c.Addresses)
+	validateContactURLs(v, c.URLs)
+	validateCustomFields(v, c.CustomFields, customFields)
+	if len(v.Violations) > 0 {
+		return v
+	}
+	return nil
+}
+
+func (t ContactPhoneType) IsValid() bool {
+	switch t {
+	case ContactPhoneTypeMobile, ContactPhoneTypeHome, ContactPhoneTypeWork:
+		return true
+	default:
+		return false
+	}
+}
+
+// validateContactName allows letters (of any script) separated by spaces, apostrophes, hyphens and dots,
+// e.g. "Mary-Jane", "O'Neil" or "Jr."
+func validateContactName(v *ValidationError, field string, name string) {
+	if strings.TrimSpace(name) == "" {
+		v.Add(field, "must not be empty")
+		return
+	}
+	if utf8.RuneCountInString(name) > MaxContactNameLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters long", MaxContactNameLength))
+	}
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsMark(r) && !strings.ContainsRune(" '’-.", r) {
+			v.Add(field, fmt.Sprintf("must contain only letters, spaces, apostrophes, hyphens and dots, found %q", r))
+			return
+		}
+	}
+}
+
+// validatePhoneNumber allows digits with optional leading "+" and separators, e.g. "+1 (503) 555-7777".
+// It returns true if the number is valid.
+func validatePhoneNumber(v *ValidationError, field string, number string) bool {
+	if strings.TrimSpace(number) == "" {
+		v.Add(field, "must not be empty")
+		return false
+	}
+	if utf8.RuneCountInString(number) > MaxPhoneNumberLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters long", MaxPhoneNumberLength))
+		return false
+	}
+	for i, r := range strings.TrimSpace(number) {
+		if !('0' <= r && r <= '9') && !strings.ContainsRune(" -().", r) && !(r == '+' && i == 0) {
+			v.Add(field, fmt.Sprintf("must contain only digits, spaces, hyphens, dots, brackets and leading plus, found %q", r))
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizePhones sets E.164 form of every phone number, contact must be validated first
+func (c *ContactToSave) NormalizePhones(defaultPhoneRegion string) error {
+	for i, phone := range c.Phones {
+		e164, err := NormalizePhoneNumber(phone.PhoneNumber, defaultPhoneRegion)
+		if err != nil {
+			return NewValidationError(fmt.Sprintf("phones[%d].phone_number", i), err.Error())
+		}
+		phone.PhoneNumberE164 = e164
+	}
+	return nil
+}
+
+func validateContactEmails(v *ValidationError, emails []*ContactEmail) {
+	if len(emails) > MaxContactEmails {
+		v.Add("emails", fmt.Sprintf("must contain at most %d emails", MaxContactEmails))
+	}
+	seen := make(map[string]int) // lower case email address -> index of the first email with this address
+	for i, email := range emails {
+		path := fmt.Sprintf("emails[%d]", i)
+		if email == nil {
+			v.Add(path, "must not be null")
+			continue
+		}
+		validateLabel(v, path+".label", email.Label)
+		if !validateEmailAddress(v, path+".address", email.Address) {
+			continue
+		}
+		key := strings.ToLower(email.Address)
+		if first, ok := seen[key]; ok {
+			v.Add(path+".address", fmt.Sprintf("duplicates emails[%d].address", first))
+		} else {
+			seen[key] = i
+		}
+	}
+}
+
+// validateEmailAddress allows bare addresses only, e.g. "joe@example.com" but not "Joe <joe@example.com>".
+// It returns true if the address is valid.
+func validateEmailAddress(v *ValidationError, field string, address string) bool {
+	if strings.TrimSpace(address) == "" {
+		v.Add(field, "must not be empty")
+		return false
+	}
+	if len(address) > MaxEmailLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters long", MaxEmailLength))
+		return false
+	}
+	if parsed, err := mail.ParseAddress(address); err != nil || parsed.Address != address {
+		v.Add(field, "is not a valid email address")
+		return false
+	}
+	return true
+}
+
+func validateContactAddresses(v *ValidationError, addresses []*ContactAddress) {
+	if len(addresses) > MaxContactAddresses {
+		v.Add("addresses", fmt.Sprintf("must contain at most %d addresses", MaxContactAddresses))
+	}
+	for i, address := range addresses {
+		path := fmt.Sprintf("addresses[%d]", i)
+		if address == nil {
+			v.Add(path, "must not be null")
+			continue
+		}
+		validateLabel(v, path+".label", address.Label)
+		parts := []struct{ field, value string }{
+			{"street", address.Street},
+			{"city", address.City},
+			{"region", address.Region},
+			{"postal_code", address.PostalCode},
+			{"country", address.Country},
+		}
+		empty := true
+		for _, part := range parts {
+			if strings.TrimSpace(part.value) != "" {
+				empty = false
+			}
+			if utf8.RuneCountInString(part.value) > MaxAddressPartLength {
+				v.Add(path+"."+part.field, fmt.Sprintf("must be at most %d characters long", MaxAddressPartLength))
+			}
+		}
+		if empty {
+			v.Add(path, "must have at least one of street, city, region, postal_code or country")
+		}
+	}
+}
+
+func validateContactURLs(v *ValidationError, urls []*ContactURL) {
+	if len(urls) > MaxContactURLs {
+		v.Add("urls", fmt.Sprintf("must contain at most %d URLs", MaxContactURLs))
+	}
+	for i, u := range urls {
+		path := fmt.Sprintf("urls[%d]", i)
+		if u == nil {
+			v.Add(path, "must not be null")
+			continue
+		}
+		validateLabel(v, path+".label", u.Label)
+		validateURL(v, path+".url", u.URL)
+	}
+}
+
+// validateURL allows absolute http and https URLs only, e.g. "https://example.com/joe"
+func validateURL(v *ValidationError, field string, rawURL string) {
+	if strings.TrimSpace(rawURL) == "" {
+		v.Add(field, "must not be empty")
+		return
+	}
+	if len(rawURL) > MaxURLLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters long", MaxURLLength))
+		return
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.Add(field, "must be an absolute http or https URL")
+	}
+}
+
+// validateLabel allows empty labels
+func validateLabel(v *ValidationError, field string, label string) {
+	if utf8.RuneCountInString(label) > MaxLabelLength {
+		v.Add(field, fmt.Sprintf("must be at most %d characters long", MaxLabelLength))
+	}
+}
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// validTestContact returns contact which passes validation, test cases break it
+func validTestContact() *ContactToSave {
+	return &ContactToSave{
+		FirstName: "Mary-Jane",
+		LastName:  "O'Neil",
+		Phones:    []*ContactPhoneToSave{{PhoneType: ContactPhoneTypeMobile, PhoneNumber: "+1 (503) 555-7777"}},
+		Emails:    []*ContactEmail{{Label: "work", Address: "mj@example.com"}},
+		Addresses: []*ContactAddress{{Label: "home", City: "Portland"}},
+		URLs:      []*ContactURL{{Label: "blog", URL: "https://mj.example.com"}},
+	}
+}
+
+// violatedFields returns fields of all violations listed by validation error, nil if there is no error
+func violatedFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var v *ValidationError
+	if !errors.As(err, &v) || !errors.Is(err, ErrValidation) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fields := make([]string, len(v.Violations))
+	for i, violation := range v.Violations {
+		fields[i] = violation.Field
+	}
+	return fields
+}
+
+func TestValidateContact(t *testing.T) {
+	phones := func(numbers ...string) []*ContactPhoneToSave {
+		list := make([]*ContactPhoneToSave, len(numbers))
+		for i, number := range numbers {
+			list[i] = &ContactPhoneToSave{PhoneType: ContactPhoneTypeHome, PhoneNumber: number}
+		}
+		return list
+	}
+	tests := []struct {
+		name       string
+		change     func(c *ContactToSave)
+		wantFields []string
+	}{
+		{
+			name:   "valid",
+			change: func(*ContactToSave) {},
+		},
+		{
+			name: "names of any script",
+			change: func(c *ContactToSave) {
+				c.FirstName, c.LastName = "Zoë", "Петров-Водкин Jr."
+			},
+		},
+		{
+			name:       "empty names",
+			change:     func(c *ContactToSave) { c.FirstName, c.LastName = "", "  " },
+			wantFields: []string{"first_name", "last_name"},
+		},
+		{
+			name:   "name of max length",
+			change: func(c *ContactToSave) { c.FirstName = strings.Repeat("ä", MaxContactNameLength) },
+		},
+		{
+			name:       "too long name",
+			change:     func(c *ContactToSave) { c.LastName = strings.Repeat("a", MaxContactNameLength+1) },
+			wantFields: []string{"last_name"},
+		},
+		{
+			name:       "name with digits",
+			change:     func(c *ContactToSave) { c.FirstName = "R2D2" },
+			wantFields: []string{"first_name"},
+		},
+		{
+			name:       "name with punctuation",
+			change:     func(c *ContactToSave) { c.LastName = "Doe!" },
+			wantFields: []string{"last_name"},
+		},
+		{
+			name:   "max phones",
+			change: func(c *ContactToSave) { c.Phones = phones(distinctTestPhones(MaxContactPhones)...) },
+		},
+		{
+			name:       "too many phones",
+			change:     func(c *ContactToSave) { c.Phones = phones(distinctTestPhones(MaxContactPhones + 1)...) },
+			wantFields: []string{"phones"},
+		},
+		{
+			name: "invalid phone type",
+			change: func(c *ContactToSave) {
+				c.Phones = append(c.Phones, &ContactPhoneToSave{PhoneType: "FAX", PhoneNumber: "503-555-1111"})
+			},
+			wantFields: []string{"phones[1].phone_type"},
+		},
+		{
+			name:       "phone with letters",
+			change:     func(c *ContactToSave) { c.Phones = phones("503-555-7777", "1-800-FLOWERS") },
+			wantFields: []string{"phones[1].phone_number"},
+		},
+		{
+			name:       "phone with plus in the middle",
+			change:     func(c *ContactToSave) { c.Phones = phones("503+555-7777") },
+			wantFields: []string{"phones[0].phone_number"},
+		},
+		{
+			name: "too long phone",
+			change: func(c *ContactToSave) {
+				c.Phones = phones("+1 503 555 7777" + strings.Repeat(" ", MaxPhoneNumberLength))
+			},
+			wantFields: []string{"phones[0].phone_number"},
+		},
+		{
+			name:       "empty and null phones",
+			change:     func(c *ContactToSave) { c.Phones = append(phones(""), nil) },
+			wantFields: []string{"phones[0].phone_number", "phones[1]"},
+		},
+		{
+			name:       "impossible phone",
+			change:     func(c *ContactToSave) { c.Phones = phones("555") },
+			wantFields: []string{"phones[0].phone_number"},
+		},
+		{
+			name:       "phones duplicated in different formats",
+			change:     func(c *ContactToSave) { c.Phones = phones("503-555-1111", "+1 503 555 7777", "(503) 5551111") },
+			wantFields: []string{"phones[2].phone_number"},
+		},
+		{
+			name: "duplicated email",
+			change: func(c *ContactToSave) {
+				c.Emails = append(c.Emails, &ContactEmail{Address: "MJ@example.com"})
+			},
+			wantFields: []string{"emails[1].address"},
+		},
+		{
+			name: "email with display name",
+			change: func(c *ContactToSave) {
+				c.Emails = []*ContactEmail{{Address: "Mary <mj@example.com>"}}
+			},
+			wantFields: []string{"emails[0].address"},
+		},
+		{
+			name: "too many emails",
+			change: func(c *ContactToSave) {
+				c.Emails = make([]*ContactEmail, MaxContactEmails+1)
+				for i := range c.Emails {
+					c.Emails[i] = &ContactEmail{Address: strings.Repeat("a", i+1) + "@example.com"}
+				}
+			},
+			wantFields: []string{"emails"},
+		},
+		{
+			name: "too long label",
+			change: func(c *ContactToSave) {
+				c.Emails[0].Label = strings.Repeat("a", MaxLabelLength+1)
+			},
+			wantFields: []string{"emails[0].label"},
+		},
+		{
+			name: "empty address",
+			change: func(c *ContactToSave) {
+				c.Addresses = append(c.Addresses, &ContactAddress{Label: "work", Street: " "})
+			},
+			wantFields: []string{"addresses[1]"},
+		},
+		{
+			name: "too long address part",
+			change: func(c *ContactToSave) {
+				c.Addresses[0].PostalCode = strings.Repeat("9", MaxAddressPartLength+1)
+			},
+			wantFields: []string{"addresses[0].postal_code"},
+		},
+		{
+			name: "too many addresses",
+			change: func(c *ContactToSave) {
+				for len(c.Addresses) <= MaxContactAddresses {
+					c.Addresses = append(c.Addresses, &ContactAddress{City: "Salem"})
+				}
+			},
+			wantFields: []string{"addresses"},
+		},
+		{
+			name: "not http URLs",
+			change: func(c *ContactToSave) {
+				c.URLs = []*ContactURL{{URL: "ftp://example.com"}, {URL: "example.com/mj"}}
+			},
+			wantFields: []string{"urls[0].url", "urls[1].url"},
+		},
+		{
+			name: "too many URLs",
+			change: func(c *ContactToSave) {
+				for len(c.URLs) <= MaxContactURLs {
+					c.URLs = append(c.URLs, &ContactURL{URL: "https://example.com"})
+				}
+			},
+			wantFields: []string{"urls"},
+		},
+		{
+			name: "all violations are listed",
+			change: func(c *ContactToSave) {
+				c.FirstName = ""
+				c.Phones = phones("abc")
+				c.URLs[0].URL = ""
+			},
+			wantFields: []string{"first_name", "phones[0].phone_number", "urls[0].url"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validTestContact()
+			tt.change(c)
+			got := violatedFields(t, c.Validate("US", nil))
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("violated fields are %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+// distinctTestPhones returns count of different valid phone numbers of US
+func distinctTestPhones(count int) []string {
+	numbers := make([]string, count)
+	for i := range numbers {
+		numbers[i] = fmt.Sprintf("503-555-%04d", i)
+	}
+	return numbers
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// APIKey describes API key issued to a client, the key itself is never stored, only its hash
+type APIKey struct {
+	ID        string
+	Name      string
+	Tenant    string // owner of address book available to the key holder
+	Role      Role
+	Prefix    string // first characters of the key, helps to tell keys apart
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
+
+type APIKeyToSave struct {
+	Name    string
+	Tenant  string
+	Role    Role
+	Prefix  string
+	KeyHash string
+}
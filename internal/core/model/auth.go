@@ -0,0 +1,44 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthenticated is returned when caller credentials are missing or invalid
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// ErrInvalidToken is returned when bearer token is malformed, expired, not signed by trusted key
+// or issued for another issuer or audience
+var ErrInvalidToken = fmt.Errorf("%w: invalid bearer token", ErrUnauthenticated)
+
+// ErrForbidden is returned when the caller has no role required to perform the operation
+var ErrForbidden = errors.New("operation is forbidden")
+
+// Role grants permissions to the caller, every role includes permissions of the roles below it
+type Role string
+
+const (
+	RoleReader Role = "reader" // can load and search contacts
+	RoleEditor Role = "editor" // can also add, update and delete contacts
+	RoleAdmin  Role = "admin"  // can also manage API keys
+)
+
+var roleRanks = map[Role]int{
+	RoleReader: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+func ParseRole(s string) (Role, error) {
+	if _, ok := roleRanks[Role(s)]; !ok {
+		return "", fmt.Errorf("unknown role %q, supported roles are reader, editor and admin", s)
+	}
+	return Role(s), nil
+}
+
+// Includes tells whether the role grants all permissions of the other role. Unknown roles grant nothing.
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[other]
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MaxContactBatchSize limits number of operations in a single batch
+const MaxContactBatchSize = 1000
+
+type ContactOperationType string
+
+const (
+	ContactOperationCreate ContactOperationType = "create"
+	ContactOperationUpdate ContactOperationType = "update"
+	ContactOperationDelete ContactOperationType = "delete"
+)
+
+// ContactBatch is a list of contact changes applied by a single request
+type ContactBatch struct {
+	Operations []*ContactOperation
+	// Atomic batch is applied in a single transaction, so either all its operations are applied or none is,
+	// otherwise every operation is applied on its own
+	Atomic bool
+}
+
+type ContactOperation struct {
+	Type    ContactOperationType
+	ID      string         // id of updated or deleted contact
+	Contact *ContactToSave // created or updated contact
+	// Version the updated or deleted contact must have, zero changes contact of any version
+	Version int
+}
+
+// ContactOperationResult is either a contact which was created or updated by the operation or an error
+type ContactOperationResult struct {
+	Contact *Contact // nil for deleted contact
+	Err     error
+}
+
+// ErrNotApplied is returned for operations of atomic batch which were not applied because another operation failed
+var ErrNotApplied = errors.New("not applied")
+
+// Validate checks that every operation has the fields its type requires, contacts are validated separately
+func (b *ContactBatch) Validate() error {
+	if len(b.Operations) == 0 {
+		return NewValidationError("operations", "must not be empty")
+	}
+	if len(b.Operations) > MaxContactBatchSize {
+		return NewValidationError("operations", fmt.Sprintf("must contain at most %d operations", MaxContactBatchSize))
+	}
+	v := &ValidationError{}
+	for i, op := range b.Operations {
+		field := fmt.Sprintf("operations[%d]", i)
+		switch op.Type {
+		case ContactOperationCreate:
+			if op.Contact == nil {
+				v.Add(field+".contact", "must not be empty")
+			}
+		case ContactOperationUpdate, ContactOperationDelete:
+			if op.ID == "" {
+				v.Add(field+".id", "must not be empty")
+			}
+			if op.Type == ContactOperationUpdate && op.Contact == nil {
+				v.Add(field+".contact", "must not be empty")
+			}
+		default:
+			v.Add(field+".op", fmt.Sprintf("unsupported operation %q", op.Type))
+		}
+	}
+	if len(v.Violations) > 0 {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+type CustomFieldType string
+
+const (
+	CustomFieldTypeString  CustomFieldType = "string"
+	CustomFieldTypeNumber  CustomFieldType = "number"
+	CustomFieldTypeBoolean CustomFieldType = "boolean"
+	CustomFieldTypeDate    CustomFieldType = "date" // calendar date such as "2024-01-31"
+	CustomFieldTypeEnum    CustomFieldType = "enum" // one of CustomFieldDefinition.EnumValues
+)
+
+const (
+	MaxCustomFields           = 50 // field definitions per tenant
+	MaxCustomFieldEnumValues  = 50
+	MaxCustomFieldValueLength = 500 // characters of string and enum values
+	customFieldDateLayout     = "2006-01-02"
+)
+
+// customFieldNameRegexp allows names that can be used as JSON keys and URL query parameters without escaping
+var customFieldNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// CustomFieldDefinition describes a field that tenant added to its contacts, e.g. "account_manager"
+type CustomFieldDefinition struct {
+	ID         string
+	Name       string
+	Type       CustomFieldType
+	Required   bool
+	EnumValues []string // allowed values of enum field, empty for other types
+}
+
+type CustomFieldDefinitionToSave struct {
+	Name       string
+	Type       CustomFieldType
+	Required   bool
+	EnumValues []string
+}
+
+// CustomFieldValue is a value of the custom field of the contact, the value is kept in canonical text form
+type CustomFieldValue struct {
+	FieldID string
+	Value   string
+}
+
+// CustomFieldFilter selects contacts having the custom field equal to the value
+type CustomFieldFilter struct {
+	Name    string
+	Value   string // as it was given in query
+	FieldID string // set by use case
+}
+
+func (t CustomFieldType) IsValid() bool {
+	switch t {
+	case CustomFieldTypeString, CustomFieldTypeNumber, CustomFieldTypeBoolean, CustomFieldTypeDate, CustomFieldTypeEnum:
+		return true
+	default:
+		return false
+	}
+}
+
+// Validate checks field definition and returns *ValidationError listing all violations, or nil if it is valid
+func (d *CustomFieldDefinitionToSave) Validate() error {
+	v := &ValidationError{}
+	if !customFieldNameRegexp.MatchString(d.Name) {
+		v.Add("name", "must start with a lower case letter and contain only lower case letters, digits and "+
+			"underscores, at most 50 characters")
+	}
+	if !d.Type.IsValid() {
+		v.Add("type", fmt.Sprintf("unsupported field type %q", string(d.Type)))
+	}
+	if d.Type == CustomFieldTypeEnum {
+		if len(d.EnumValues) == 0 {
+			v.Add("enum_values", "must not be empty for enum field")
+		} else if len(d.EnumValues) > MaxCustomFieldEnumValues {
+			v.Add("enum_values", fmt.Sprintf("must contain at most %d values", MaxCustomFieldEnumValues))
+		}
+		seen := make(map[string]int)
+		for i, value := range d.EnumValues {
+			path := fmt.Sprintf("enum_values[%d]", i)
+			if strings.TrimSpace(value) == "" {
+				v.Add(path, "must not be empty")
+			} else if utf8.RuneCountInString(value) > MaxCustomFieldValueLength {
+				v.Add(path, fmt.Sprintf("must be at most %d characters long", MaxCustomFieldValueLength))
+			} else if first, ok := seen[value]; ok {
+				v.Add(path, fmt.Sprintf("duplicates enum_values[%d]", first))
+			} else {
+				seen[value] = i
+			}
+		}
+	} else if len(d.EnumValues) > 0 {
+		v.Add("enum_values", "must be empty for non-enum field")
+	}
+	if len(v.Violations) > 0 {
+		return v
+	}
+	return nil
+}
+
+// ParseValue checks value decoded from JSON (string, float64 or bool) against the definition and returns
+// its canonical text form
+func (d *CustomFieldDefinition) ParseValue(value any) (string, error) {
+	switch d.Type {
+	case CustomFieldTypeNumber:
+		if n, ok := value.(float64); ok {
+			return strconv.FormatFloat(n, 'f', -1, 64), nil
+		}
+		return "", errors.New("must be a number")
+	case CustomFieldTypeBoolean:
+		if b, ok := value.(bool); ok {
+			return strconv.FormatBool(b), nil
+		}
+		return "", errors.New("must be a boolean")
+	default:
+		if s, ok := value.(string); ok {
+			return d.ParseText(s)
+		}
+		return "", errors.New("must be a string")
+	}
+}
+
+// ParseText checks value given as text (e.g. in URL query) against the definition and returns its canonical
+// text form
+func (d *CustomFieldDefinition) ParseText(text string) (string, error) {
+	switch d.Type {
+	case CustomFieldTypeNumber:
+		n, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return "", errors.New("must be a number")
+		}
+		return strconv.FormatFloat(n, 'f', -1, 64), nil
+	case CustomFieldTypeBoolean:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return "", errors.New("must be a boolean")
+		}
+		return strconv.FormatBool(b), nil
+	case CustomFieldTypeDate:
+		if _, err := time.Parse(customFieldDateLayout, text); err != nil {
+			return "", errors.New("must be a date in YYYY-MM-DD format")
+		}
+		return text, nil
+	case CustomFieldTypeEnum:
+		for _, allowed := range d.EnumValues {
+			if text == allowed {
+				return text, nil
+			}
+		}
+		return "", fmt.Errorf("must be one of %s", strings.Join(d.EnumValues, ", "))
+	default:
+		if strings.TrimSpace(text) == "" {
+			return "", errors.New("must not be empty")
+		}
+		if utf8.RuneCountInString(text) > MaxCustomFieldValueLength {
+			return "", fmt.Errorf("must be at most %d characters long", MaxCustomFieldValueLength)
+		}
+		return text, nil
+	}
+}
+
+// DecodeCustomFieldValue converts canonical text form of the value to string, float64 or bool
+func DecodeCustomFieldValue(fieldType CustomFieldType, text string) any {
+	switch fieldType {
+	case CustomFieldTypeNumber:
+		if n, err := strconv.ParseFloat(text, 64); err == nil {
+			return n
+		}
+	case CustomFieldTypeBoolean:
+		if b, err := strconv.ParseBool(text); err == nil {
+			return b
+		}
+	}
+	return text
+}
+
+// validateCustomFields checks custom field values of the contact against field definitions of the tenant
+func validateCustomFields(v *ValidationError, values map[string]any, fields []*CustomFieldDefinition) {
+	byName := make(map[string]*CustomFieldDefinition, len(fields))
+	for _, field := range fields {
+		byName[field.Name] = field
+		if _, ok := values[field.Name]; field.Required && !ok {
+			v.Add("custom_fields."+field.Name, "is required")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names) // violations are reported in stable order
+	for _, name := range names {
+		value := values[name]
+		field, ok := byName[name]
+		if !ok {
+			v.Add("custom_fields."+name, "unknown custom field")
+			continue
+		}
+		if _, err := field.ParseValue(value); err != nil {
+			v.Add("custom_fields."+name, err.Error())
+		}
+	}
+}
+
+// NormalizeCustomFields sets canonical values of custom fields, contact must be validated first
+func (c *ContactToSave) NormalizeCustomFields(fields []*CustomFieldDefinition) error {
+	c.CustomFieldValues = make([]*CustomFieldValue, 0, len(c.CustomFields))
+	for _, field := range fields {
+		value, ok := c.CustomFields[field.Name]
+		if !ok {
+			continue
+		}
+		text, err := field.ParseValue(value)
+		if err != nil {
+			return NewValidationError("custom_fields."+field.Name, err.Error())
+		}
+		c.CustomFieldValues = append(c.CustomFieldValues, &CustomFieldValue{FieldID: field.ID, Value: text})
+	}
+	return nil
+}
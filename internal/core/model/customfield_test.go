@@ -0,0 +1,173 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateContactCustomFields(t *testing.T) {
+	fields := []*CustomFieldDefinition{
+		{ID: "1", Name: "manager", Type: CustomFieldTypeString, Required: true},
+		{ID: "2", Name: "score", Type: CustomFieldTypeNumber},
+		{ID: "3", Name: "vip", Type: CustomFieldTypeBoolean},
+		{ID: "4", Name: "since", Type: CustomFieldTypeDate},
+		{ID: "5", Name: "tier", Type: CustomFieldTypeEnum, EnumValues: []string{"silver", "gold"}},
+	}
+	tests := []struct {
+		name       string
+		values     map[string]any
+		wantFields []string
+	}{
+		{
+			name:   "only required field",
+			values: map[string]any{"manager": "Jane"},
+		},
+		{
+			name: "every type",
+			values: map[string]any{
+				"manager": "Jane",
+				"score":   42.5,
+				"vip":     true,
+				"since":   "2024-01-31",
+				"tier":    "gold",
+			},
+		},
+		{
+			name:       "missing required field",
+			values:     map[string]any{"score": 1.0},
+			wantFields: []string{"custom_fields.manager"},
+		},
+		{
+			name:       "empty string",
+			values:     map[string]any{"manager": " "},
+			wantFields: []string{"custom_fields.manager"},
+		},
+		{
+			name:       "too long string",
+			values:     map[string]any{"manager": strings.Repeat("a", MaxCustomFieldValueLength+1)},
+			wantFields: []string{"custom_fields.manager"},
+		},
+		{
+			name: "values of wrong types",
+			values: map[string]any{
+				"manager": 1.0,
+				"score":   "42",
+				"vip":     "true",
+				"since":   true,
+				"tier":    1.0,
+			},
+			wantFields: []string{
+				"custom_fields.manager",
+				"custom_fields.score",
+				"custom_fields.since",
+				"custom_fields.tier",
+				"custom_fields.vip",
+			},
+		},
+		{
+			name:       "invalid date",
+			values:     map[string]any{"manager": "Jane", "since": "2024-02-30"},
+			wantFields: []string{"custom_fields.since"},
+		},
+		{
+			name:       "value not in enum",
+			values:     map[string]any{"manager": "Jane", "tier": "Gold"},
+			wantFields: []string{"custom_fields.tier"},
+		},
+		{
+			name:       "unknown field",
+			values:     map[string]any{"manager": "Jane", "nickname": "JJ"},
+			wantFields: []string{"custom_fields.nickname"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validTestContact()
+			c.CustomFields = tt.values
+			got := violatedFields(t, c.Validate("US", fields))
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("violated fields are %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestParseCustomFieldText(t *testing.T) {
+	tests := []struct {
+		fieldType CustomFieldType
+		text      string
+		want      string
+		wantErr   bool
+	}{
+		{fieldType: CustomFieldTypeString, text: "Jane Doe", want: "Jane Doe"},
+		{fieldType: CustomFieldTypeString, text: "", wantErr: true},
+		{fieldType: CustomFieldTypeNumber, text: "42.50", want: "42.5"},
+		{fieldType: CustomFieldTypeNumber, text: "1e3", want: "1000"},
+		{fieldType: CustomFieldTypeNumber, text: "forty", wantErr: true},
+		{fieldType: CustomFieldTypeBoolean, text: "1", want: "true"},
+		{fieldType: CustomFieldTypeBoolean, text: "FALSE", want: "false"},
+		{fieldType: CustomFieldTypeBoolean, text: "yes", wantErr: true},
+		{fieldType: CustomFieldTypeDate, text: "2024-01-31", want: "2024-01-31"},
+		{fieldType: CustomFieldTypeDate, text: "31.01.2024", wantErr: true},
+		{fieldType: CustomFieldTypeEnum, text: "gold", want: "gold"},
+		{fieldType: CustomFieldTypeEnum, text: "bronze", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.fieldType)+" "+tt.text, func(t *testing.T) {
+			d := &CustomFieldDefinition{Name: "field", Type: tt.fieldType}
+			if tt.fieldType == CustomFieldTypeEnum {
+				d.EnumValues = []string{"silver", "gold"}
+			}
+			got, err := d.ParseText(tt.text)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseText() = %q, error %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCustomFieldDefinition(t *testing.T) {
+	tests := []struct {
+		name       string
+		d          *CustomFieldDefinitionToSave
+		wantFields []string
+	}{
+		{
+			name: "string",
+			d:    &CustomFieldDefinitionToSave{Name: "account_manager", Type: CustomFieldTypeString, Required: true},
+		},
+		{
+			name: "enum",
+			d:    &CustomFieldDefinitionToSave{Name: "tier", Type: CustomFieldTypeEnum, EnumValues: []string{"silver", "gold"}},
+		},
+		{
+			name:       "invalid name and type",
+			d:          &CustomFieldDefinitionToSave{Name: "Account-Manager", Type: "text"},
+			wantFields: []string{"name", "type"},
+		},
+		{
+			name:       "enum without values",
+			d:          &CustomFieldDefinitionToSave{Name: "tier", Type: CustomFieldTypeEnum},
+			wantFields: []string{"enum_values"},
+		},
+		{
+			name:       "enum with empty and duplicated values",
+			d:          &CustomFieldDefinitionToSave{Name: "tier", Type: CustomFieldTypeEnum, EnumValues: []string{"gold", "", "gold"}},
+			wantFields: []string{"enum_values[1]", "enum_values[2]"},
+		},
+		{
+			name:       "values of non-enum field",
+			d:          &CustomFieldDefinitionToSave{Name: "score", Type: CustomFieldTypeNumber, EnumValues: []string{"1"}},
+			wantFields: []string{"enum_values"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := violatedFields(t, tt.d.Validate())
+			if !reflect.DeepEqual(got, tt.wantFields) {
+				t.Errorf("violated fields are %v, want %v", got, tt.wantFields)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// Errors returned by use cases, entry points (such as REST API) translate them into their own error codes.
+// Use cases and adapters wrap them with details, so they must be checked by errors.Is or errors.As.
+var (
+	// ErrNotFound is returned when requested entity does not exist (or belongs to another tenant)
+	ErrNotFound = errors.New("not found")
+	// ErrValidation is returned when input is invalid, all problems are listed by ValidationError
+	ErrValidation = errors.New("validation failed")
+	// ErrConflict is returned when operation conflicts with the current state of the entity
+	ErrConflict = errors.New("conflict")
+	// ErrPreconditionFailed is returned when entity is changed on condition that it has the version expected
+	// by the caller, but it was changed by someone else since then
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrUnavailable is returned when dependency (e.g. identity provider) is temporarily unavailable,
+	// so the same request may succeed later
+	ErrUnavailable = errors.New("service unavailable")
+)
+
+// FieldViolation describes a problem with a single input field. Nested fields are addressed by path,
+// e.g. "phones[1].phone_type".
+type FieldViolation struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists all problems found in the input
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func NewValidationError(field string, message string) *ValidationError {
+	return &ValidationError{Violations: []FieldViolation{{Field: field, Message: message}}}
+}
+
+// Add appends violation of the field
+func (e *ValidationError) Add(field string, message string) {
+	e.Violations = append(e.Violations, FieldViolation{Field: field, Message: message})
+}
+
+func (e *ValidationError) Error() string {
+	problems := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		problems[i] = v.Field + ": " + v.Message
+	}
+	return ErrValidation.Error() + ": " + strings.Join(problems, "; ")
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrValidation
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	MaxGroupNameLength     = 100 // characters
+	MaxGroupMembersRequest = 100 // contacts added to or removed from group by a single request
+)
+
+// Group is a named set of contacts of the tenant, e.g. "Customers" or "Family", contact may be a member
+// of many groups
+type Group struct {
+	ID          string
+	Name        string
+	MemberCount int
+}
+
+type GroupToSave struct {
+	Name string
+}
+
+// GroupRef identifies group the contact is a member of
+type GroupRef struct {
+	ID   string
+	Name string
+}
+
+// Validate checks group and returns *ValidationError listing all violations, or nil if group is valid
+func (g *GroupToSave) Validate() error {
+	v := &ValidationError{}
+	if strings.TrimSpace(g.Name) == "" {
+		v.Add("name", "must not be empty")
+	} else if utf8.RuneCountInString(g.Name) > MaxGroupNameLength {
+		v.Add("name", fmt.Sprintf("must be at most %d characters long", MaxGroupNameLength))
+	}
+	if len(v.Violations) > 0 {
+		return v
+	}
+	return nil
+}
+
+// ValidateGroupMembers checks contact ids of group membership request
+func ValidateGroupMembers(contactIDs []string) error {
+	v := &ValidationError{}
+	if len(contactIDs) == 0 {
+		v.Add("contact_ids", "must not be empty")
+	} else if len(contactIDs) > MaxGroupMembersRequest {
+		v.Add("contact_ids", fmt.Sprintf("must contain at most %d contact ids", MaxGroupMembersRequest))
+	}
+	if len(v.Violations) > 0 {
+		return v
+	}
+	return nil
+}
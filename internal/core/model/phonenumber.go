@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"github.com/nyaruka/phonenumbers"
+	"strings"
+)
+
+var errNotPhoneNumber = errors.New("is not a valid phone number")
+
+// NormalizePhoneNumber returns phone number in E.164 format, e.g. "+15035557777" for "(503) 555-7777".
+// Numbers without "+" and country code are treated as numbers of the default region (ISO 3166-1 code, e.g. "US").
+func NormalizePhoneNumber(number string, defaultRegion string) (string, error) {
+	num, err := phonenumbers.Parse(number, defaultRegion)
+	if err != nil {
+		if errors.Is(err, phonenumbers.ErrInvalidCountryCode) {
+			if strings.HasPrefix(strings.TrimSpace(number), "+") {
+				return "", errors.New("has unknown country code")
+			}
+			return "", errors.New("must start with + and country code")
+		}
+		return "", errNotPhoneNumber
+	}
+	if !phonenumbers.IsPossibleNumber(num) {
+		return "", errNotPhoneNumber
+	}
+	return phonenumbers.Format(num, phonenumbers.E164), nil
+}
+
+// FormatPhoneNumberNational returns E.164 phone number in national format, e.g. "(503) 555-7777"
+func FormatPhoneNumberNational(e164 string) string {
+	return formatE164(e164, phonenumbers.NATIONAL)
+}
+
+// FormatPhoneNumberInternational returns E.164 phone number in international format, e.g. "+1 503-555-7777"
+func FormatPhoneNumberInternational(e164 string) string {
+	return formatE164(e164, phonenumbers.INTERNATIONAL)
+}
+
+func formatE164(e164 string, format phonenumbers.PhoneNumberFormat) string {
+	if e164 == "" {
+		return ""
+	}
+	num, err := phonenumbers.Parse(e164, "")
+	if err != nil {
+		return e164
+	}
+	return phonenumbers.Format(num, format)
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestNormalizePhoneNumber(t *testing.T) {
+	tests := []struct {
+		number        string
+		defaultRegion string
+		want          string
+		wantErr       string
+	}{
+		{number: "503-555-7777", defaultRegion: "US", want: "+15035557777"},
+		{number: "(503) 5557777", defaultRegion: "US", want: "+15035557777"},
+		{number: " 503.555.7777 ", defaultRegion: "US", want: "+15035557777"},
+		{number: "+1 503 555 7777", defaultRegion: "US", want: "+15035557777"},
+		{number: "+1 503 555 7777", defaultRegion: "GB", want: "+15035557777"},
+		{number: "+1 503 555 7777", defaultRegion: "", want: "+15035557777"},
+		{number: "020 7946 0018", defaultRegion: "GB", want: "+442079460018"},
+		{number: "503-555-7777", defaultRegion: "", wantErr: "must start with + and country code"},
+		{number: "+999 503 555 7777", defaultRegion: "US", wantErr: "has unknown country code"},
+		{number: "555", defaultRegion: "US", wantErr: "is not a valid phone number"},
+		{number: "503-555-77777777", defaultRegion: "US", wantErr: "is not a valid phone number"},
+		{number: "not a number", defaultRegion: "US", wantErr: "is not a valid phone number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.number+" in "+tt.defaultRegion, func(t *testing.T) {
+			got, err := NormalizePhoneNumber(tt.number, tt.defaultRegion)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("NormalizePhoneNumber() = %q, error %v, want error %q", got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("NormalizePhoneNumber() = %q, error %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPhoneNumber(t *testing.T) {
+	tests := []struct {
+		e164              string
+		wantNational      string
+		wantInternational string
+	}{
+		{e164: "+15035557777", wantNational: "(503) 555-7777", wantInternational: "+1 503-555-7777"},
+		{e164: "+442079460018", wantNational: "020 7946 0018", wantInternational: "+44 20 7946 0018"},
+		{e164: "", wantNational: "", wantInternational: ""},
+		{e164: "garbage", wantNational: "garbage", wantInternational: "garbage"}, // returned as it is
+	}
+	for _, tt := range tests {
+		t.Run(tt.e164, func(t *testing.T) {
+			if got := FormatPhoneNumberNational(tt.e164); got != tt.wantNational {
+				t.Errorf("FormatPhoneNumberNational() = %q, want %q", got, tt.wantNational)
+			}
+			if got := FormatPhoneNumberInternational(tt.e164); got != tt.wantInternational {
+				t.Errorf("FormatPhoneNumberInternational() = %q, want %q", got, tt.wantInternational)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"reflect"
+	"sort"
+	"time"
+)
+
+type ContactRevisionAction string
+
+const (
+	ContactRevisionCreated  ContactRevisionAction = "created"
+	ContactRevisionUpdated  ContactRevisionAction = "updated"
+	ContactRevisionDeleted  ContactRevisionAction = "deleted"
+	ContactRevisionRestored ContactRevisionAction = "restored"
+	ContactRevisionReverted ContactRevisionAction = "reverted"
+)
+
+// ContactRevision is a change of the contact recorded in its history
+type ContactRevision struct {
+	Revision  int // 1 for the first recorded change of the contact, then 2, 3...
+	Action    ContactRevisionAction
+	Actor     string // subject of the caller who made the change
+	ChangedAt time.Time
+	Contact   *Contact // contact as it was right after the change, it has no groups
+	// RevertedTo is a number of the revision the contact was reverted to by ContactRevisionReverted action
+	RevertedTo int
+	// Changes lists fields changed by this revision, it is nil if the previous state of the contact is unknown
+	// (contact was changed before history was recorded)
+	Changes []*ContactFieldChange
+}
+
+// ContactFieldChange is an old and a new value of the contact field, e.g. "last_name", "phones" or
+// "custom_fields.tier". Value is nil if the field had no value.
+type ContactFieldChange struct {
+	Field string
+	Old   any
+	New   any
+}
+
+// DiffContacts returns changes of contact fields made between old and new versions of the contact, old is nil for
+// created contact. Lists of phones, emails, addresses and URLs are compared as a whole.
+func DiffContacts(old *Contact, new *Contact) []*ContactFieldChange {
+	if old == nil {
+		old = &Contact{}
+	}
+	changes := make([]*ContactFieldChange, 0)
+	addChange := func(field string, oldValue any, newValue any) {
+		if !equalValues(oldValue, newValue) {
+			changes = append(changes, &ContactFieldChange{Field: field, Old: oldValue, New: newValue})
+		}
+	}
+	addChange("first_name", old.FirstName, new.FirstName)
+	addChange("last_name", old.LastName, new.LastName)
+	addChange("phones", old.Phones, new.Phones)
+	addChange("emails", old.Emails, new.Emails)
+	addChange("addresses", old.Addresses, new.Addresses)
+	addChange("urls", old.URLs, new.URLs)
+
+	names := make([]string, 0, len(old.CustomFields)+len(new.CustomFields))
+	for name := range old.CustomFields {
+		names = append(names, name)
+	}
+	for name := range new.CustomFields {
+		if _, ok := old.CustomFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names) // changes are reported in stable order
+	for _, name := range names {
+		addChange("custom_fields."+name, old.CustomFields[name], new.CustomFields[name])
+	}
+	return changes
+}
+
+// equalValues compares field values deeply, nil and empty lists are equal
+func equalValues(a any, b any) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Slice && vb.Kind() == reflect.Slice && va.Len() == 0 && vb.Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
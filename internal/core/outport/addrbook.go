@@ -3,12 +3,52 @@ package outport
 import (
 	"context"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"time"
 )
 
 type AddrBook interface {
-	LoadAllContacts(ctx context.Context) ([]*model.Contact, error)
+	LoadContacts(ctx context.Context, q *model.ContactListQuery) (*model.ContactListPage, error)
+	SearchContacts(ctx context.Context, text string, limit int) ([]*model.Contact, error)
 	LoadContactByID(ctx context.Context, ID string) (*model.Contact, error)
+	// LoadCurrentContactByID loads contact the same way as LoadContactByID does, but bypasses cache, so that
+	// the contact is in the state it was last saved in, e.g. to change it on condition that it has the same version
+	LoadCurrentContactByID(ctx context.Context, ID string) (*model.Contact, error)
+	FindContactsByPhone(ctx context.Context, phoneE164 string) ([]*model.Contact, error)
 	AddContact(ctx context.Context, c *model.ContactToSave) (*model.Contact, error)
+	// UpdateContact replaces contact, model.ErrPreconditionFailed is returned if c.Version does not match
 	UpdateContact(ctx context.Context, ID string, c *model.ContactToSave) (*model.Contact, error)
-	DeleteContact(ctx context.Context, ID string) (found bool, err error)
+	// DeleteContact moves contact to trash, it is not loaded, listed or found until it is restored.
+	// Contact of any version is deleted if version is zero, otherwise model.ErrPreconditionFailed is returned
+	// if the contact has another version.
+	DeleteContact(ctx context.Context, ID string, version int) (found bool, err error)
+	// ApplyContactOperations applies operations in a single transaction. It stops at the first operation which fails
+	// with model error (e.g. model.ErrNotFound) and applies none, results up to the failed operation are returned then.
+	ApplyContactOperations(ctx context.Context, ops []*model.ContactOperation) ([]*model.ContactOperationResult, error)
+	// LoadTrashedContacts returns up to limit contacts in trash, the most recently deleted go first
+	LoadTrashedContacts(ctx context.Context, limit int) ([]*model.Contact, error)
+	// RestoreContact moves contact back from trash, nil is returned if there is no such contact in trash
+	RestoreContact(ctx context.Context, ID string) (*model.Contact, error)
+	// LoadContactRevisions returns up to limit revisions of the contact, the most recent go first. Contact in trash
+	// has revisions too, found is false if there is no such contact.
+	LoadContactRevisions(ctx context.Context, ID string, limit int) (revisions []*model.ContactRevision, found bool, err error)
+	// LoadContactRevision returns revision of the contact by its number, nil if there is no such revision
+	LoadContactRevision(ctx context.Context, ID string, revision int) (*model.ContactRevision, error)
+	// RevertContact saves contact the same way as UpdateContact does, but records the change as revert to revision
+	RevertContact(ctx context.Context, ID string, revision int, c *model.ContactToSave) (*model.Contact, error)
+	// LoadContactRevisionAsOf returns the last revision of the contact made at or before asOf, nil if there is none
+	LoadContactRevisionAsOf(ctx context.Context, ID string, asOf time.Time) (*model.ContactRevision, error)
+	// PurgeContacts permanently deletes contacts of all tenants moved to trash before deletedBefore
+	PurgeContacts(ctx context.Context, deletedBefore time.Time) (count int, err error)
+
+	LoadGroups(ctx context.Context) ([]*model.Group, error)
+	LoadGroupByID(ctx context.Context, ID string) (*model.Group, error)
+	AddGroup(ctx context.Context, g *model.GroupToSave) (*model.Group, error)
+	UpdateGroup(ctx context.Context, ID string, g *model.GroupToSave) (*model.Group, error)
+	// DeleteGroup deletes group and memberships of its contacts, contacts are kept
+	DeleteGroup(ctx context.Context, ID string) (found bool, err error)
+	// AddGroupMembers adds contacts to the group, contacts which are members already are skipped. Nothing is
+	// added if some contacts do not exist, their ids are returned instead.
+	AddGroupMembers(ctx context.Context, groupID string, contactIDs []string) (missing []string, found bool, err error)
+	// RemoveGroupMembers removes contacts from the group, contacts which are not members are skipped
+	RemoveGroupMembers(ctx context.Context, groupID string, contactIDs []string) (found bool, err error)
 }
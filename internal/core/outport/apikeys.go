@@ -0,0 +1,14 @@
+package outport
+
+import (
+	"context"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+type APIKeys interface {
+	AddAPIKey(ctx context.Context, k *model.APIKeyToSave) (*model.APIKey, error)
+	LoadAllAPIKeys(ctx context.Context) ([]*model.APIKey, error)
+	// LoadActiveAPIKeyByHash returns nil if there is no such key or key was revoked
+	LoadActiveAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, ID string) (found bool, err error)
+}
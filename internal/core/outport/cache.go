@@ -7,22 +7,27 @@ import (
 )
 
 // Cache declares generic cache interface to be implemented by real cache implementation (such as Redis for example)
+// Cache is never a source of truth, so callers are expected to treat failed operation as a cache miss.
 type Cache interface {
 	Close()
 	Register(partition *CachePartition)
-	Set(ctx context.Context, key CacheKey, value any)
-	Get(ctx context.Context, key CacheKey, value any) bool
-	Del(ctx context.Context, key CacheKey)
+	Set(ctx context.Context, key CacheKey, value any) error
+	Get(ctx context.Context, key CacheKey, value any) (found bool, err error)
+	Del(ctx context.Context, key CacheKey) error
 }
 
 type CachePartition struct {
 	Namespace     string        // Namespace key prefix, must be unique amongst other namespaces
 	Ttl           time.Duration // Time-to-live
 	LocalMaxItems int           // Max number of items in cache (in-memory cache only, for redis etc will be ignored)
+	// RefreshAfter enables stale-while-revalidate: item older than this is still returned, but it is rebuilt
+	// in background. It must be shorter than Ttl, zero value disables background refresh.
+	RefreshAfter time.Duration
 }
 
 func (cp *CachePartition) String() string {
-	return fmt.Sprintf("{namespace=%s ttl=%s localMaxItems=%d}", cp.Namespace, cp.Ttl.String(), cp.LocalMaxItems)
+	return fmt.Sprintf("{namespace=%s ttl=%s localMaxItems=%d refreshAfter=%s}",
+		cp.Namespace, cp.Ttl.String(), cp.LocalMaxItems, cp.RefreshAfter.String())
 }
 
 // CacheKey consists of two parts - namespace (can be your entity type) and encoded key itself
@@ -0,0 +1,14 @@
+package outport
+
+import (
+	"context"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// CustomFields stores definitions of custom contact fields of the caller tenant
+type CustomFields interface {
+	LoadCustomFields(ctx context.Context) ([]*model.CustomFieldDefinition, error)
+	AddCustomField(ctx context.Context, d *model.CustomFieldDefinitionToSave) (*model.CustomFieldDefinition, error)
+	// DeleteCustomField deletes field definition along with field values of all contacts
+	DeleteCustomField(ctx context.Context, ID string) (found bool, err error)
+}
@@ -0,0 +1,12 @@
+package outport
+
+import (
+	"context"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+)
+
+// TokenVerifier verifies bearer tokens (JWT) issued by external identity provider
+type TokenVerifier interface {
+	// VerifyToken returns identity of token subject, or error wrapping model.ErrInvalidToken if token is rejected
+	VerifyToken(ctx context.Context, rawToken string) (*app.Identity, error)
+}
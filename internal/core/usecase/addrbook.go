@@ -2,20 +2,69 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
 )
 
+const (
+	DefaultContactListLimit   = 50
+	MaxContactListLimit       = 500
+	DefaultContactSearchLimit = 20
+	MaxContactSearchLimit     = 100
+)
+
 func (uc *UseCases) LoadAddrBookContacts(
 	ctx context.Context,
+	q *model.ContactListQuery,
+) (*model.ContactListPage, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultContactListLimit
+	} else if q.Limit > MaxContactListLimit {
+		q.Limit = MaxContactListLimit
+	}
+	if q.SortBy == "" {
+		q.SortBy = model.ContactSortByLastName
+	}
+	if q.CustomField != nil {
+		if err := uc.resolveCustomFieldFilter(ctx, q.CustomField); err != nil {
+			return nil, err
+		}
+	}
+	app.Logger(ctx).Debugf("Load address book contacts page: %+v", q)
+	page, err := uc.AddrBook.LoadContacts(ctx, q)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading address book contacts page failed with error: %v", err)
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Loaded %d of %d address book contacts", len(page.Contacts), page.TotalCount)
+	return page, nil
+}
+
+func (uc *UseCases) SearchAddrBookContacts(
+	ctx context.Context,
+	text string,
+	limit int,
 ) ([]*model.Contact, error) {
-	app.Logger(ctx).Debug("Load all address book contacts")
-	contacts, err := uc.AddrBook.LoadAllContacts(ctx)
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultContactSearchLimit
+	} else if limit > MaxContactSearchLimit {
+		limit = MaxContactSearchLimit
+	}
+	app.Logger(ctx).Debugf("Search address book contacts by text=%q", text)
+	contacts, err := uc.AddrBook.SearchContacts(ctx, text, limit)
 	if err != nil {
-		app.Logger(ctx).Errorf("Loading all address book contacts failed with error: %v", err)
+		app.Logger(ctx).Errorf("Searching address book contacts by text=%q failed with error: %v", text, err)
 		return nil, err
 	}
-	app.Logger(ctx).Debugf("Loaded address book contacts: %v", contacts)
+	app.Logger(ctx).Debugf("Found %d address book contacts", len(contacts))
 	return contacts, nil
 }
 
@@ -23,6 +72,9 @@ func (uc *UseCases) LoadAddrBookContactByID(
 	ctx context.Context,
 	ID string,
 ) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
 	app.Logger(ctx).Debugf("Load address book contact by id=%s", ID)
 	contact, err := uc.AddrBook.LoadContactByID(ctx, ID)
 	if err != nil {
@@ -31,16 +83,45 @@ func (uc *UseCases) LoadAddrBookContactByID(
 	}
 	if contact == nil {
 		app.Logger(ctx).Infof("No address book contact found with id=%s", ID)
-	} else {
-		app.Logger(ctx).Debugf("Loaded address book contact: %v", contact)
+		return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
 	}
+	app.Logger(ctx).Debugf("Loaded address book contact: %v", contact)
 	return contact, nil
 }
 
+// FindAddrBookContactsByPhone returns contacts having the phone number, number is normalized the same way as
+// numbers of saved contacts, so it may be given in any format, e.g. "(503) 555-7777" or "+15035557777"
+func (uc *UseCases) FindAddrBookContactsByPhone(
+	ctx context.Context,
+	number string,
+) ([]*model.Contact, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	phoneE164, err := model.NormalizePhoneNumber(number, uc.Phones.DefaultRegion)
+	if err != nil {
+		return nil, model.NewValidationError("number", err.Error())
+	}
+	app.Logger(ctx).Debugf("Find address book contacts by phone=%s", phoneE164)
+	contacts, err := uc.AddrBook.FindContactsByPhone(ctx, phoneE164)
+	if err != nil {
+		app.Logger(ctx).Errorf("Finding address book contacts by phone=%s failed with error: %v", phoneE164, err)
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Found %d address book contacts by phone=%s", len(contacts), phoneE164)
+	return contacts, nil
+}
+
 func (uc *UseCases) AddAddrBookContact(
 	ctx context.Context,
 	contact *model.ContactToSave,
 ) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := uc.validateContact(ctx, contact); err != nil {
+		return nil, err
+	}
 	app.Logger(ctx).Debugf("Add address book contact: %v", contact)
 	newContact, err := uc.AddrBook.AddContact(ctx, contact)
 	if err != nil {
@@ -51,39 +132,152 @@ func (uc *UseCases) AddAddrBookContact(
 	return newContact, nil
 }
 
+// UpdateAddrBookContact replaces the contact, it is updated only if it has contact.Version (unless it is zero)
 func (uc *UseCases) UpdateAddrBookContact(
 	ctx context.Context,
 	ID string,
 	contact *model.ContactToSave,
-) (updatedContact *model.Contact, found bool, err error) {
+) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := uc.validateContact(ctx, contact); err != nil {
+		return nil, err
+	}
 	app.Logger(ctx).Debugf("Update address book contact by id=%s with value: %v", ID, contact)
-	updatedContact, err = uc.AddrBook.UpdateContact(ctx, ID, contact)
+	updatedContact, err := uc.AddrBook.UpdateContact(ctx, ID, contact)
+	if errors.Is(err, model.ErrPreconditionFailed) {
+		app.Logger(ctx).Infof("Address book contact by id=%s has version other than %d", ID, contact.Version)
+		return nil, err
+	}
 	if err != nil {
 		app.Logger(ctx).Errorf("Update address book contact by id=%s failed with error: %v", ID, err)
-		return nil, false, err
+		return nil, err
 	}
 	if updatedContact == nil {
 		app.Logger(ctx).Infof("Attempt to update non-existing contact by id=%s", ID)
-		return nil, false, nil
+		return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
 	}
 	app.Logger(ctx).Debugf("Updated address book contact by ID=%s", ID)
-	return updatedContact, true, nil
+	return updatedContact, nil
+}
+
+// patchContactAttempts limits number of attempts to patch contact which is concurrently changed by someone else
+const patchContactAttempts = 3
+
+// PatchAddrBookContact applies patch to the current state of the contact and saves it the same way as
+// UpdateAddrBookContact does. Contact is saved only if it was not changed since its state was loaded, otherwise
+// the patch is applied again to the new state, so that concurrent changes are never lost. Unless version is zero,
+// the contact must have this version.
+func (uc *UseCases) PatchAddrBookContact(
+	ctx context.Context,
+	ID string,
+	patch model.ContactPatch,
+	version int,
+) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		app.Logger(ctx).Debugf("Patch address book contact by id=%s, attempt=%d", ID, attempt)
+		// cached contact may be stale, it would make the version check below fail for no reason
+		current, err := uc.AddrBook.LoadCurrentContactByID(ctx, ID)
+		if err != nil {
+			app.Logger(ctx).Errorf("Loading address book contact by id=%s failed with error: %v", ID, err)
+			return nil, err
+		}
+		if current == nil {
+			app.Logger(ctx).Infof("Attempt to patch non-existing contact by id=%s", ID)
+			return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
+		}
+		if version != 0 && current.Version != version {
+			app.Logger(ctx).Infof("Address book contact by id=%s has version other than %d", ID, version)
+			return nil, fmt.Errorf("%w: contact id=%s was changed", model.ErrPreconditionFailed, ID)
+		}
+		contact, err := patch.Apply(current)
+		if err != nil {
+			app.Logger(ctx).Infof("Patch cannot be applied to address book contact by id=%s: %v", ID, err)
+			return nil, err
+		}
+		contact.Version = current.Version
+		if err = uc.validateContact(ctx, contact); err != nil {
+			return nil, err
+		}
+		patchedContact, err := uc.AddrBook.UpdateContact(ctx, ID, contact)
+		if errors.Is(err, model.ErrPreconditionFailed) {
+			if version == 0 && attempt < patchContactAttempts {
+				app.Logger(ctx).Infof("Address book contact by id=%s was changed concurrently, patching it again", ID)
+				continue
+			}
+			app.Logger(ctx).Infof("Address book contact by id=%s has version other than %d", ID, contact.Version)
+			return nil, err
+		}
+		if err != nil {
+			app.Logger(ctx).Errorf("Patch address book contact by id=%s failed with error: %v", ID, err)
+			return nil, err
+		}
+		if patchedContact == nil {
+			app.Logger(ctx).Infof("Attempt to patch non-existing contact by id=%s", ID)
+			return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
+		}
+		app.Logger(ctx).Debugf("Patched address book contact by ID=%s", ID)
+		return patchedContact, nil
+	}
 }
 
+// DeleteAddrBookContact moves the contact to trash, it is deleted only if it has given version (unless it is zero)
 func (uc *UseCases) DeleteAddrBookContact(
 	ctx context.Context,
 	ID string,
-) (found bool, err error) {
+	version int,
+) error {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return err
+	}
 	app.Logger(ctx).Debugf("Delete address book contact by id=%s", ID)
-	found, err = uc.AddrBook.DeleteContact(ctx, ID)
+	found, err := uc.AddrBook.DeleteContact(ctx, ID, version)
+	if errors.Is(err, model.ErrPreconditionFailed) {
+		app.Logger(ctx).Infof("Address book contact by id=%s has version other than %d", ID, version)
+		return err
+	}
 	if err != nil {
-		app.Logger(ctx).Errorf("Deleting address book contact by id=%s failed with error:", ID)
-		return
+		app.Logger(ctx).Errorf("Deleting address book contact by id=%s failed with error: %v", ID, err)
+		return err
 	}
-	if found {
-		app.Logger(ctx).Debugf("Deleted address book contact by id=%s", ID)
-	} else {
+	if !found {
 		app.Logger(ctx).Infof("Attempt to delete non-existing contact by id=%s", ID)
+		return fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Debugf("Moved address book contact by id=%s to trash", ID)
+	return nil
+}
+
+// validateContact checks contact, normalizes its phone numbers to E.164 and custom field values to their
+// canonical form
+func (uc *UseCases) validateContact(ctx context.Context, contact *model.ContactToSave) error {
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return err
+	}
+	return uc.validateContactWith(ctx, contact, fields)
+}
+
+// validateContactWith validates contact the same way as validateContact does with already loaded custom fields
+func (uc *UseCases) validateContactWith(
+	ctx context.Context,
+	contact *model.ContactToSave,
+	fields []*model.CustomFieldDefinition,
+) error {
+	err := contact.Validate(uc.Phones.DefaultRegion, fields)
+	if err == nil {
+		err = contact.NormalizePhones(uc.Phones.DefaultRegion)
+	}
+	if err == nil {
+		err = contact.NormalizeCustomFields(fields)
+	}
+	if err != nil {
+		app.Logger(ctx).Infof("Invalid address book contact: %v", err)
 	}
-	return
+	return err
 }
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"strings"
+)
+
+const (
+	apiKeyTextPrefix   = "ak_"
+	apiKeyRandomBytes  = 32
+	apiKeyDisplayChars = 8 // number of key characters (after "ak_") kept to tell keys apart
+)
+
+// configAPIKey identifies static API key configured by credentials.key setting
+var configAPIKey = &model.APIKey{ID: "config", Name: "credentials.key", Tenant: app.DefaultTenant, Role: model.RoleAdmin}
+
+// CreateAPIKey issues new API key giving access with the role to address book of the tenant.
+// The returned raw key is shown once, only its hash is stored.
+func (uc *UseCases) CreateAPIKey(
+	ctx context.Context,
+	name string,
+	tenant string,
+	role model.Role,
+) (key *model.APIKey, rawKey string, err error) {
+	if err = authorize(ctx, model.RoleAdmin); err != nil {
+		return nil, "", err
+	}
+	verr := &model.ValidationError{}
+	if strings.TrimSpace(name) == "" {
+		verr.Add("name", "must not be empty")
+	}
+	if strings.TrimSpace(tenant) == "" {
+		verr.Add("tenant", "must not be empty")
+	}
+	if _, err = model.ParseRole(string(role)); err != nil {
+		verr.Add("role", err.Error())
+	}
+	if len(verr.Violations) > 0 {
+		return nil, "", verr
+	}
+	random := make([]byte, apiKeyRandomBytes)
+	if _, err = rand.Read(random); err != nil {
+		return nil, "", err
+	}
+	rawKey = apiKeyTextPrefix + base64.RawURLEncoding.EncodeToString(random)
+	app.Logger(ctx).Debugf("Add API key name=%s tenant=%s role=%s", name, tenant, role)
+	key, err = uc.APIKeys.AddAPIKey(ctx, &model.APIKeyToSave{
+		Name:    name,
+		Tenant:  tenant,
+		Role:    role,
+		Prefix:  rawKey[:len(apiKeyTextPrefix)+apiKeyDisplayChars],
+		KeyHash: uc.hashAPIKey(rawKey),
+	})
+	if err != nil {
+		app.Logger(ctx).Errorf("Adding API key failed with error: %v", err)
+		return nil, "", err
+	}
+	app.Logger(ctx).Infof("Added API key id=%s name=%s", key.ID, key.Name)
+	return key, rawKey, nil
+}
+
+func (uc *UseCases) LoadAPIKeys(
+	ctx context.Context,
+) ([]*model.APIKey, error) {
+	if err := authorize(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	keys, err := uc.APIKeys.LoadAllAPIKeys(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading API keys failed with error: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (uc *UseCases) RevokeAPIKey(
+	ctx context.Context,
+	ID string,
+) error {
+	if err := authorize(ctx, model.RoleAdmin); err != nil {
+		return err
+	}
+	found, err := uc.APIKeys.RevokeAPIKey(ctx, ID)
+	if err != nil {
+		app.Logger(ctx).Errorf("Revoking API key id=%s failed with error: %v", ID, err)
+		return err
+	}
+	if !found {
+		app.Logger(ctx).Infof("Attempt to revoke non-existing or already revoked API key id=%s", ID)
+		return fmt.Errorf("%w: active API key id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Infof("Revoked API key id=%s", ID)
+	return nil
+}
+
+// AuthenticateAPIKey returns API key matching raw key presented by a client, or nil if the key is unknown or revoked.
+// Static key from credentials.key setting (if it is set) is accepted as well.
+func (uc *UseCases) AuthenticateAPIKey(
+	ctx context.Context,
+	rawKey string,
+) (*model.APIKey, error) {
+	if staticKey := uc.Credentials.Key; staticKey != "" &&
+		subtle.ConstantTimeCompare([]byte(staticKey), []byte(rawKey)) == 1 {
+		return configAPIKey, nil
+	}
+	if !strings.HasPrefix(rawKey, apiKeyTextPrefix) {
+		return nil, nil
+	}
+	key, err := uc.APIKeys.LoadActiveAPIKeyByHash(ctx, uc.hashAPIKey(rawKey))
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading API key by hash failed with error: %v", err)
+		return nil, err
+	}
+	return key, nil
+}
+
+// hashAPIKey returns HMAC of the key keyed by credentials.secret setting, so that leaked hashes
+// cannot be checked against guessed keys without the secret
+func (uc *UseCases) hashAPIKey(rawKey string) string {
+	mac := hmac.New(sha256.New, []byte(uc.Credentials.Secret))
+	mac.Write([]byte(rawKey))
+	return hex.EncodeToString(mac.Sum(nil))
+}
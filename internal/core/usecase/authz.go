@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// authorize returns error wrapping model.ErrForbidden unless the caller has the required role
+// (or a role that includes it). Every entry point (HTTP, CLI, ...) must put caller identity into the context.
+func authorize(ctx context.Context, required model.Role) error {
+	identity := app.CallerIdentity(ctx)
+	if identity == nil {
+		app.Logger(ctx).Infof("Anonymous caller has no %s role", required)
+		return fmt.Errorf("%w: caller is not authenticated", model.ErrForbidden)
+	}
+	for _, role := range identity.Roles {
+		if model.Role(role).Includes(required) {
+			return nil
+		}
+	}
+	app.Logger(ctx).Infof("Caller %s has no %s role, granted roles: %v", identity.Subject, required, identity.Roles)
+	return fmt.Errorf("%w: %s role is required", model.ErrForbidden, required)
+}
+
+// AuthorizeDiagnostics returns error wrapping model.ErrForbidden unless the caller may read runtime
+// diagnostics of the server, which are not scoped to a tenant and so are available to admins only
+func (uc *UseCases) AuthorizeDiagnostics(ctx context.Context) error {
+	return authorize(ctx, model.RoleAdmin)
+}
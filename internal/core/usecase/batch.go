@@ -0,0 +1,99 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// ApplyAddrBookBatch applies operations of the batch in their order and returns result of every operation.
+// If operation of atomic batch fails, then other operations are not applied and their result is model.ErrNotApplied.
+func (uc *UseCases) ApplyAddrBookBatch(
+	ctx context.Context,
+	batch *model.ContactBatch,
+) ([]*model.ContactOperationResult, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := batch.Validate(); err != nil {
+		return nil, err
+	}
+	for _, op := range batch.Operations {
+		if op.Type == model.ContactOperationUpdate {
+			op.Contact.Version = op.Version
+		}
+	}
+	app.Logger(ctx).Debugf("Apply batch of %d address book operations, atomic=%t", len(batch.Operations), batch.Atomic)
+	if !batch.Atomic {
+		return uc.applyAddrBookOperations(ctx, batch.Operations), nil
+	}
+	return uc.applyAddrBookOperationsAtomically(ctx, batch.Operations)
+}
+
+// applyAddrBookOperations applies every operation on its own, the same way as use case of the operation does
+func (uc *UseCases) applyAddrBookOperations(
+	ctx context.Context,
+	ops []*model.ContactOperation,
+) []*model.ContactOperationResult {
+	results := make([]*model.ContactOperationResult, len(ops))
+	for i, op := range ops {
+		result := &model.ContactOperationResult{}
+		switch op.Type {
+		case model.ContactOperationCreate:
+			result.Contact, result.Err = uc.AddAddrBookContact(ctx, op.Contact)
+		case model.ContactOperationUpdate:
+			result.Contact, result.Err = uc.UpdateAddrBookContact(ctx, op.ID, op.Contact)
+		case model.ContactOperationDelete:
+			result.Err = uc.DeleteAddrBookContact(ctx, op.ID, op.Version)
+		}
+		results[i] = result
+	}
+	return results
+}
+
+func (uc *UseCases) applyAddrBookOperationsAtomically(
+	ctx context.Context,
+	ops []*model.ContactOperation,
+) ([]*model.ContactOperationResult, error) {
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return nil, err
+	}
+	for i, op := range ops {
+		if op.Type == model.ContactOperationDelete {
+			continue
+		}
+		if err = uc.validateContactWith(ctx, op.Contact, fields); err != nil {
+			return failedBatchResults(len(ops), i, err), nil
+		}
+	}
+	results, err := uc.AddrBook.ApplyContactOperations(ctx, ops)
+	if err != nil {
+		app.Logger(ctx).Errorf("Applying batch of address book operations failed with error: %v", err)
+		return nil, err
+	}
+	if failed := len(results) - 1; results[failed].Err != nil {
+		app.Logger(ctx).Infof("Batch of address book operations is not applied, operation %d failed: %v",
+			failed, results[failed].Err)
+		return failedBatchResults(len(ops), failed, results[failed].Err), nil
+	}
+	app.Logger(ctx).Debugf("Applied batch of %d address book operations", len(results))
+	return results, nil
+}
+
+// failedBatchResults reports failure of the operation of atomic batch, the rest of operations are not applied
+func failedBatchResults(count int, failed int, err error) []*model.ContactOperationResult {
+	results := make([]*model.ContactOperationResult, count)
+	for i := range results {
+		if i == failed {
+			results[i] = &model.ContactOperationResult{Err: err}
+		} else {
+			results[i] = &model.ContactOperationResult{
+				Err: fmt.Errorf("%w: operation %d failed", model.ErrNotApplied, failed),
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+func (uc *UseCases) LoadCustomFields(
+	ctx context.Context,
+) ([]*model.CustomFieldDefinition, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return nil, err
+	}
+	return fields, nil
+}
+
+// AddCustomField defines new custom field of tenant contacts, existing contacts get no value of the field
+// (even if it is required) until they are updated
+func (uc *UseCases) AddCustomField(
+	ctx context.Context,
+	field *model.CustomFieldDefinitionToSave,
+) (*model.CustomFieldDefinition, error) {
+	if err := authorize(ctx, model.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := field.Validate(); err != nil {
+		app.Logger(ctx).Infof("Invalid custom field: %v", err)
+		return nil, err
+	}
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return nil, err
+	}
+	if len(fields) >= model.MaxCustomFields {
+		return nil, fmt.Errorf("%w: at most %d custom fields may be defined", model.ErrConflict, model.MaxCustomFields)
+	}
+	for _, existing := range fields {
+		if existing.Name == field.Name {
+			return nil, fmt.Errorf("%w: custom field name=%s already exists", model.ErrConflict, field.Name)
+		}
+	}
+	app.Logger(ctx).Debugf("Add custom field: %+v", field)
+	newField, err := uc.CustomFields.AddCustomField(ctx, field)
+	if err != nil {
+		app.Logger(ctx).Errorf("Adding custom field failed with error: %v", err)
+		return nil, err
+	}
+	app.Logger(ctx).Infof("Added custom field id=%s name=%s", newField.ID, newField.Name)
+	return newField, nil
+}
+
+// DeleteCustomField deletes custom field definition and values of the field of all contacts
+func (uc *UseCases) DeleteCustomField(
+	ctx context.Context,
+	ID string,
+) error {
+	if err := authorize(ctx, model.RoleAdmin); err != nil {
+		return err
+	}
+	found, err := uc.CustomFields.DeleteCustomField(ctx, ID)
+	if err != nil {
+		app.Logger(ctx).Errorf("Deleting custom field id=%s failed with error: %v", ID, err)
+		return err
+	}
+	if !found {
+		app.Logger(ctx).Infof("Attempt to delete non-existing custom field id=%s", ID)
+		return fmt.Errorf("%w: custom field id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Infof("Deleted custom field id=%s", ID)
+	return nil
+}
+
+// resolveCustomFieldFilter finds custom field of the list filter and converts filter value to canonical form
+func (uc *UseCases) resolveCustomFieldFilter(ctx context.Context, filter *model.CustomFieldFilter) error {
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return err
+	}
+	param := "cf." + filter.Name
+	for _, field := range fields {
+		if field.Name == filter.Name {
+			value, err := field.ParseText(filter.Value)
+			if err != nil {
+				return model.NewValidationError(param, err.Error())
+			}
+			filter.FieldID, filter.Value = field.ID, value
+			return nil
+		}
+	}
+	return model.NewValidationError(param, "unknown custom field")
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"strings"
+)
+
+func (uc *UseCases) LoadGroups(
+	ctx context.Context,
+) ([]*model.Group, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	groups, err := uc.AddrBook.LoadGroups(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading groups failed with error: %v", err)
+		return nil, err
+	}
+	return groups, nil
+}
+
+func (uc *UseCases) LoadGroupByID(
+	ctx context.Context,
+	ID string,
+) (*model.Group, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	group, err := uc.AddrBook.LoadGroupByID(ctx, ID)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading group by id=%s failed with error: %v", ID, err)
+		return nil, err
+	}
+	if group == nil {
+		return nil, fmt.Errorf("%w: group id=%s", model.ErrNotFound, ID)
+	}
+	return group, nil
+}
+
+func (uc *UseCases) AddGroup(
+	ctx context.Context,
+	group *model.GroupToSave,
+) (*model.Group, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := uc.validateGroup(ctx, "", group); err != nil {
+		return nil, err
+	}
+	newGroup, err := uc.AddrBook.AddGroup(ctx, group)
+	if err != nil {
+		app.Logger(ctx).Errorf("Adding group failed with error: %v", err)
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Added group id=%s name=%s", newGroup.ID, newGroup.Name)
+	return newGroup, nil
+}
+
+func (uc *UseCases) UpdateGroup(
+	ctx context.Context,
+	ID string,
+	group *model.GroupToSave,
+) (*model.Group, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	if err := uc.validateGroup(ctx, ID, group); err != nil {
+		return nil, err
+	}
+	updatedGroup, err := uc.AddrBook.UpdateGroup(ctx, ID, group)
+	if err != nil {
+		app.Logger(ctx).Errorf("Updating group id=%s failed with error: %v", ID, err)
+		return nil, err
+	}
+	if updatedGroup == nil {
+		return nil, fmt.Errorf("%w: group id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Debugf("Updated group id=%s", ID)
+	return updatedGroup, nil
+}
+
+// DeleteGroup deletes group, its member contacts are kept
+func (uc *UseCases) DeleteGroup(
+	ctx context.Context,
+	ID string,
+) error {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return err
+	}
+	found, err := uc.AddrBook.DeleteGroup(ctx, ID)
+	if err != nil {
+		app.Logger(ctx).Errorf("Deleting group id=%s failed with error: %v", ID, err)
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: group id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Debugf("Deleted group id=%s", ID)
+	return nil
+}
+
+// AddGroupMembers adds contacts to the group, nothing is added if some of the contacts do not exist
+func (uc *UseCases) AddGroupMembers(
+	ctx context.Context,
+	groupID string,
+	contactIDs []string,
+) error {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return err
+	}
+	if err := model.ValidateGroupMembers(contactIDs); err != nil {
+		return err
+	}
+	missing, found, err := uc.AddrBook.AddGroupMembers(ctx, groupID, contactIDs)
+	if err != nil {
+		app.Logger(ctx).Errorf("Adding members to group id=%s failed with error: %v", groupID, err)
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: group id=%s", model.ErrNotFound, groupID)
+	}
+	if len(missing) > 0 {
+		verr := &model.ValidationError{}
+		for i, ID := range contactIDs {
+			for _, missingID := range missing {
+				if ID == missingID {
+					verr.Add(fmt.Sprintf("contact_ids[%d]", i), "contact not found")
+					break
+				}
+			}
+		}
+		return verr
+	}
+	app.Logger(ctx).Debugf("Added contacts %s to group id=%s", strings.Join(contactIDs, ","), groupID)
+	return nil
+}
+
+func (uc *UseCases) RemoveGroupMembers(
+	ctx context.Context,
+	groupID string,
+	contactIDs []string,
+) error {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return err
+	}
+	if err := model.ValidateGroupMembers(contactIDs); err != nil {
+		return err
+	}
+	found, err := uc.AddrBook.RemoveGroupMembers(ctx, groupID, contactIDs)
+	if err != nil {
+		app.Logger(ctx).Errorf("Removing members from group id=%s failed with error: %v", groupID, err)
+		return err
+	}
+	if !found {
+		return fmt.Errorf("%w: group id=%s", model.ErrNotFound, groupID)
+	}
+	app.Logger(ctx).Debugf("Removed contacts %s from group id=%s", strings.Join(contactIDs, ","), groupID)
+	return nil
+}
+
+// validateGroup checks group and makes sure that no other group of the tenant has the same name
+func (uc *UseCases) validateGroup(ctx context.Context, ID string, group *model.GroupToSave) error {
+	if err := group.Validate(); err != nil {
+		app.Logger(ctx).Infof("Invalid group: %v", err)
+		return err
+	}
+	groups, err := uc.AddrBook.LoadGroups(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading groups failed with error: %v", err)
+		return err
+	}
+	for _, existing := range groups {
+		if existing.ID != ID && existing.Name == group.Name {
+			return fmt.Errorf("%w: group name=%s already exists", model.ErrConflict, group.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"time"
+)
+
+const (
+	DefaultContactHistoryLimit = 50
+	MaxContactHistoryLimit     = 500
+)
+
+// LoadAddrBookContactHistory returns revisions of the contact along with changed fields, the most recent go first
+func (uc *UseCases) LoadAddrBookContactHistory(
+	ctx context.Context,
+	ID string,
+	limit int,
+) ([]*model.ContactRevision, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultContactHistoryLimit
+	} else if limit > MaxContactHistoryLimit {
+		limit = MaxContactHistoryLimit
+	}
+	app.Logger(ctx).Debugf("Load history of address book contact by id=%s, limit=%d", ID, limit)
+	// one extra revision is requested to find out changes made by the oldest returned revision
+	revisions, found, err := uc.AddrBook.LoadContactRevisions(ctx, ID, limit+1)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading history of address book contact by id=%s failed with error: %v", ID, err)
+		return nil, err
+	}
+	if !found {
+		app.Logger(ctx).Infof("No address book contact found with id=%s", ID)
+		return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
+	}
+	for i, rev := range revisions {
+		if i+1 < len(revisions) {
+			rev.Changes = model.DiffContacts(revisions[i+1].Contact, rev.Contact)
+		} else if rev.Action == model.ContactRevisionCreated {
+			rev.Changes = model.DiffContacts(nil, rev.Contact)
+		}
+	}
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+	app.Logger(ctx).Debugf("Loaded %d revisions of address book contact by id=%s", len(revisions), ID)
+	return revisions, nil
+}
+
+// LoadAddrBookContactAsOf returns the contact as it was at given time, contact which was not created yet or
+// was in trash at that time is not found
+func (uc *UseCases) LoadAddrBookContactAsOf(
+	ctx context.Context,
+	ID string,
+	asOf time.Time,
+) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Load address book contact by id=%s as of %s", ID, asOf.Format(time.RFC3339))
+	rev, err := uc.AddrBook.LoadContactRevisionAsOf(ctx, ID, asOf)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading address book contact by id=%s as of %s failed: %v", ID, asOf.Format(time.RFC3339), err)
+		return nil, err
+	}
+	if rev == nil || rev.Action == model.ContactRevisionDeleted {
+		app.Logger(ctx).Infof("No address book contact found with id=%s as of %s", ID, asOf.Format(time.RFC3339))
+		return nil, fmt.Errorf("%w: contact id=%s as of %s", model.ErrNotFound, ID, asOf.Format(time.RFC3339))
+	}
+	app.Logger(ctx).Debugf("Loaded address book contact revision=%d: %v", rev.Revision, rev.Contact)
+	return rev.Contact, nil
+}
+
+// RevertAddrBookContact replaces names, phones, details and custom field values of the contact with the ones it had
+// right after given revision. Values of custom fields deleted since then are dropped, the rest is validated the same
+// way as updated contact is.
+func (uc *UseCases) RevertAddrBookContact(
+	ctx context.Context,
+	ID string,
+	revision int,
+) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Revert address book contact by id=%s to revision=%d", ID, revision)
+	rev, err := uc.AddrBook.LoadContactRevision(ctx, ID, revision)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading address book contact by id=%s revision=%d failed: %v", ID, revision, err)
+		return nil, err
+	}
+	if rev == nil {
+		app.Logger(ctx).Infof("No address book contact found with id=%s revision=%d", ID, revision)
+		return nil, fmt.Errorf("%w: contact id=%s revision=%d", model.ErrNotFound, ID, revision)
+	}
+	contact := rev.Contact.ToSave()
+	fields, err := uc.CustomFields.LoadCustomFields(ctx)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading custom fields failed with error: %v", err)
+		return nil, err
+	}
+	defined := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		defined[field.Name] = true
+	}
+	for name := range contact.CustomFields {
+		if !defined[name] {
+			delete(contact.CustomFields, name)
+		}
+	}
+	if err = uc.validateContact(ctx, contact); err != nil {
+		return nil, err
+	}
+	reverted, err := uc.AddrBook.RevertContact(ctx, ID, revision, contact)
+	if err != nil {
+		app.Logger(ctx).Errorf("Reverting address book contact by id=%s failed with error: %v", ID, err)
+		return nil, err
+	}
+	if reverted == nil {
+		app.Logger(ctx).Infof("Attempt to revert non-existing contact by id=%s", ID)
+		return nil, fmt.Errorf("%w: contact id=%s", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Debugf("Reverted address book contact to revision=%d: %v", revision, reverted)
+	return reverted, nil
+}
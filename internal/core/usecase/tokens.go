@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+)
+
+// AuthenticateToken returns identity of bearer token subject. Rejected tokens result in error
+// wrapping model.ErrInvalidToken.
+func (uc *UseCases) AuthenticateToken(
+	ctx context.Context,
+	rawToken string,
+) (*app.Identity, error) {
+	if uc.Tokens == nil {
+		return nil, fmt.Errorf("%w: identity provider is not configured", model.ErrInvalidToken)
+	}
+	identity, err := uc.Tokens.VerifyToken(ctx, rawToken)
+	if err != nil {
+		if errors.Is(err, model.ErrInvalidToken) {
+			app.Logger(ctx).Debugf("Bearer token rejected: %v", err)
+		} else {
+			app.Logger(ctx).Errorf("Verifying bearer token failed with error: %v", err)
+		}
+		return nil, err
+	}
+	return identity, nil
+}
@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"time"
+)
+
+const (
+	DefaultTrashListLimit = 50
+	MaxTrashListLimit     = 500
+)
+
+// LoadAddrBookTrash returns contacts moved to trash, the most recently deleted go first
+func (uc *UseCases) LoadAddrBookTrash(
+	ctx context.Context,
+	limit int,
+) ([]*model.Contact, error) {
+	if err := authorize(ctx, model.RoleReader); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultTrashListLimit
+	} else if limit > MaxTrashListLimit {
+		limit = MaxTrashListLimit
+	}
+	app.Logger(ctx).Debugf("Load address book contacts in trash, limit=%d", limit)
+	contacts, err := uc.AddrBook.LoadTrashedContacts(ctx, limit)
+	if err != nil {
+		app.Logger(ctx).Errorf("Loading address book contacts in trash failed with error: %v", err)
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Loaded %d address book contacts in trash", len(contacts))
+	return contacts, nil
+}
+
+func (uc *UseCases) RestoreAddrBookContact(
+	ctx context.Context,
+	ID string,
+) (*model.Contact, error) {
+	if err := authorize(ctx, model.RoleEditor); err != nil {
+		return nil, err
+	}
+	app.Logger(ctx).Debugf("Restore address book contact by id=%s", ID)
+	contact, err := uc.AddrBook.RestoreContact(ctx, ID)
+	if err != nil {
+		app.Logger(ctx).Errorf("Restoring address book contact by id=%s failed with error: %v", ID, err)
+		return nil, err
+	}
+	if contact == nil {
+		app.Logger(ctx).Infof("No address book contact found in trash with id=%s", ID)
+		return nil, fmt.Errorf("%w: contact id=%s in trash", model.ErrNotFound, ID)
+	}
+	app.Logger(ctx).Debugf("Restored address book contact: %v", contact)
+	return contact, nil
+}
+
+// PurgeAddrBookTrash permanently deletes contacts of all tenants which stayed in trash longer than
+// trash.retention. It is run by background job, which has no caller identity, and it is not exposed to callers.
+func (uc *UseCases) PurgeAddrBookTrash(ctx context.Context) error {
+	if uc.Trash.Retention <= 0 {
+		return nil
+	}
+	deletedBefore := time.Now().Add(-uc.Trash.Retention)
+	app.Logger(ctx).Debugf("Purge address book contacts moved to trash before %s", deletedBefore.Format(time.RFC3339))
+	count, err := uc.AddrBook.PurgeContacts(ctx, deletedBefore)
+	if err != nil {
+		app.Logger(ctx).Errorf("Purging address book trash failed after %d contact(s) with error: %v", count, err)
+		return err
+	}
+	if count > 0 {
+		app.Logger(ctx).Infof("Purged %d address book contact(s) from trash", count)
+	}
+	return nil
+}
@@ -1,10 +1,18 @@
 package usecase
 
 import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
 	"github.com/skvenkat/golang-chi-rest-api/internal/core/outport"
 )
 
 type UseCases struct {
-	AddrBook outport.AddrBook
+	AddrBook     outport.AddrBook
+	APIKeys      outport.APIKeys
+	CustomFields outport.CustomFields  // field definitions, field values are stored by AddrBook along with contacts
+	Tokens       outport.TokenVerifier // nil if identity provider is not configured
 	// other output/secondary ports can be added here
+
+	Credentials app.CredentialsConfig
+	Phones      app.PhonesConfig
+	Trash       app.TrashConfig
 }
@@ -0,0 +1,79 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/di"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/model"
+	"go.uber.org/zap"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// APIKeysCreate issues new API key and prints it. The raw key cannot be retrieved later.
+func APIKeysCreate(deployment string, name string, tenant string, role string) {
+	di := wireCommandDependencies(deployment)
+	defer di.Close()
+	key, rawKey, err := di.UseCases.CreateAPIKey(operatorContext(), name, tenant, model.Role(role))
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+	fmt.Printf("created API key id=%s name=%s tenant=%s role=%s\n", key.ID, key.Name, key.Tenant, key.Role)
+	fmt.Println("store the key now, it will not be shown again:")
+	fmt.Println(rawKey)
+}
+
+// APIKeysList prints all issued API keys (without raw keys)
+func APIKeysList(deployment string) {
+	di := wireCommandDependencies(deployment)
+	defer di.Close()
+	keys, err := di.UseCases.LoadAPIKeys(operatorContext())
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tNAME\tTENANT\tROLE\tPREFIX\tCREATED AT\tREVOKED AT")
+	for _, k := range keys {
+		revokedAt := "-"
+		if k.RevokedAt != nil {
+			revokedAt = k.RevokedAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s…\t%s\t%s\n",
+			k.ID, k.Name, k.Tenant, k.Role, k.Prefix, k.CreatedAt.Format(time.RFC3339), revokedAt)
+	}
+	_ = w.Flush()
+}
+
+// APIKeysRevoke revokes API key, so that it is no longer accepted by API server
+func APIKeysRevoke(deployment string, ID string) {
+	di := wireCommandDependencies(deployment)
+	defer di.Close()
+	err := di.UseCases.RevokeAPIKey(operatorContext(), ID)
+	if errors.Is(err, model.ErrNotFound) {
+		_, _ = fmt.Fprintf(os.Stderr, "API key id=%s does not exist or is already revoked\n", ID)
+		os.Exit(1)
+	}
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+	fmt.Printf("revoked API key id=%s\n", ID)
+}
+
+// operatorContext returns context of CLI commands, they are run by deployment operator who has access to
+// configuration and database anyway, so the operator is granted admin role
+func operatorContext() context.Context {
+	return app.ContextWithIdentity(app.BackgroundContextWithDefaultLogger(), &app.Identity{
+		Subject: "cli",
+		Tenant:  app.DefaultTenant,
+		Roles:   []string{string(model.RoleAdmin)},
+	})
+}
+
+func wireCommandDependencies(deployment string) *di.DI {
+	initLogger()
+	cfg := app.LoadConfig(deployment)
+	return wireDependencies(cfg)
+}
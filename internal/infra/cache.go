@@ -14,6 +14,12 @@ func wireCachePorts(cfg *app.Config, _ *di.DI) (outport.Cache, func()) {
 		return cache.NewNoCache(), func() {}
 	case "inmem":
 		return cache.NewInMemCache(), func() {}
+	case "redis":
+		c := cache.NewRedisCache(cfg.Cache)
+		return c, c.Close
+	case "tiered":
+		c := cache.NewTieredRedisCache(cfg.Cache)
+		return c, c.Close
 	default:
 		panic(fmt.Sprintf("unknown cache type: %s", cfg.Cache.Type))
 	}
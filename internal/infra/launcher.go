@@ -8,11 +8,21 @@ import (
 )
 
 func Start(deployment string) {
-	logger, _ := zap.NewDevelopment()
-	zap.ReplaceGlobals(logger)
+	initLogger()
 	ctx := app.ContextWithLogger(context.Background(), zap.S())
 
 	cfg := app.LoadConfig(deployment)
 	di := wireDependencies(cfg)
+	stopPurger := startTrashPurger(ctx, di)
+	closeDI := di.Close
+	di.Close = func() {
+		stopPurger()
+		closeDI()
+	}
 	apiserver.Start(ctx, di)
 }
+
+func initLogger() {
+	logger, _ := zap.NewDevelopment()
+	zap.ReplaceGlobals(logger)
+}
@@ -0,0 +1,87 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist"
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/persist/migrations"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"go.uber.org/zap"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// MigrateUp applies all pending database migrations
+func MigrateUp(deployment string) {
+	migrator, closeDB := newMigrator(deployment)
+	defer closeDB()
+	applied, err := migrator.Up(context.Background())
+	printMigrations("applied", applied)
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+}
+
+// MigrateDown reverts specified number of most recently applied database migrations
+func MigrateDown(deployment string, steps int) {
+	migrator, closeDB := newMigrator(deployment)
+	defer closeDB()
+	reverted, err := migrator.Down(context.Background(), steps)
+	printMigrations("reverted", reverted)
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+}
+
+// MigrateStatus prints all database migrations and whether they were applied
+func MigrateStatus(deployment string) {
+	migrator, closeDB := newMigrator(deployment)
+	defer closeDB()
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		zap.S().Fatalln(err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+	for _, st := range statuses {
+		appliedAt, note := "pending", ""
+		if st.AppliedAt != nil {
+			appliedAt = st.AppliedAt.Format(time.RFC3339)
+		}
+		if st.Modified {
+			note = "script modified after it was applied"
+		} else if st.Missing {
+			note = "script is missing"
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", st.Version, st.Name, appliedAt, note)
+	}
+	_ = w.Flush()
+}
+
+// MigrateCreate adds new empty migration scripts to the source tree
+func MigrateCreate(dir string, name string) {
+	files, err := migrations.Create(dir, name)
+	for _, f := range files {
+		fmt.Println("created", f)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func newMigrator(deployment string) (*migrations.Migrator, func()) {
+	initLogger()
+	cfg := app.LoadConfig(deployment)
+	return persist.NewMigrator(cfg)
+}
+
+func printMigrations(action string, list []*migrations.Migration) {
+	if len(list) == 0 {
+		fmt.Println("no migrations", action)
+	}
+	for _, m := range list {
+		fmt.Printf("%s %d_%s\n", action, m.Version, m.Name)
+	}
+}
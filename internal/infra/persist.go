@@ -18,5 +18,7 @@ func wirePersistPorts(
 		cache,
 	)
 	di.UseCases.AddrBook = addrBook
+	di.UseCases.APIKeys = persist.NewAPIKeysAdapter(pers)
+	di.UseCases.CustomFields = persist.NewCustomFieldsAdapter(pers, addrBook)
 	return pers.Close
 }
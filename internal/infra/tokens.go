@@ -0,0 +1,13 @@
+package infra
+
+import (
+	"github.com/skvenkat/golang-chi-rest-api/internal/adapters/oidc"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/app"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/di"
+)
+
+func wireTokenPorts(cfg *app.Config, di *di.DI) {
+	if cfg.OIDC.Enabled() {
+		di.UseCases.Tokens = oidc.NewTokenVerifier(cfg.OIDC)
+	}
+}
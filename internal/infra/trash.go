@@ -0,0 +1,45 @@
+package infra
+
+import (
+	"context"
+	"github.com/skvenkat/golang-chi-rest-api/internal/core/di"
+	"go.uber.org/zap"
+	"time"
+)
+
+const defaultTrashPurgeInterval = time.Hour
+
+// startTrashPurger periodically purges contacts whose trash retention has expired, the first purge runs right
+// away. Returned function stops purging and waits for the running purge to finish.
+func startTrashPurger(ctx context.Context, di *di.DI) func() {
+	cfg := di.Config.Trash
+	if cfg.Retention <= 0 {
+		zap.S().Info("Trash retention is not configured, deleted contacts are kept in trash forever")
+		return func() {}
+	}
+	interval := cfg.PurgeInterval
+	if interval <= 0 {
+		interval = defaultTrashPurgeInterval
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// failed purge is logged by use case and retried on the next tick
+			_ = di.UseCases.PurgeAddrBookTrash(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	zap.S().Infof("Deleted contacts are purged from trash after %s, every %s", cfg.Retention, interval)
+	return func() {
+		cancel()
+		<-stopped
+	}
+}
@@ -9,13 +9,22 @@ import (
 
 func wireDependencies(cfg *app.Config) *di.DI {
 	zap.S().Info("Initialize DI objects")
+	if err := cfg.Credentials.Validate(); err != nil {
+		zap.S().Fatalln(err)
+	}
 	newDI := &di.DI{
-		Config:   cfg,
-		UseCases: &usecase.UseCases{},
+		Config: cfg,
+		UseCases: &usecase.UseCases{
+			Credentials: cfg.Credentials,
+			Phones:      cfg.Phones,
+			Trash:       cfg.Trash,
+		},
 	}
 
 	cache, cacheCleanup := wireCachePorts(cfg, newDI)
 
+	wireTokenPorts(cfg, newDI)
+
 	persistCleanup := wirePersistPorts(
 		cfg,
 		cache,